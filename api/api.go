@@ -2,6 +2,7 @@ package api
 
 import (
 	"github.com/burxondv/note-template/config"
+	"github.com/burxondv/note-template/pkg/email"
 	"github.com/burxondv/note-template/storage"
 	"github.com/gin-gonic/gin"
 
@@ -16,13 +17,14 @@ type RouterOptions struct {
 	Cfg      *config.Config
 	Storage  storage.StorageI
 	InMemory storage.InMemoryStorageI
+	Mailer   email.Mailer
 }
 
 // @title           Swagger for note api
 // @version         1.0
 // @description     This is a note service api.
 // @host      localhost:8000
-// @BasePath  /v1
+// @BasePath  /
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
@@ -34,23 +36,91 @@ func New(opt *RouterOptions) *gin.Engine {
 		Cfg:      opt.Cfg,
 		Storage:  opt.Storage,
 		InMemory: opt.InMemory,
+		Mailer:   opt.Mailer,
 	})
 
+	router.Use(handlerV1.RequestID, handlerV1.RequestTimeout)
+
 	router.Static("/media", "./media")
 
-	apiV1 := router.Group("/v1")
+	router.GET("/s/:token", handlerV1.GetPublicNote)
+
+	authV1 := router.Group("/v1/auth")
+
+	authV1.POST("/register", handlerV1.Register)
+	authV1.POST("/verify", handlerV1.Verify)
+	authV1.POST("/resend-code", handlerV1.ResendCode)
+	authV1.POST("/login", handlerV1.Login)
+	authV1.POST("/refresh", handlerV1.Refresh)
+	authV1.POST("/forgot-password", handlerV1.ForgotPassword)
+	authV1.POST("/reset-password", handlerV1.ResetPassword)
+
+	apiV1 := router.Group("/v1", handlerV1.AuthMiddleware)
+
+	sessionV1 := apiV1.Group("", handlerV1.SessionOnly)
+
+	sessionV1.POST("/auth/logout", handlerV1.Logout)
+	sessionV1.POST("/auth/change-password", handlerV1.ChangePassword)
+
+	sessionV1.GET("/me", handlerV1.GetMe)
+	sessionV1.PUT("/me", handlerV1.UpdateMe)
+	sessionV1.PATCH("/me", handlerV1.PatchMe)
+
+	sessionV1.POST("/tokens", handlerV1.CreateApiToken)
+	sessionV1.GET("/tokens", handlerV1.GetAllApiTokens)
+	sessionV1.DELETE("/tokens/:id", handlerV1.DeleteApiToken)
+
+	adminV1 := sessionV1.Group("", handlerV1.RequirePermission(v1.PermissionManageUsers))
+
+	adminV1.GET("/users/:id", handlerV1.GetUser)
+	adminV1.GET("/users", handlerV1.GetAllUsers)
+	adminV1.PUT("/users/:id", handlerV1.UpdateUser)
+	adminV1.PATCH("/users/:id", handlerV1.PatchUser)
+	adminV1.PUT("/users/:id/role", handlerV1.UpdateUserRole)
+	adminV1.DELETE("/users/:id", handlerV1.DeleteUser)
+
+	notesRead := apiV1.Group("", handlerV1.RequireScope(v1.ScopeNotesRead))
+
+	notesRead.GET("/notes/:id", handlerV1.GetNote)
+	notesRead.GET("/notes", handlerV1.GetAllNotes)
+	notesRead.GET("/notes/trash", handlerV1.GetNotesTrash)
+	notesRead.GET("/notes/:id/revisions", handlerV1.GetAllNoteRevisions)
+	notesRead.GET("/notes/:id/revisions/diff", handlerV1.GetNoteRevisionDiff)
+	notesRead.GET("/notes/:id/revisions/:revision_id", handlerV1.GetNoteRevision)
+	notesRead.GET("/notes/:id/shares", handlerV1.GetAllNoteShares)
+	notesRead.GET("/links", handlerV1.GetAllNoteLinks)
+	notesRead.GET("/tags", handlerV1.GetAllTags)
+	notesRead.GET("/notebooks", handlerV1.GetAllNotebooks)
+	notesRead.GET("/notebooks/tree", handlerV1.GetNotebookTree)
+	notesRead.GET("/notebooks/:id", handlerV1.GetNotebook)
+
+	notesWrite := apiV1.Group("", handlerV1.RequireScope(v1.ScopeNotesWrite))
+
+	notesWrite.POST("/notes", handlerV1.CreateNote)
+	notesWrite.PUT("/notes/:id", handlerV1.UpdateNote)
+	notesWrite.PATCH("/notes/:id", handlerV1.PatchNote)
+	notesWrite.DELETE("/notes/:id", handlerV1.DeleteNote)
+	notesWrite.POST("/notes/:id/restore", handlerV1.RestoreNote)
+	notesWrite.DELETE("/notes/:id/permanent", handlerV1.PurgeNote)
+	notesWrite.POST("/notes/:id/revisions/:revision_id/restore", handlerV1.RestoreNoteRevision)
+	notesWrite.PUT("/notes/:id/tags/:tag_id", handlerV1.AttachNoteTag)
+	notesWrite.DELETE("/notes/:id/tags/:tag_id", handlerV1.DetachNoteTag)
+	notesWrite.POST("/notes/:id/shares", handlerV1.CreateNoteShare)
+	notesWrite.PUT("/notes/:id/shares/:user_id", handlerV1.UpdateNoteShare)
+	notesWrite.DELETE("/notes/:id/shares/:user_id", handlerV1.DeleteNoteShare)
+	notesWrite.POST("/notes/:id/links", handlerV1.CreateNoteLink)
+	notesWrite.DELETE("/links/:id", handlerV1.RevokeNoteLink)
 
-	apiV1.POST("/users", handlerV1.CreateUser)
-	apiV1.GET("/users/:id", handlerV1.GetUser)
-	apiV1.GET("/users", handlerV1.GetAllUsers)
-	apiV1.PUT("/users/:id", handlerV1.UpdateUser)
-	apiV1.DELETE("/users/:id", handlerV1.DeleteUser)
+	notesWrite.POST("/tags", handlerV1.CreateTag)
+	notesWrite.PUT("/tags/:id", handlerV1.RenameTag)
+	notesWrite.POST("/tags/:id/merge", handlerV1.MergeTag)
+	notesWrite.DELETE("/tags/:id", handlerV1.DeleteTag)
 
-	apiV1.POST("/notes", handlerV1.CreateNote)
-	apiV1.GET("/notes/:id", handlerV1.GetNote)
-	apiV1.GET("/notes", handlerV1.GetAllNotes)
-	apiV1.PUT("/notes/:id", handlerV1.UpdateNote)
-	apiV1.DELETE("/notes/:id", handlerV1.DeleteNote)
+	notesWrite.POST("/notes/:id/move", handlerV1.MoveNote)
+	notesWrite.POST("/notebooks", handlerV1.CreateNotebook)
+	notesWrite.PUT("/notebooks/:id", handlerV1.RenameNotebook)
+	notesWrite.POST("/notebooks/:id/move", handlerV1.MoveNotebook)
+	notesWrite.DELETE("/notebooks/:id", handlerV1.DeleteNotebook)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type ApiToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateApiTokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required" enums:"notes:read,notes:write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateApiTokenResponse struct {
+	ApiToken
+	// Token is shown only once, it can not be recovered later
+	Token string `json:"token"`
+}
+
+type GetAllApiTokensResponse struct {
+	Tokens []*ApiToken `json:"tokens"`
+	Count  int         `json:"count"`
+}
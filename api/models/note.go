@@ -5,30 +5,60 @@ import "time"
 type Note struct {
 	ID          int64      `json:"id"`
 	UserID      int64      `json:"user_id"`
+	NotebookID  *int64     `json:"notebook_id"`
 	Title       string     `json:"title"`
 	Description string     `json:"description"`
+	Version     int64      `json:"version"`
+	Tags        []*NoteTag `json:"tags"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   *time.Time `json:"updated_at"`
 	DeletedAt   *time.Time `json:"deleted_at"`
+	// Highlight is present only in the results of a full-text search
+	Highlight *NoteHighlight `json:"highlight,omitempty"`
 }
 
-type CreateNoteRequest struct {
-	UserID      int64  `json:"user_id"`
+// NoteHighlight holds HTML-escaped fragments of the note with the matches wrapped in <mark> tags
+type NoteHighlight struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 }
 
+// CreateNoteRequest has the same limits as the columns of the notes table
+type CreateNoteRequest struct {
+	Title       string `json:"title" binding:"required,notblank,max=60" maxLength:"60"`
+	Description string `json:"description" binding:"max=100" maxLength:"100"`
+}
+
 type UpdateNote struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title       string `json:"title" binding:"required,notblank,max=60" maxLength:"60"`
+	Description string `json:"description" binding:"max=100" maxLength:"100"`
+}
+
+// PatchNoteRequest documents a merge patch, absent fields are left untouched
+type PatchNoteRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
 }
 
 type GetAllNotesParams struct {
-	Limit      int32  `json:"limit" binding:"required" default:"10"`
-	Page       int32  `json:"page" binding:"required" default:"1"`
-	Search     string `json:"search"`
-	UserID     int64  `json:"user_id"`
-	SortByData string `json:"sort_by_data" enums:"asc,desc" default:"desc"`
+	Limit  int32  `json:"limit" binding:"required" default:"10" minimum:"1" maximum:"100"`
+	Page   int32  `json:"page" binding:"required" default:"1" minimum:"1"`
+	Search string `json:"search"`
+	// SearchMode title matches a part of the title, fulltext searches the title and
+	// description with the web search syntax ("quoted phrase", or, -excluded) ordered by relevance
+	SearchMode string `json:"search_mode" enums:"title,fulltext" default:"title"`
+	SortBy     string `json:"sort_by" enums:"created_at,updated_at,title" default:"created_at"`
+	// SortOrder is the direction of sort_by
+	SortOrder string `json:"sort_order" enums:"asc,desc" default:"desc"`
+	// TagsAny, TagsAll and TagsExclude are comma separated tag ids
+	TagsAny     string `json:"tags_any"`
+	TagsAll     string `json:"tags_all"`
+	TagsExclude string `json:"tags_exclude"`
+	NotebookID  int64  `json:"notebook_id"`
+	// IncludeDescendants also lists the notes of the notebooks nested in notebook_id
+	IncludeDescendants bool `json:"include_descendants"`
+	// IncludeShared also lists the notes other users shared with the current user
+	IncludeShared bool `json:"include_shared"`
 }
 
 type GetAllNotesResponse struct {
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type NoteLink struct {
+	ID          int64      `json:"id"`
+	NoteID      int64      `json:"note_id"`
+	HasPassword bool       `json:"has_password"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	MaxViews    *int64     `json:"max_views"`
+	ViewCount   int64      `json:"view_count"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreateNoteLinkRequest struct {
+	// Password is asked in the X-Link-Password header when the link is opened
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxViews  *int64     `json:"max_views" binding:"omitempty,min=1"`
+}
+
+type CreateNoteLinkResponse struct {
+	NoteLink
+	// Token is shown only once, the note is served at Path without authorization
+	Token string `json:"token"`
+	Path  string `json:"path"`
+}
+
+type GetAllNoteLinksResponse struct {
+	Links []*NoteLink `json:"links"`
+	Count int         `json:"count"`
+}
+
+// PublicNote is the read-only view of a note opened through a share link
+type PublicNote struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
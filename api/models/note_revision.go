@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type NoteRevision struct {
+	ID     int64 `json:"id"`
+	NoteID int64 `json:"note_id"`
+	// AuthorID is null when the author's account was deleted
+	AuthorID    *int64    `json:"author_id" extensions:"x-nullable"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type GetAllNoteRevisionsResponse struct {
+	Revisions []*NoteRevision `json:"revisions"`
+	Count     int             `json:"count"`
+}
+
+type NoteRevisionDiffParams struct {
+	From int64 `json:"from" binding:"required"`
+	To   int64 `json:"to" binding:"required"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op" enums:"equal,insert,delete"`
+	Text string `json:"text"`
+}
+
+type NoteRevisionDiff struct {
+	From        int64       `json:"from"`
+	To          int64       `json:"to"`
+	Title       []*DiffLine `json:"title"`
+	Description []*DiffLine `json:"description"`
+}
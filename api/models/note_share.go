@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type NoteShare struct {
+	NoteID     int64      `json:"note_id"`
+	UserID     int64      `json:"user_id"`
+	Email      string     `json:"email"`
+	Permission string     `json:"permission" enums:"viewer,editor"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+type CreateNoteShareRequest struct {
+	// Email of the user the note is shared with
+	Email      string `json:"email" binding:"required,email"`
+	Permission string `json:"permission" binding:"required,oneof=viewer editor" enums:"viewer,editor"`
+}
+
+type UpdateNoteShareRequest struct {
+	Permission string `json:"permission" binding:"required,oneof=viewer editor" enums:"viewer,editor"`
+}
+
+type GetAllNoteSharesResponse struct {
+	Shares []*NoteShare `json:"shares"`
+	Count  int          `json:"count"`
+}
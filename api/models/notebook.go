@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type Notebook struct {
+	ID        int64      `json:"id"`
+	ParentID  *int64     `json:"parent_id"`
+	Name      string     `json:"name"`
+	NoteCount int64      `json:"note_count"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// NotebookNode is a notebook in the tree, NoteCount counts the notes placed
+// directly in it and TotalNoteCount adds the notes of all nested notebooks
+type NotebookNode struct {
+	ID             int64           `json:"id"`
+	Name           string          `json:"name"`
+	NoteCount      int64           `json:"note_count"`
+	TotalNoteCount int64           `json:"total_note_count"`
+	Children       []*NotebookNode `json:"children"`
+}
+
+type CreateNotebookRequest struct {
+	Name string `json:"name" binding:"required,max=60"`
+	// ParentID nests the notebook, it is created at the top level when empty
+	ParentID *int64 `json:"parent_id"`
+}
+
+type RenameNotebookRequest struct {
+	Name string `json:"name" binding:"required,max=60"`
+}
+
+type MoveNotebookRequest struct {
+	// ParentID is the new parent, null moves the notebook to the top level
+	ParentID *int64 `json:"parent_id"`
+}
+
+type MoveNoteRequest struct {
+	// NotebookID is the new notebook, null moves the note to the top level
+	NotebookID *int64 `json:"notebook_id"`
+}
+
+type GetAllNotebooksResponse struct {
+	Notebooks []*Notebook `json:"notebooks"`
+	Count     int         `json:"count"`
+}
+
+type GetNotebookTreeResponse struct {
+	Notebooks []*NotebookNode `json:"notebooks"`
+}
@@ -1,9 +1,24 @@
 package models
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+// Problem is an RFC 7807 application/problem+json error body
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is a stable machine readable code, clients should match on it instead of the detail
+	Code      string       `json:"code,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a single invalid field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 type ResponseOK struct {
 	Message string `json:"message"`
-}
\ No newline at end of file
+}
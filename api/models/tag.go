@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	NoteCount int64     `json:"note_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NoteTag is the short form of a tag embedded in a note
+type NoteTag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type CreateTagRequest struct {
+	Name string `json:"name" binding:"required,max=30"`
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required,max=30"`
+}
+
+type MergeTagRequest struct {
+	// TargetID is the tag that receives the notes, the merged tag is deleted
+	TargetID int64 `json:"target_id" binding:"required"`
+}
+
+type GetAllTagsResponse struct {
+	Tags  []*Tag `json:"tags"`
+	Count int    `json:"count"`
+}
@@ -9,23 +9,54 @@ type User struct {
 	PhoneNumber string     `json:"phone_number"`
 	Email       string     `json:"email"`
 	ImageURL    string     `json:"image_url"`
+	Role        string     `json:"role"`
+	IsActive    bool       `json:"is_active"`
+	Version     int64      `json:"version"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   *time.Time `json:"updated_at"`
 	DeletedAt   *time.Time `json:"deleted_at"`
 }
 
+// CreateUserRequest has the same limits as the columns of the users table,
+// phone_number is in the E.164 format, e.g. +998901234567
 type CreateUserRequest struct {
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	PhoneNumber string `json:"phone_number"`
-	Email       string `json:"email"`
-	ImageURL    string `json:"image_url"`
+	FirstName   string `json:"first_name" binding:"required,notblank,max=30" maxLength:"30"`
+	LastName    string `json:"last_name" binding:"required,notblank,max=30" maxLength:"30"`
+	PhoneNumber string `json:"phone_number" binding:"omitempty,max=20,phone" maxLength:"20" example:"+998901234567"`
+	Email       string `json:"email" binding:"required,max=50,email" maxLength:"50"`
+	Password    string `json:"password" binding:"required"`
+	ImageURL    string `json:"image_url" binding:"omitempty,httpurl"`
+}
+
+type UpdateUserRequest struct {
+	FirstName   string `json:"first_name" binding:"required,notblank,max=30" maxLength:"30"`
+	LastName    string `json:"last_name" binding:"required,notblank,max=30" maxLength:"30"`
+	PhoneNumber string `json:"phone_number" binding:"omitempty,max=20,phone" maxLength:"20" example:"+998901234567"`
+	Email       string `json:"email" binding:"required,max=50,email" maxLength:"50"`
+	ImageURL    string `json:"image_url" binding:"omitempty,httpurl"`
+}
+
+// PatchUserRequest documents a merge patch, absent fields are left untouched
+// and null clears phone_number and image_url
+type PatchUserRequest struct {
+	FirstName   *string `json:"first_name"`
+	LastName    *string `json:"last_name"`
+	PhoneNumber *string `json:"phone_number"`
+	Email       *string `json:"email"`
+	ImageURL    *string `json:"image_url"`
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required" enums:"user,admin"`
 }
 
 type GetAllUserParams struct {
-	Limit  int32  `json:"limit" binding:"required" default:"10"`
-	Page   int32  `json:"page" binding:"required" default:"1"`
+	Limit  int32  `json:"limit" binding:"required" default:"10" minimum:"1" maximum:"100"`
+	Page   int32  `json:"page" binding:"required" default:"1" minimum:"1"`
 	Search string `json:"search"`
+	SortBy string `json:"sort_by" enums:"created_at,first_name,last_name,email" default:"created_at"`
+	// SortOrder is the direction of sort_by
+	SortOrder string `json:"sort_order" enums:"asc,desc" default:"desc"`
 }
 
 type GetAllUsersResponse struct {
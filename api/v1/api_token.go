@@ -0,0 +1,185 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+
+	apiTokenPrefix = "nt_"
+)
+
+var (
+	ErrUnknownScope     = errors.New("unknown scope")
+	ErrExpiryInPast     = errors.New("expires_at must be in the future")
+	ErrApiTokenNotFound = errors.New("api token not found")
+)
+
+var knownScopes = map[string]bool{
+	ScopeNotesRead:  true,
+	ScopeNotesWrite: true,
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/tokens [post]
+// @Summary Create an api token
+// @Description Create a personal api token, the token itself is returned only once
+// @Tags token
+// @Accept json
+// @Produce json
+// @Param token body models.CreateApiTokenRequest true "Token"
+// @Success 201 {object} models.CreateApiTokenResponse
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) CreateApiToken(c *gin.Context) {
+	var (
+		req models.CreateApiTokenRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !knownScopes[scope] {
+			problemResponse(c, http.StatusBadRequest, ErrUnknownScope)
+			return
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		problemResponse(c, http.StatusBadRequest, ErrExpiryInPast)
+		return
+	}
+
+	secret, err := utils.RandomString(32)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	token := apiTokenPrefix + secret
+
+	resp, err := h.storage.ApiToken().Create(c.Request.Context(), &repo.ApiToken{
+		UserID:    getAuthPayload(c).UserID,
+		Name:      req.Name,
+		TokenHash: utils.HashToken(token),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreateApiTokenResponse{
+		ApiToken: parseApiTokenModel(resp),
+		Token:    token,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/tokens [get]
+// @Summary Get all api tokens
+// @Description Get all api tokens of the current user
+// @Tags token
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllApiTokensResponse
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetAllApiTokens(c *gin.Context) {
+	result, err := h.storage.ApiToken().GetAll(c.Request.Context(), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	response := models.GetAllApiTokensResponse{
+		Tokens: make([]*models.ApiToken, 0),
+		Count:  len(result),
+	}
+
+	for _, token := range result {
+		t := parseApiTokenModel(token)
+		response.Tokens = append(response.Tokens, &t)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/tokens/{id} [delete]
+// @Summary Revoke an api token
+// @Description Revoke an api token
+// @Tags token
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) DeleteApiToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.storage.ApiToken().Delete(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, ErrApiTokenNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully revoked",
+	})
+}
+
+// verifyApiToken looks the token up by its hash and records its usage
+func (h *handlerV1) verifyApiToken(ctx context.Context, token string) (*utils.Payload, error) {
+	apiToken, err := h.storage.ApiToken().GetByHash(ctx, utils.HashToken(token))
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if apiToken.ExpiresAt != nil && apiToken.ExpiresAt.Before(time.Now()) {
+		return nil, ErrUnauthorized
+	}
+
+	err = h.storage.ApiToken().UpdateLastUsed(ctx, apiToken.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.Payload{
+		UserID: apiToken.UserID,
+		Type:   utils.ApiToken,
+		Scopes: apiToken.Scopes,
+	}, nil
+}
+
+func parseApiTokenModel(token *repo.ApiToken) models.ApiToken {
+	return models.ApiToken{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
@@ -0,0 +1,153 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createApiToken(t *testing.T, ts *testServer, token string, req models.CreateApiTokenRequest) models.CreateApiTokenResponse {
+	rec := ts.doAuth(http.MethodPost, "/v1/tokens", token, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var resp models.CreateApiTokenResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.Token)
+
+	return resp
+}
+
+func TestApiTokenScopes(t *testing.T) {
+	ts := newTestServer()
+	_, session := loggedInUser(t, ts)
+	note := createNote(t, ts, session)
+
+	readOnly := createApiToken(t, ts, session, models.CreateApiTokenRequest{
+		Name:   "backup script",
+		Scopes: []string{"notes:read"},
+	})
+
+	stored, err := ts.storage.ApiToken().GetByHash(context.Background(), utils.HashToken(readOnly.Token))
+	require.NoError(t, err)
+	require.NotEqual(t, readOnly.Token, stored.TokenHash)
+	require.Nil(t, stored.LastUsedAt)
+
+	rec := ts.doAuth(http.MethodGet, fmt.Sprintf("/v1/notes/%d", note.ID), readOnly.Token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	stored, err = ts.storage.ApiToken().GetByHash(context.Background(), utils.HashToken(readOnly.Token))
+	require.NoError(t, err)
+	require.NotNil(t, stored.LastUsedAt)
+
+	rec = ts.doAuth(http.MethodPost, "/v1/notes", readOnly.Token, models.CreateNoteRequest{
+		Title:       "title",
+		Description: "description",
+	})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/me", readOnly.Token, nil)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, "/v1/tokens", readOnly.Token, models.CreateApiTokenRequest{
+		Name:   "escalate",
+		Scopes: []string{"notes:write"},
+	})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	readWrite := createApiToken(t, ts, session, models.CreateApiTokenRequest{
+		Name:   "sync",
+		Scopes: []string{"notes:read", "notes:write"},
+	})
+
+	createNote(t, ts, readWrite.Token)
+}
+
+func TestApiTokenValidation(t *testing.T) {
+	ts := newTestServer()
+	_, session := loggedInUser(t, ts)
+
+	rec := ts.doAuth(http.MethodPost, "/v1/tokens", session, models.CreateApiTokenRequest{
+		Name:   "bad",
+		Scopes: []string{"users:manage"},
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	past := time.Now().Add(-time.Hour)
+	rec = ts.doAuth(http.MethodPost, "/v1/tokens", session, models.CreateApiTokenRequest{
+		Name:      "bad",
+		Scopes:    []string{"notes:read"},
+		ExpiresAt: &past,
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestApiTokenExpiry(t *testing.T) {
+	ts := newTestServer()
+	user, session := loggedInUser(t, ts)
+
+	soon := time.Now().Add(time.Hour)
+	token := createApiToken(t, ts, session, models.CreateApiTokenRequest{
+		Name:      "temporary",
+		Scopes:    []string{"notes:read"},
+		ExpiresAt: &soon,
+	})
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notes", token.Token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// the api refuses an expiry in the past, store one directly
+	expired := time.Now().Add(-time.Minute)
+	random, err := utils.RandomString(32)
+	require.NoError(t, err)
+	plain := "nt_" + random
+	_, err = ts.storage.ApiToken().Create(context.Background(), &repo.ApiToken{
+		UserID:    int64(user.ID),
+		Name:      "expired",
+		TokenHash: utils.HashToken(plain),
+		Scopes:    []string{"notes:read"},
+		ExpiresAt: &expired,
+	})
+	require.NoError(t, err)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes", plain, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestApiTokenListAndRevoke(t *testing.T) {
+	ts := newTestServer()
+	_, session := loggedInUser(t, ts)
+	_, otherSession := loggedInUser(t, ts)
+
+	token := createApiToken(t, ts, session, models.CreateApiTokenRequest{
+		Name:   "ci",
+		Scopes: []string{"notes:read"},
+	})
+
+	rec := ts.doAuth(http.MethodGet, "/v1/tokens", session, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var list models.GetAllApiTokensResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Tokens, 1)
+	require.Equal(t, "ci", list.Tokens[0].Name)
+	require.NotContains(t, rec.Body.String(), token.Token)
+
+	path := fmt.Sprintf("/v1/tokens/%d", token.ID)
+
+	rec = ts.doAuth(http.MethodDelete, path, otherSession, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodDelete, path, session, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes", token.Token, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
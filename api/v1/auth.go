@@ -0,0 +1,800 @@
+package v1
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/pkg/email"
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	verificationCodeLength = 6
+	verificationCodeTTL    = 15 * time.Minute
+	verificationKeyPrefix  = "verification_code:"
+	revokedTokenKeyPrefix  = "revoked_token:"
+	resetTokenKeyPrefix    = "reset_password:"
+	sessionKeyPrefix       = "user_session:"
+	resetTokenTTL          = 30 * time.Minute
+	// maxResetRequests reset mails are sent to an email within resetRequestWindow
+	resetRequestKeyPrefix = "reset_password_requests:"
+	resetRequestWindow    = time.Hour
+	maxResetRequests      = 3
+	// maxCodeAttempts wrong guesses use up a code, a new one has to be requested
+	maxCodeAttempts    = 5
+	codeAttemptsSuffix = ":attempts"
+	resendKeyPrefix    = "verification_resend:"
+	resendCodeCooldown = time.Minute
+)
+
+var (
+	ErrWrongCode         = errors.New("wrong verification code")
+	ErrCodeExpired       = errors.New("verification code has expired")
+	ErrTooManyAttempts   = errors.New("too many wrong codes, request a new one")
+	ErrAlreadyVerified   = errors.New("user is already verified")
+	ErrNotVerified       = errors.New("user is not verified")
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrWrongCredentials  = errors.New("wrong email or password")
+	ErrWrongPassword     = errors.New("wrong password")
+	ErrInvalidResetToken = errors.New("reset token is invalid or has expired")
+)
+
+// @Router /v1/auth/register [post]
+// @Summary Register a user
+// @Description Register a user and send a verification code to their email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body models.CreateUserRequest true "User"
+// @Success 201 {object} models.User
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) Register(c *gin.Context) {
+	var (
+		req models.CreateUserRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	err = utils.ValidatePassword(req.Password)
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password, h.cfg.PasswordHashCost)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp, err := h.storage.User().Create(c.Request.Context(), &repo.User{
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		PhoneNumber: req.PhoneNumber,
+		Email:       req.Email,
+		Password:    hashedPassword,
+		ImageURL:    req.ImageURL,
+		Role:        repo.RoleUser,
+		IsActive:    false,
+	})
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	err = h.sendCode(c.Request.Context(), verificationKeyPrefix, resp.Email, "Verification code")
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, parseUserModel(resp))
+}
+
+// @Router /v1/auth/verify [post]
+// @Summary Verify a user
+// @Description Activate a user with the code sent to their email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.VerifyRequest true "Data"
+// @Success 200 {object} models.User
+// @Failure 429 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) Verify(c *gin.Context) {
+	var (
+		req models.VerifyRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	user, err := h.storage.User().GetByEmail(c.Request.Context(), req.Email)
+	if errors.Is(err, repo.ErrNotFound) {
+		problemResponse(c, http.StatusBadRequest, ErrWrongCode)
+		return
+	}
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.IsActive {
+		problemResponse(c, http.StatusBadRequest, ErrAlreadyVerified)
+		return
+	}
+
+	err = h.checkCode(c.Request.Context(), verificationKeyPrefix, user.Email, req.Code)
+	if errors.Is(err, ErrWrongCode) || errors.Is(err, ErrCodeExpired) {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, ErrTooManyAttempts) {
+		problemResponse(c, http.StatusTooManyRequests, err)
+		return
+	}
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.storage.User().Activate(c.Request.Context(), user.ID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	user.IsActive = true
+	c.JSON(http.StatusOK, parseUserModel(user))
+}
+
+// @Router /v1/auth/resend-code [post]
+// @Summary Resend the verification code
+// @Description Send a new verification code to the email if it belongs to a user that is not verified yet
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.ResendCodeRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) ResendCode(c *gin.Context) {
+	var (
+		req models.ResendCodeRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	user, err := h.storage.User().GetByEmail(c.Request.Context(), req.Email)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err == nil && !user.IsActive {
+		err = h.resendCode(c.Request.Context(), user.Email)
+		if err != nil {
+			problemResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	// the response is the same whether the email is registered, verified or not
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "If the email is waiting for verification, a new code has been sent to it",
+	})
+}
+
+// @Router /v1/auth/login [post]
+// @Summary Login
+// @Description Exchange an email and password for access and refresh tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.LoginRequest true "Data"
+// @Success 200 {object} models.AuthResponse
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) Login(c *gin.Context) {
+	var (
+		req models.LoginRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	user, err := h.storage.User().GetByEmail(c.Request.Context(), req.Email)
+	if errors.Is(err, repo.ErrNotFound) {
+		problemResponse(c, http.StatusUnauthorized, ErrWrongCredentials)
+		return
+	}
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = utils.CheckPassword(req.Password, user.Password)
+	if err != nil {
+		problemResponse(c, http.StatusUnauthorized, ErrWrongCredentials)
+		return
+	}
+
+	if !user.IsActive {
+		problemResponse(c, http.StatusForbidden, ErrNotVerified)
+		return
+	}
+
+	if utils.NeedsRehash(user.Password, h.cfg.PasswordHashCost) {
+		err = h.setPassword(c.Request.Context(), user.ID, req.Password)
+		if err != nil {
+			problemResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	resp, err := h.createTokens(c.Request.Context(), user.ID)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Router /v1/auth/refresh [post]
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new token pair, the old refresh token is revoked
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.RefreshRequest true "Data"
+// @Success 200 {object} models.AuthResponse
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) Refresh(c *gin.Context) {
+	var (
+		req models.RefreshRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	payload, err := h.verifyToken(c.Request.Context(), req.RefreshToken, utils.RefreshToken)
+	if errors.Is(err, ErrUnauthorized) {
+		problemResponse(c, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	user, err := h.storage.User().Get(c.Request.Context(), payload.UserID)
+	if errors.Is(err, repo.ErrNotFound) {
+		problemResponse(c, http.StatusUnauthorized, ErrUnauthorized)
+		return
+	}
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !user.IsActive {
+		problemResponse(c, http.StatusUnauthorized, ErrUnauthorized)
+		return
+	}
+
+	// the refresh token is claimed in one step so concurrent refreshes can not both rotate it
+	err = h.claimToken(c.Request.Context(), payload)
+	if errors.Is(err, ErrUnauthorized) {
+		problemResponse(c, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp, err := h.createTokens(c.Request.Context(), user.ID)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/auth/logout [post]
+// @Summary Logout
+// @Description Revoke the current access token and the given refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.LogoutRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) Logout(c *gin.Context) {
+	var (
+		req models.LogoutRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	payload := getAuthPayload(c)
+
+	err = h.revokeToken(c.Request.Context(), payload)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if req.RefreshToken != "" {
+		refresh, err := utils.VerifyToken(h.cfg.AuthSecretKey, req.RefreshToken)
+		if err == nil && refresh.Type == utils.RefreshToken && refresh.UserID == payload.UserID {
+			err = h.revokeToken(c.Request.Context(), refresh)
+			if err != nil {
+				problemResponse(c, http.StatusInternalServerError, err)
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Successfully logged out",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/auth/change-password [post]
+// @Summary Change password
+// @Description Change the password of the current user, the old password is required
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.ChangePasswordRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) ChangePassword(c *gin.Context) {
+	var (
+		req models.ChangePasswordRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	user, err := h.storage.User().Get(c.Request.Context(), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	err = utils.CheckPassword(req.OldPassword, user.Password)
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, ErrWrongPassword)
+		return
+	}
+
+	err = utils.ValidatePassword(req.NewPassword)
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.setPassword(c.Request.Context(), user.ID, req.NewPassword)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Password successfully changed",
+	})
+}
+
+// @Router /v1/auth/forgot-password [post]
+// @Summary Forgot password
+// @Description Send a single-use password reset token to the email if it is registered, at most three an hour.
+// @Description The response is the same for every email and the mail is sent after it
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.ForgotPasswordRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) ForgotPassword(c *gin.Context) {
+	var (
+		req models.ForgotPasswordRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	// every email is counted whether it is registered or not, so the limit tells nothing either
+	requests, err := h.countAttempt(c.Request.Context(), resetRequestKeyPrefix+req.Email, resetRequestWindow)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if requests <= maxResetRequests {
+		user, err := h.storage.User().GetByEmail(c.Request.Context(), req.Email)
+		if err != nil && !errors.Is(err, repo.ErrNotFound) {
+			problemResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		// the mail is sent after the response so its time and failures do not
+		// tell a registered email apart
+		if err == nil {
+			go func() {
+				err := h.sendResetToken(context.Background(), user)
+				if err != nil {
+					log.Printf("failed to send the reset token to user %d: %v", user.ID, err)
+				}
+			}()
+		}
+	}
+
+	// the response is the same whether the email is registered or not
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "If the email is registered, a reset token has been sent to it",
+	})
+}
+
+// @Router /v1/auth/reset-password [post]
+// @Summary Reset password
+// @Description Set a new password using a reset token, all existing sessions are invalidated
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.ResetPasswordRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) ResetPassword(c *gin.Context) {
+	var (
+		req models.ResetPasswordRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	err = utils.ValidatePassword(req.NewPassword)
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	key := resetTokenKeyPrefix + utils.HashToken(req.Token)
+
+	// the token is consumed in one step so concurrent requests can not both use it
+	value, err := h.inMemory.GetDelete(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		problemResponse(c, http.StatusBadRequest, ErrInvalidResetToken)
+		return
+	}
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	userID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.setPassword(c.Request.Context(), userID, req.NewPassword)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.invalidateSessions(c.Request.Context(), userID)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "Password successfully reset",
+	})
+}
+
+func (h *handlerV1) sendResetToken(ctx context.Context, user *repo.User) error {
+	token, err := utils.RandomString(32)
+	if err != nil {
+		return err
+	}
+
+	// only the hash of the token is kept so a leaked key can not be used to reset the password
+	err = h.inMemory.Set(ctx, resetTokenKeyPrefix+utils.HashToken(token), strconv.FormatInt(user.ID, 10), resetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body, err := email.RenderTemplate(email.ResetPasswordEmail, map[string]string{
+		"token": token,
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send([]string{user.Email}, "Password reset", body)
+}
+
+func (h *handlerV1) setPassword(ctx context.Context, userID int64, password string) error {
+	hashedPassword, err := utils.HashPassword(password, h.cfg.PasswordHashCost)
+	if err != nil {
+		return err
+	}
+
+	return h.storage.User().UpdatePassword(ctx, userID, hashedPassword)
+}
+
+// resendCode sends a new code unless one was sent to the email within resendCodeCooldown,
+// a skipped resend is not reported so the caller can not tell it apart
+func (h *handlerV1) resendCode(ctx context.Context, email string) error {
+	_, err := h.inMemory.Get(ctx, resendKeyPrefix+email)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, storage.ErrKeyNotFound) {
+		return err
+	}
+
+	err = h.inMemory.Set(ctx, resendKeyPrefix+email, "1", resendCodeCooldown)
+	if err != nil {
+		return err
+	}
+
+	return h.sendCode(ctx, verificationKeyPrefix, email, "Verification code")
+}
+
+func (h *handlerV1) sendCode(ctx context.Context, keyPrefix, to, subject string) error {
+	code, err := utils.GenerateCode(verificationCodeLength)
+	if err != nil {
+		return err
+	}
+
+	err = h.inMemory.Set(ctx, keyPrefix+to, code, verificationCodeTTL)
+	if err != nil {
+		return err
+	}
+
+	// the counter is set after the code so it never expires before it
+	err = h.inMemory.Set(ctx, keyPrefix+to+codeAttemptsSuffix, "0", verificationCodeTTL)
+	if err != nil {
+		return err
+	}
+
+	body, err := email.RenderTemplate(email.VerificationEmail, map[string]string{
+		"code": code,
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send([]string{to}, subject, body)
+}
+
+// checkCode compares the code with the one stored under keyPrefix+email
+// and removes it on success so that it can not be used twice. Every wrong
+// guess is counted and the code is dropped after maxCodeAttempts of them
+func (h *handlerV1) checkCode(ctx context.Context, keyPrefix, email, code string) error {
+	key := keyPrefix + email
+
+	stored, err := h.inMemory.Get(ctx, key)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return ErrCodeExpired
+	}
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(code)) != 1 {
+		attempts, err := h.inMemory.Incr(ctx, key+codeAttemptsSuffix)
+		if err != nil {
+			return err
+		}
+
+		if attempts >= maxCodeAttempts {
+			err = h.deleteCode(ctx, key)
+			if err != nil {
+				return err
+			}
+
+			return ErrTooManyAttempts
+		}
+
+		return ErrWrongCode
+	}
+
+	return h.deleteCode(ctx, key)
+}
+
+func (h *handlerV1) deleteCode(ctx context.Context, key string) error {
+	err := h.inMemory.Delete(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return h.inMemory.Delete(ctx, key+codeAttemptsSuffix)
+}
+
+func (h *handlerV1) createTokens(ctx context.Context, userID int64) (*models.AuthResponse, error) {
+	session, err := h.currentSession(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, payload, err := utils.CreateToken(
+		h.cfg.AuthSecretKey,
+		userID,
+		session,
+		utils.AccessToken,
+		h.cfg.AccessTokenDuration,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, _, err := utils.CreateToken(
+		h.cfg.AuthSecretKey,
+		userID,
+		session,
+		utils.RefreshToken,
+		h.cfg.RefreshTokenDuration,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    payload.ExpiresAt.Time,
+	}, nil
+}
+
+// verifyToken parses the token, checks its type and makes sure
+// it was neither revoked nor issued for an invalidated session
+func (h *handlerV1) verifyToken(ctx context.Context, token, tokenType string) (*utils.Payload, error) {
+	payload, err := utils.VerifyToken(h.cfg.AuthSecretKey, token)
+	if err != nil || payload.Type != tokenType {
+		return nil, ErrUnauthorized
+	}
+
+	session, err := h.currentSession(ctx, payload.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session != payload.Session {
+		return nil, ErrUnauthorized
+	}
+
+	_, err = h.inMemory.Get(ctx, revokedTokenKeyPrefix+payload.ID)
+	if err == nil {
+		return nil, ErrUnauthorized
+	}
+	if !errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// revokeToken remembers the token id until the token expires by itself
+func (h *handlerV1) revokeToken(ctx context.Context, payload *utils.Payload) error {
+	ttl := time.Until(payload.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return h.inMemory.Set(ctx, revokedTokenKeyPrefix+payload.ID, "1", ttl)
+}
+
+// countAttempt adds one to the counter at key and returns it, the counter
+// starts with the first attempt and is dropped window after it
+func (h *handlerV1) countAttempt(ctx context.Context, key string, window time.Duration) (int64, error) {
+	_, err := h.inMemory.SetNX(ctx, key, "0", window)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := h.inMemory.Incr(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	// the key may have expired right before Incr created it again without one
+	if count == 1 {
+		err = h.inMemory.Set(ctx, key, "1", window)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// claimToken revokes the token like revokeToken and fails with ErrUnauthorized
+// when it was already revoked, e.g. by a concurrent request
+func (h *handlerV1) claimToken(ctx context.Context, payload *utils.Payload) error {
+	ttl := time.Until(payload.ExpiresAt.Time)
+	if ttl <= 0 {
+		return ErrUnauthorized
+	}
+
+	claimed, err := h.inMemory.SetNX(ctx, revokedTokenKeyPrefix+payload.ID, "1", ttl)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+func (h *handlerV1) currentSession(ctx context.Context, userID int64) (string, error) {
+	session, err := h.inMemory.Get(ctx, sessionKeyPrefix+strconv.FormatInt(userID, 10))
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return session, nil
+}
+
+// invalidateSessions starts a new session for the user,
+// every token issued before is rejected from now on
+func (h *handlerV1) invalidateSessions(ctx context.Context, userID int64) error {
+	session, err := utils.RandomString(16)
+	if err != nil {
+		return err
+	}
+
+	return h.inMemory.Set(ctx, sessionKeyPrefix+strconv.FormatInt(userID, 10), session, 0)
+}
@@ -0,0 +1,573 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "Secret123"
+
+var (
+	codeRegexp  = regexp.MustCompile(`<b>(\d+)</b>`)
+	tokenRegexp = regexp.MustCompile(`<b>([0-9a-f]+)</b>`)
+)
+
+func register(t *testing.T, ts *testServer) models.User {
+	rec := ts.do(http.MethodPost, "/v1/auth/register", models.CreateUserRequest{
+		FirstName:   faker.FirstName(),
+		LastName:    faker.LastName(),
+		PhoneNumber: faker.E164PhoneNumber(),
+		Email:       faker.Email(),
+		Password:    testPassword,
+	})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var user models.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &user))
+
+	return user
+}
+
+func sentCode(t *testing.T, ts *testServer) string {
+	match := codeRegexp.FindStringSubmatch(ts.mailer.last().Body)
+	require.Len(t, match, 2)
+
+	return match[1]
+}
+
+func TestRegister(t *testing.T) {
+	ts := newTestServer()
+
+	user := register(t, ts)
+	require.False(t, user.IsActive)
+
+	mail := ts.mailer.last()
+	require.Equal(t, []string{user.Email}, mail.To)
+	require.Len(t, sentCode(t, ts), 6)
+}
+
+func TestRegisterValidation(t *testing.T) {
+	ts := newTestServer()
+
+	valid := func() models.CreateUserRequest {
+		return models.CreateUserRequest{
+			FirstName:   "John",
+			LastName:    "Doe",
+			PhoneNumber: "+998901234567",
+			Email:       "john@example.com",
+			Password:    testPassword,
+			ImageURL:    "https://example.com/john.png",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(req *models.CreateUserRequest)
+		field  string
+	}{
+		{"blank first name", func(req *models.CreateUserRequest) { req.FirstName = " " }, "first_name"},
+		{"long last name", func(req *models.CreateUserRequest) { req.LastName = strings.Repeat("a", 31) }, "last_name"},
+		{"local phone", func(req *models.CreateUserRequest) { req.PhoneNumber = "90-123-45-67" }, "phone_number"},
+		{"long phone", func(req *models.CreateUserRequest) { req.PhoneNumber = "+9989012345678901" }, "phone_number"},
+		{"bad email", func(req *models.CreateUserRequest) { req.Email = "john.example.com" }, "email"},
+		{"long email", func(req *models.CreateUserRequest) { req.Email = strings.Repeat("a", 39) + "@example.com" }, "email"},
+		{"bad image url", func(req *models.CreateUserRequest) { req.ImageURL = "ftp://example.com/john.png" }, "image_url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.change(&req)
+
+			rec := ts.do(http.MethodPost, "/v1/auth/register", req)
+			require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+			problem := decodeProblem(t, rec)
+			require.Len(t, problem.Errors, 1)
+			require.Equal(t, tt.field, problem.Errors[0].Field)
+		})
+	}
+
+	req := valid()
+	req.PhoneNumber = ""
+	req.ImageURL = ""
+	rec := ts.do(http.MethodPost, "/v1/auth/register", req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func TestVerify(t *testing.T) {
+	ts := newTestServer()
+	user := register(t, ts)
+
+	rec := ts.do(http.MethodPost, "/v1/auth/verify", models.VerifyRequest{
+		Email: user.Email,
+		Code:  "wrong",
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/verify", models.VerifyRequest{
+		Email: user.Email,
+		Code:  sentCode(t, ts),
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	stored, err := ts.storage.User().Get(context.Background(), int64(user.ID))
+	require.NoError(t, err)
+	require.True(t, stored.IsActive)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/verify", models.VerifyRequest{
+		Email: user.Email,
+		Code:  "000000",
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestVerifyAttempts(t *testing.T) {
+	ts := newTestServer()
+	user := register(t, ts)
+	code := sentCode(t, ts)
+
+	for i := 0; i < 4; i++ {
+		rec := ts.do(http.MethodPost, "/v1/auth/verify", models.VerifyRequest{
+			Email: user.Email,
+			Code:  "wrong",
+		})
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+
+	rec := ts.do(http.MethodPost, "/v1/auth/verify", models.VerifyRequest{
+		Email: user.Email,
+		Code:  "wrong",
+	})
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// the code is used up even when it is right
+	rec = ts.do(http.MethodPost, "/v1/auth/verify", models.VerifyRequest{
+		Email: user.Email,
+		Code:  code,
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/resend-code", models.ResendCodeRequest{
+		Email: user.Email,
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/verify", models.VerifyRequest{
+		Email: user.Email,
+		Code:  sentCode(t, ts),
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestResendCode(t *testing.T) {
+	ts := newTestServer()
+	user := register(t, ts)
+	verified := verifiedUser(t, ts)
+	sent := len(ts.mailer.sent)
+
+	rec := ts.do(http.MethodPost, "/v1/auth/resend-code", models.ResendCodeRequest{
+		Email: "nobody@example.com",
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	unknownBody := rec.Body.String()
+
+	rec = ts.do(http.MethodPost, "/v1/auth/resend-code", models.ResendCodeRequest{
+		Email: verified.Email,
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, unknownBody, rec.Body.String())
+	require.Len(t, ts.mailer.sent, sent)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/resend-code", models.ResendCodeRequest{
+		Email: user.Email,
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, unknownBody, rec.Body.String())
+	require.Len(t, ts.mailer.sent, sent+1)
+	require.Equal(t, []string{user.Email}, ts.mailer.last().To)
+
+	// a second resend within the cooldown is skipped without telling
+	rec = ts.do(http.MethodPost, "/v1/auth/resend-code", models.ResendCodeRequest{
+		Email: user.Email,
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, unknownBody, rec.Body.String())
+	require.Len(t, ts.mailer.sent, sent+1)
+}
+
+func TestCreateNoteUnverified(t *testing.T) {
+	ts := newTestServer()
+	user := register(t, ts)
+
+	token, _, err := utils.CreateToken(ts.cfg.AuthSecretKey, int64(user.ID), "", utils.AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	rec := ts.doAuth(http.MethodPost, "/v1/notes", token, models.CreateNoteRequest{
+		Title:       faker.Word(),
+		Description: faker.Sentence(),
+	})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func verifiedUser(t *testing.T, ts *testServer) models.User {
+	user := register(t, ts)
+
+	rec := ts.do(http.MethodPost, "/v1/auth/verify", models.VerifyRequest{
+		Email: user.Email,
+		Code:  sentCode(t, ts),
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &user))
+	return user
+}
+
+func login(t *testing.T, ts *testServer, user models.User) models.AuthResponse {
+	rec := ts.do(http.MethodPost, "/v1/auth/login", models.LoginRequest{
+		Email:    user.Email,
+		Password: testPassword,
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var tokens models.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	require.NotEmpty(t, tokens.AccessToken)
+	require.NotEmpty(t, tokens.RefreshToken)
+
+	return tokens
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+	tokens := login(t, ts, user)
+
+	path := "/v1/me"
+
+	rec := ts.do(http.MethodGet, path, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path, "garbage", nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path, tokens.RefreshToken, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path, tokens.AccessToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestRegisterWeakPassword(t *testing.T) {
+	ts := newTestServer()
+
+	rec := ts.do(http.MethodPost, "/v1/auth/register", models.CreateUserRequest{
+		FirstName: faker.FirstName(),
+		LastName:  faker.LastName(),
+		Email:     faker.Email(),
+		Password:  "password",
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Empty(t, ts.mailer.sent)
+}
+
+func TestRegisterStoresHash(t *testing.T) {
+	ts := newTestServer()
+	user := register(t, ts)
+
+	stored, err := ts.storage.User().Get(context.Background(), int64(user.ID))
+	require.NoError(t, err)
+	require.NotEqual(t, testPassword, stored.Password)
+	require.NoError(t, utils.CheckPassword(testPassword, stored.Password))
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+
+	rec := ts.do(http.MethodPost, "/v1/auth/login", models.LoginRequest{
+		Email:    user.Email,
+		Password: "Wrong1234",
+	})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/login", models.LoginRequest{
+		Email:    "nobody@example.com",
+		Password: testPassword,
+	})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestLoginUnverified(t *testing.T) {
+	ts := newTestServer()
+	user := register(t, ts)
+
+	rec := ts.do(http.MethodPost, "/v1/auth/login", models.LoginRequest{
+		Email:    user.Email,
+		Password: testPassword,
+	})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestLoginRehash(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+
+	ts.cfg.PasswordHashCost = bcrypt.MinCost + 1
+	login(t, ts, user)
+
+	stored, err := ts.storage.User().Get(context.Background(), int64(user.ID))
+	require.NoError(t, err)
+
+	cost, err := bcrypt.Cost([]byte(stored.Password))
+	require.NoError(t, err)
+	require.Equal(t, bcrypt.MinCost+1, cost)
+}
+
+func TestChangePassword(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+	tokens := login(t, ts, user)
+
+	rec := ts.doAuth(http.MethodPost, "/v1/auth/change-password", tokens.AccessToken, models.ChangePasswordRequest{
+		OldPassword: "Wrong1234",
+		NewPassword: "NewSecret123",
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, "/v1/auth/change-password", tokens.AccessToken, models.ChangePasswordRequest{
+		OldPassword: testPassword,
+		NewPassword: "weak",
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, "/v1/auth/change-password", tokens.AccessToken, models.ChangePasswordRequest{
+		OldPassword: testPassword,
+		NewPassword: "NewSecret123",
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = ts.do(http.MethodPost, "/v1/auth/login", models.LoginRequest{
+		Email:    user.Email,
+		Password: "NewSecret123",
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRefreshRotation(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+	tokens := login(t, ts, user)
+
+	rec := ts.do(http.MethodPost, "/v1/auth/refresh", models.RefreshRequest{
+		RefreshToken: tokens.RefreshToken,
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rotated models.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rotated))
+	require.NotEqual(t, tokens.RefreshToken, rotated.RefreshToken)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/refresh", models.RefreshRequest{
+		RefreshToken: tokens.RefreshToken,
+	})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestLogout(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+	tokens := login(t, ts, user)
+
+	rec := ts.doAuth(http.MethodPost, "/v1/auth/logout", tokens.AccessToken, models.LogoutRequest{
+		RefreshToken: tokens.RefreshToken,
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = ts.doAuth(http.MethodGet, "/v1/me", tokens.AccessToken, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/refresh", models.RefreshRequest{
+		RefreshToken: tokens.RefreshToken,
+	})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestForgotPassword(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+	other := verifiedUser(t, ts)
+	sent := ts.mailer.count()
+
+	forgot := func(email string) string {
+		rec := ts.do(http.MethodPost, "/v1/auth/forgot-password", models.ForgotPasswordRequest{
+			Email: email,
+		})
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		return rec.Body.String()
+	}
+
+	unknownBody := forgot("nobody@example.com")
+
+	require.Equal(t, unknownBody, forgot(user.Email))
+	mail := ts.mailer.wait(t, sent+1)
+	require.Equal(t, []string{user.Email}, mail.To)
+
+	// only the first maxResetRequests requests of an email send mail
+	for i := 0; i < 3; i++ {
+		require.Equal(t, unknownBody, forgot(user.Email))
+	}
+	ts.mailer.wait(t, sent+3)
+
+	// a mail that fails to send is not reported either
+	ts.mailer.fail(errors.New("smtp is down"))
+	require.Equal(t, unknownBody, forgot(other.Email))
+
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, sent+3, ts.mailer.count())
+}
+
+func TestResetPassword(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+	tokens := login(t, ts, user)
+	sent := ts.mailer.count()
+
+	rec := ts.do(http.MethodPost, "/v1/auth/forgot-password", models.ForgotPasswordRequest{
+		Email: user.Email,
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	match := tokenRegexp.FindStringSubmatch(ts.mailer.wait(t, sent+1).Body)
+	require.Len(t, match, 2)
+	resetToken := match[1]
+
+	rec = ts.do(http.MethodPost, "/v1/auth/reset-password", models.ResetPasswordRequest{
+		Token:       "wrong",
+		NewPassword: "NewSecret123",
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/reset-password", models.ResetPasswordRequest{
+		Token:       resetToken,
+		NewPassword: "NewSecret123",
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = ts.do(http.MethodPost, "/v1/auth/reset-password", models.ResetPasswordRequest{
+		Token:       resetToken,
+		NewPassword: "OtherSecret123",
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/me", tokens.AccessToken, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/refresh", models.RefreshRequest{
+		RefreshToken: tokens.RefreshToken,
+	})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/login", models.LoginRequest{
+		Email:    user.Email,
+		Password: "NewSecret123",
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var fresh models.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &fresh))
+
+	rec = ts.doAuth(http.MethodGet, "/v1/me", fresh.AccessToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestResetPasswordConcurrent(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+	sent := ts.mailer.count()
+
+	rec := ts.do(http.MethodPost, "/v1/auth/forgot-password", models.ForgotPasswordRequest{
+		Email: user.Email,
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	match := tokenRegexp.FindStringSubmatch(ts.mailer.wait(t, sent+1).Body)
+	require.Len(t, match, 2)
+
+	var (
+		wg    sync.WaitGroup
+		codes = make([]int, 10)
+	)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			rec := ts.do(http.MethodPost, "/v1/auth/reset-password", models.ResetPasswordRequest{
+				Token:       match[1],
+				NewPassword: fmt.Sprintf("NewSecret%d", i),
+			})
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	var reset int
+	for _, code := range codes {
+		if code == http.StatusOK {
+			reset++
+		} else {
+			require.Equal(t, http.StatusBadRequest, code)
+		}
+	}
+	require.Equal(t, 1, reset)
+}
+
+func TestRefreshConcurrent(t *testing.T) {
+	ts := newTestServer()
+	user := verifiedUser(t, ts)
+	tokens := login(t, ts, user)
+	ts.inMemory.getDelay = 10 * time.Millisecond
+
+	var (
+		wg    sync.WaitGroup
+		codes = make([]int, 10)
+	)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			rec := ts.do(http.MethodPost, "/v1/auth/refresh", models.RefreshRequest{
+				RefreshToken: tokens.RefreshToken,
+			})
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	var refreshed int
+	for _, code := range codes {
+		if code == http.StatusOK {
+			refreshed++
+		} else {
+			require.Equal(t, http.StatusUnauthorized, code)
+		}
+	}
+	require.Equal(t, 1, refreshed)
+}
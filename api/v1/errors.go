@@ -0,0 +1,195 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const problemContentType = "application/problem+json"
+
+// Stable codes of the errors, clients should match on them instead of the messages
+const (
+	CodeValidation       = "validation_failed"
+	CodeMalformedBody    = "malformed_body"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeInvalidReference = "invalid_reference"
+	CodeVersionMismatch  = "version_mismatch"
+	CodeReadOnly         = "read_only"
+	CodeLinkUnavailable  = "link_unavailable"
+	CodeInvalidSort      = "invalid_sort"
+	CodeTagExists        = "tag_exists"
+	CodeShareExists      = "share_exists"
+	CodeNotebookCycle    = "notebook_cycle"
+	CodeNotebookNotFound = "notebook_not_found"
+	CodeTimeout          = "timeout"
+	CodeInternal         = "internal_error"
+)
+
+// storageErrors is checked in order, the specific errors come before the kinds they belong to.
+// The detail of a kind is fixed, the driver error the storage wrapped in it is never sent
+var storageErrors = []struct {
+	err    error
+	status int
+	code   string
+	detail string
+}{
+	{repo.ErrVersionMismatch, http.StatusPreconditionFailed, CodeVersionMismatch, repo.ErrVersionMismatch.Error()},
+	{repo.ErrReadOnly, http.StatusForbidden, CodeReadOnly, repo.ErrReadOnly.Error()},
+	{repo.ErrLinkUnavailable, http.StatusGone, CodeLinkUnavailable, repo.ErrLinkUnavailable.Error()},
+	{repo.ErrInvalidSort, http.StatusBadRequest, CodeInvalidSort, repo.ErrInvalidSort.Error()},
+	{repo.ErrTagExists, http.StatusConflict, CodeTagExists, repo.ErrTagExists.Error()},
+	{repo.ErrShareExists, http.StatusConflict, CodeShareExists, repo.ErrShareExists.Error()},
+	{repo.ErrNotebookCycle, http.StatusConflict, CodeNotebookCycle, repo.ErrNotebookCycle.Error()},
+	{repo.ErrNotebookNotFound, http.StatusUnprocessableEntity, CodeNotebookNotFound, repo.ErrNotebookNotFound.Error()},
+	{repo.ErrNotFound, http.StatusNotFound, CodeNotFound, "the resource was not found"},
+	{repo.ErrConflict, http.StatusConflict, CodeConflict, "the resource clashes with an existing one"},
+	{repo.ErrInvalidReference, http.StatusUnprocessableEntity, CodeInvalidReference, "the request refers to a resource that does not exist"},
+	{context.DeadlineExceeded, http.StatusServiceUnavailable, CodeTimeout, "the storage did not answer in time"},
+}
+
+// storageError writes the response for an error returned by the storage,
+// notFound names the missing resource in place of the generic message of repo.ErrNotFound
+func storageError(c *gin.Context, err error, notFound error) {
+	for _, e := range storageErrors {
+		if !errors.Is(err, e.err) {
+			continue
+		}
+
+		detail := e.detail
+		if e.err == repo.ErrNotFound && notFound != nil {
+			detail = notFound.Error()
+		}
+
+		writeProblem(c, newProblem(c, e.status, e.code, detail))
+		return
+	}
+
+	problemResponse(c, http.StatusInternalServerError, err)
+}
+
+// problemResponse aborts the request with a problem+json body for err,
+// binding and parsing errors are turned into field errors and the details
+// of internal errors are only logged and never sent to the client
+func problemResponse(c *gin.Context, status int, err error) {
+	var (
+		validationErrs validator.ValidationErrors
+		syntaxErr      *json.SyntaxError
+		typeErr        *json.UnmarshalTypeError
+		numErr         *strconv.NumError
+	)
+
+	switch {
+	case status >= http.StatusInternalServerError:
+		_ = c.Error(err)
+		writeProblem(c, newProblem(c, status, CodeInternal, "the server could not process the request"))
+	case errors.As(err, &validationErrs):
+		problem := newProblem(c, status, CodeValidation, "the request has invalid fields")
+		for _, fe := range validationErrs {
+			problem.Errors = append(problem.Errors, models.FieldError{
+				Field:   fieldName(fe),
+				Message: fieldMessage(fe),
+			})
+		}
+		writeProblem(c, problem)
+	case errors.As(err, &typeErr):
+		problem := newProblem(c, status, CodeMalformedBody, "the request body has fields of the wrong type")
+		problem.Errors = []models.FieldError{{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be a %s", typeErr.Type.Kind()),
+		}}
+		writeProblem(c, problem)
+	case errors.As(err, &syntaxErr):
+		writeProblem(c, newProblem(c, status, CodeMalformedBody, "the request body is not valid json"))
+	case errors.As(err, &numErr):
+		writeProblem(c, newProblem(c, status, CodeInvalidParameter, fmt.Sprintf("%q is not a valid number", numErr.Num)))
+	default:
+		writeProblem(c, newProblem(c, status, "", err.Error()))
+	}
+}
+
+// fieldProblem aborts the request with a single field error
+func fieldProblem(c *gin.Context, status int, field, message string) {
+	problem := newProblem(c, status, CodeValidation, "the request has invalid fields")
+	problem.Errors = []models.FieldError{{Field: field, Message: message}}
+	writeProblem(c, problem)
+}
+
+func newProblem(c *gin.Context, status int, code, detail string) *models.Problem {
+	problemType := "about:blank"
+	if code != "" {
+		problemType = "urn:problem-type:" + code
+	}
+
+	return &models.Problem{
+		Type:      problemType,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: c.GetString(requestIDKey),
+	}
+}
+
+func writeProblem(c *gin.Context, problem *models.Problem) {
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// fieldName is the json path of the field without the name of the request struct
+func fieldName(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "email":
+		return "must be a valid email"
+	case "phone":
+		return "must be a phone number in the E.164 format, e.g. +998901234567"
+	case "url", "httpurl":
+		return "must be a valid http or https url"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		return "must be at least " + fe.Param() + lengthUnit(fe)
+	case "max":
+		return "must be at most " + fe.Param() + lengthUnit(fe)
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	}
+
+	return fmt.Sprintf("failed the %q rule", fe.Tag())
+}
+
+func lengthUnit(fe validator.FieldError) string {
+	switch fe.Kind().String() {
+	case "string":
+		return " characters long"
+	case "slice", "map", "array":
+		return " items long"
+	}
+	return ""
+}
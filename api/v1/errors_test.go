@@ -0,0 +1,151 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/burxondv/note-template/api/models"
+	v1 "github.com/burxondv/note-template/api/v1"
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) models.Problem {
+	require.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	var problem models.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.NotEmpty(t, problem.Type)
+	require.NotEmpty(t, problem.Title)
+	require.NotEmpty(t, problem.RequestID)
+
+	return problem
+}
+
+func TestStorageErrorCodes(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	createTag(t, ts, token, "work")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		status int
+		code   string
+	}{
+		{"missing note", http.MethodGet, "/v1/notes/999", nil, http.StatusNotFound, v1.CodeNotFound},
+		{"duplicate tag", http.MethodPost, "/v1/tags", models.CreateTagRequest{Name: "work"}, http.StatusConflict, v1.CodeTagExists},
+		{"unknown sort", http.MethodGet, "/v1/notes?sort_by=secret", nil, http.StatusBadRequest, v1.CodeInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.doAuth(tt.method, tt.path, token, tt.body)
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+
+			problem := decodeProblem(t, rec)
+			require.Equal(t, tt.status, problem.Status)
+			require.Equal(t, tt.code, problem.Code)
+			require.NotEmpty(t, problem.Detail)
+		})
+	}
+}
+
+func TestStorageErrorHidesDriverText(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "note.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := sqlite.NewMigrator(db.DB)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(0))
+
+	ts := newTestServerWithStorage(storage.NewStorageSqlite(db, 0))
+
+	req := models.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@example.com",
+		Password:  testPassword,
+	}
+
+	rec := ts.do(http.MethodPost, "/v1/auth/register", req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = ts.do(http.MethodPost, "/v1/auth/register", req)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	problem := decodeProblem(t, rec)
+	require.Equal(t, v1.CodeConflict, problem.Code)
+	require.NotEmpty(t, problem.Detail)
+	for _, driverText := range []string{"UNIQUE", "constraint", "users.email"} {
+		require.NotContains(t, problem.Detail, driverText)
+	}
+}
+
+func TestProblemFieldErrors(t *testing.T) {
+	ts := newTestServer()
+
+	rec := ts.do(http.MethodPost, "/v1/auth/login", models.LoginRequest{})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	problem := decodeProblem(t, rec)
+	require.Equal(t, v1.CodeValidation, problem.Code)
+	require.Equal(t, "/v1/auth/login", problem.Instance)
+	require.ElementsMatch(t, []models.FieldError{
+		{Field: "email", Message: "is required"},
+		{Field: "password", Message: "is required"},
+	}, problem.Errors)
+}
+
+func TestProblemMalformedBody(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	rec := ts.doAuth(http.MethodPost, "/v1/notes", token, map[string]interface{}{"title": 1})
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	problem := decodeProblem(t, rec)
+	require.Equal(t, v1.CodeMalformedBody, problem.Code)
+	require.Equal(t, []models.FieldError{{Field: "title", Message: "must be a string"}}, problem.Errors)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes/abc", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, v1.CodeInvalidParameter, decodeProblem(t, rec).Code)
+}
+
+func TestRequestID(t *testing.T) {
+	ts := newTestServer()
+
+	rec := ts.doHeaders(http.MethodGet, "/v1/me", "", map[string]string{"X-Request-ID": "abc-123"}, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, "abc-123", rec.Header().Get("X-Request-ID"))
+	require.Equal(t, "abc-123", decodeProblem(t, rec).RequestID)
+
+	rec = ts.do(http.MethodGet, "/v1/me", nil)
+	require.Len(t, rec.Header().Get("X-Request-ID"), 32)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	ts.cfg.RequestTimeout = time.Nanosecond
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notes", token, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code, rec.Body.String())
+	require.Equal(t, v1.CodeTimeout, decodeProblem(t, rec).Code)
+
+	ts.cfg.RequestTimeout = time.Minute
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
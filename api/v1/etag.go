@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrIfMatchRequired = errors.New("If-Match header is required")
+	ErrInvalidIfMatch  = errors.New("If-Match header must be an ETag returned by the api")
+	ErrWeakIfMatch     = errors.New("If-Match header must be a strong ETag")
+)
+
+// setETag exposes the version of the returned resource as a strong ETag
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// ifMatchVersion reads the version the client last saw from the If-Match header
+// and writes the error response itself, ok is false when the handler should stop.
+// If-Match uses the strong comparison, so a weak ETag never matches
+func ifMatchVersion(c *gin.Context) (int64, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		problemResponse(c, http.StatusPreconditionRequired, ErrIfMatchRequired)
+		return 0, false
+	}
+	if strings.HasPrefix(header, "W/") {
+		problemResponse(c, http.StatusPreconditionFailed, ErrWeakIfMatch)
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, ErrInvalidIfMatch)
+		return 0, false
+	}
+
+	return version, true
+}
@@ -1,8 +1,8 @@
 package v1
 
 import (
-	"github.com/burxondv/note-template/api/models"
 	"github.com/burxondv/note-template/config"
+	"github.com/burxondv/note-template/pkg/email"
 	"github.com/burxondv/note-template/storage"
 )
 
@@ -10,24 +10,23 @@ type handlerV1 struct {
 	cfg      *config.Config
 	storage  storage.StorageI
 	inMemory storage.InMemoryStorageI
+	mailer   email.Mailer
 }
 
 type HandlerV10Options struct {
 	Cfg      *config.Config
 	Storage  storage.StorageI
 	InMemory storage.InMemoryStorageI
+	Mailer   email.Mailer
 }
 
 func New(options *HandlerV10Options) *handlerV1 {
+	registerValidation()
+
 	return &handlerV1{
 		cfg:      options.Cfg,
 		storage:  options.Storage,
 		inMemory: options.InMemory,
-	}
-}
-
-func errorResponse(err error) *models.ErrorResponse {
-	return &models.ErrorResponse{
-		Error: err.Error(),
+		mailer:   options.Mailer,
 	}
 }
@@ -0,0 +1,226 @@
+package v1_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burxondv/note-template/api"
+	"github.com/burxondv/note-template/config"
+	"github.com/burxondv/note-template/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+type sentMail struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// fakeMailer captures messages instead of sending them over smtp,
+// with err set every message fails like a broken smtp server
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []sentMail
+	err  error
+}
+
+func (m *fakeMailer) Send(to []string, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+
+	m.sent = append(m.sent, sentMail{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+func (m *fakeMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.sent)
+}
+
+func (m *fakeMailer) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+// wait returns the n-th message once it was sent, for mail sent after the response
+func (m *fakeMailer) wait(t *testing.T, n int) sentMail {
+	require.Eventually(t, func() bool { return m.count() >= n }, time.Second, time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sent[n-1]
+}
+
+func (m *fakeMailer) last() sentMail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sent[len(m.sent)-1]
+}
+
+type fakeInMemory struct {
+	mu   sync.Mutex
+	data map[string]string
+	// getDelay delays the answer of Get to widen the window of check-then-act races
+	getDelay time.Duration
+}
+
+func (f *fakeInMemory) Set(ctx context.Context, key, value string, exp time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.data[key] = value
+	return nil
+}
+
+func (f *fakeInMemory) SetNX(ctx context.Context, key, value string, exp time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.data[key]; ok {
+		return false, nil
+	}
+	f.data[key] = value
+	return true, nil
+}
+
+func (f *fakeInMemory) Get(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	val, ok := f.data[key]
+	f.mu.Unlock()
+
+	// the answer is late like one coming over the network, the value may be stale by then
+	time.Sleep(f.getDelay)
+
+	if !ok {
+		return "", storage.ErrKeyNotFound
+	}
+	return val, nil
+}
+
+func (f *fakeInMemory) Delete(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.data, key)
+	return nil
+}
+
+func (f *fakeInMemory) GetDelete(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	val, ok := f.data[key]
+	if !ok {
+		return "", storage.ErrKeyNotFound
+	}
+	delete(f.data, key)
+	return val, nil
+}
+
+func (f *fakeInMemory) Incr(ctx context.Context, key string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var value int64
+	if stored, ok := f.data[key]; ok {
+		var err error
+		value, err = strconv.ParseInt(stored, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+	value++
+	f.data[key] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+type testServer struct {
+	cfg      *config.Config
+	router   *gin.Engine
+	storage  storage.StorageI
+	inMemory *fakeInMemory
+	mailer   *fakeMailer
+}
+
+func newTestServer() *testServer {
+	return newTestServerWithStorage(storage.NewStorageMemory())
+}
+
+// newTestServerWithStorage runs the api on strg, the tests use the memory storage
+// unless they need the behaviour of a real database
+func newTestServerWithStorage(strg storage.StorageI) *testServer {
+	ts := &testServer{
+		storage:  strg,
+		inMemory: &fakeInMemory{data: make(map[string]string)},
+		mailer:   &fakeMailer{},
+	}
+
+	ts.cfg = &config.Config{
+		AuthSecretKey:        "test-secret-that-is-32-bytes-long",
+		AccessTokenDuration:  15 * time.Minute,
+		RefreshTokenDuration: time.Hour,
+		PasswordHashCost:     bcrypt.MinCost,
+	}
+
+	ts.router = api.New(&api.RouterOptions{
+		Cfg:      ts.cfg,
+		Storage:  ts.storage,
+		InMemory: ts.inMemory,
+		Mailer:   ts.mailer,
+	})
+
+	return ts
+}
+
+func (ts *testServer) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	return ts.doAuth(method, path, "", body)
+}
+
+func (ts *testServer) doAuth(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	return ts.doHeaders(method, path, token, nil, body)
+}
+
+func (ts *testServer) doHeaders(method, path, token string, headers map[string]string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	ts.router.ServeHTTP(rec, req)
+
+	return rec
+}
@@ -0,0 +1,190 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authorizationHeaderKey  = "Authorization"
+	authorizationTypeBearer = "bearer"
+	authPayloadKey          = "auth_payload"
+	requestIDHeaderKey      = "X-Request-ID"
+	requestIDKey            = "request_id"
+	maxRequestIDLength      = 64
+)
+
+const (
+	PermissionManageUsers = "users:manage"
+)
+
+var ErrForbidden = errors.New("you don't have permission to perform this action")
+
+// rolePermissions lists what each role is allowed to do on top of managing its own data
+var rolePermissions = map[string][]string{
+	repo.RoleUser:  {},
+	repo.RoleAdmin: {PermissionManageUsers},
+}
+
+// RequestID reuses the X-Request-ID header of the client or generates a new one,
+// the id is echoed back in the response and put into the problem bodies
+func (h *handlerV1) RequestID(c *gin.Context) {
+	id := c.GetHeader(requestIDHeaderKey)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	c.Set(requestIDKey, id)
+	c.Header(requestIDHeaderKey, id)
+	c.Next()
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestTimeout bounds the request with the request timeout of the config,
+// the storage calls made with the context of the request are canceled once it passes
+func (h *handlerV1) RequestTimeout(c *gin.Context) {
+	if h.cfg.RequestTimeout <= 0 {
+		c.Next()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.RequestTimeout)
+	defer cancel()
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// AuthMiddleware rejects requests without a valid access or api token
+// and puts the token payload into the request context
+func (h *handlerV1) AuthMiddleware(c *gin.Context) {
+	token := c.GetHeader(authorizationHeaderKey)
+	if token == "" {
+		problemResponse(c, http.StatusUnauthorized, ErrUnauthorized)
+		return
+	}
+
+	fields := strings.Fields(token)
+	if len(fields) == 2 && strings.ToLower(fields[0]) == authorizationTypeBearer {
+		token = fields[1]
+	}
+
+	var (
+		payload *utils.Payload
+		err     error
+	)
+	if strings.HasPrefix(token, apiTokenPrefix) {
+		payload, err = h.verifyApiToken(c.Request.Context(), token)
+	} else {
+		payload, err = h.verifyToken(c.Request.Context(), token, utils.AccessToken)
+	}
+	if errors.Is(err, ErrUnauthorized) {
+		problemResponse(c, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Set(authPayloadKey, payload)
+	c.Next()
+}
+
+// RequirePermission only lets through users whose role grants the permission,
+// it must be used after AuthMiddleware
+func (h *handlerV1) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := h.storage.User().Get(c.Request.Context(), getAuthPayload(c).UserID)
+		if errors.Is(err, repo.ErrNotFound) {
+			problemResponse(c, http.StatusUnauthorized, ErrUnauthorized)
+			return
+		}
+		if err != nil {
+			problemResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !hasPermission(user.Role, permission) {
+			problemResponse(c, http.StatusForbidden, ErrForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScope rejects api tokens that were not granted the scope,
+// access tokens of a login session are allowed everything
+func (h *handlerV1) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload := getAuthPayload(c)
+
+		if payload.Type == utils.ApiToken && !hasScope(payload.Scopes, scope) {
+			problemResponse(c, http.StatusForbidden, ErrForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SessionOnly rejects api tokens, it guards account management endpoints
+func (h *handlerV1) SessionOnly(c *gin.Context) {
+	if getAuthPayload(c).Type == utils.ApiToken {
+		problemResponse(c, http.StatusForbidden, ErrForbidden)
+		return
+	}
+
+	c.Next()
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getAuthPayload(c *gin.Context) *utils.Payload {
+	return c.MustGet(authPayloadKey).(*utils.Payload)
+}
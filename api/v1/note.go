@@ -1,15 +1,28 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/burxondv/note-template/api/models"
 	"github.com/burxondv/note-template/storage/repo"
 	"github.com/gin-gonic/gin"
 )
 
-// @Router /notes [post]
+const (
+	searchModeTitle    = "title"
+	searchModeFullText = "fulltext"
+)
+
+var (
+	ErrNoteNotFound      = errors.New("note not found")
+	ErrUnknownSearchMode = errors.New("search_mode must be title or fulltext")
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/notes [post]
 // @Summary Create a note
 // @Description Create a note
 // @Tags note
@@ -17,7 +30,9 @@ import (
 // @Produce json
 // @Param note body models.CreateNoteRequest true "Note"
 // @Success 201 {object} models.Note
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) CreateNote(c *gin.Context) {
 	var (
 		req models.CreateNoteRequest
@@ -25,24 +40,39 @@ func (h *handlerV1) CreateNote(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		bindingError(c, err)
+		return
+	}
+
+	userID := getAuthPayload(c).UserID
+
+	user, err := h.storage.User().Get(c.Request.Context(), userID)
+	if err != nil {
+		storageError(c, err, nil)
 		return
 	}
 
-	resp, err := h.storage.Note().Create(&repo.Note{
-		UserID:      req.UserID,
+	if !user.IsActive {
+		problemResponse(c, http.StatusForbidden, ErrNotVerified)
+		return
+	}
+
+	resp, err := h.storage.Note().Create(c.Request.Context(), &repo.Note{
+		UserID:      userID,
 		Title:       req.Title,
 		Description: req.Description,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		storageError(c, err, nil)
 		return
 	}
 
+	setETag(c, resp.Version)
 	c.JSON(http.StatusCreated, parseNoteModel(resp))
 }
 
-// @Router /notes/{id} [get]
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id} [get]
 // @Summary Get note by id
 // @Description Get note by id
 // @Tags note
@@ -50,120 +80,290 @@ func (h *handlerV1) CreateNote(c *gin.Context) {
 // @Produce json
 // @Param id path int true "ID"
 // @Success 200 {object} models.Note
-// @Failure 500 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Version of the note"
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) GetNote(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
 		return
 	}
 
-	resp, err := h.storage.Note().Get(int64(id))
+	resp, err := h.storage.Note().Get(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		storageError(c, err, ErrNoteNotFound)
 		return
 	}
 
+	setETag(c, resp.Version)
 	c.JSON(http.StatusOK, parseNoteModel(resp))
 }
 
-// @Router /notes [get]
+// @Security ApiKeyAuth
+// @Router /v1/notes [get]
 // @Summary Get all notes
-// @Description Get all notes
+// @Description Get all notes, tags_any, tags_all and tags_exclude filter by comma separated tag ids,
+// @Description notebook_id keeps the notes of a notebook and include_descendants adds its nested notebooks,
+// @Description include_shared adds the notes other users shared with the current user.
+// @Description search_mode=fulltext searches the title and description with the web search syntax,
+// @Description orders the notes by relevance unless sort_by is given and adds highlighted fragments of the matches
 // @Tags note
 // @Accept json
 // @Produce json
 // @Param filter query models.GetAllNotesParams false "Filter"
 // @Success 200 {object} models.GetAllNotesResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) GetAllNotes(c *gin.Context) {
 	req, err := validateGetAllNoteParams(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := h.storage.Note().GetAll(&repo.GetAllNotesParams{
-		Page:       req.Page,
-		Limit:      req.Limit,
-		Search:     req.Search,
-		UserID:     req.UserID,
-		SortByData: req.SortByData,
-	})
+	params, err := getAllNotesParams(req, getAuthPayload(c).UserID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.storage.Note().GetAll(c.Request.Context(), params)
+	if err != nil {
+		storageError(c, err, nil)
 		return
 	}
 
 	c.JSON(http.StatusOK, getNoteResponse(result))
 }
 
-// @Router /notes/{id} [put]
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id} [put]
 // @Summary Update a note
-// @Description Update a note
+// @Description Update a note, If-Match must hold the ETag of the note the change is based on
 // @Tags note
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
+// @Param If-Match header string true "ETag of the note"
 // @Param note body models.UpdateNote true "Note"
 // @Success 200 {object} models.Note
-// @Failure 500 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Version of the note"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) UpdateNote(c *gin.Context) {
-	var req repo.Note
+	var req models.UpdateNote
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		bindingError(c, err)
 		return
 	}
 
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
 		return
 	}
-	req.ID = int64(id)
 
-	noteData, err := h.storage.Note().Get(req.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+	version, ok := ifMatchVersion(c)
+	if !ok {
 		return
 	}
-	updated, err := h.storage.Note().Update(&repo.Note{
-		ID:          noteData.ID,
-		UserID:      noteData.UserID,
+
+	updated, err := h.storage.Note().Update(c.Request.Context(), &repo.Note{
+		ID:          int64(id),
+		UserID:      getAuthPayload(c).UserID,
 		Title:       req.Title,
 		Description: req.Description,
-		CreatedAt:   noteData.CreatedAt,
-		UpdatedAt:   noteData.UpdatedAt,
-		DeletedAt:   noteData.DeletedAt,
+		Version:     version,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		storageError(c, err, ErrNoteNotFound)
 		return
 	}
 
+	setETag(c, updated.Version)
 	c.JSON(http.StatusCreated, parseNoteModel(updated))
 }
 
-// @Router /notes/{id} [delete]
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id} [patch]
+// @Summary Patch a note
+// @Description Change only the fields present in the body (RFC 7396 JSON merge patch), If-Match must hold the ETag of the note
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param If-Match header string true "ETag of the note"
+// @Param note body models.PatchNoteRequest true "Merge patch"
+// @Success 200 {object} models.Note
+// @Header 200 {string} ETag "Version of the note"
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) PatchNote(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseMergePatch(c, notePatchFields)
+	if !ok {
+		return
+	}
+
+	userID := getAuthPayload(c).UserID
+
+	var updated *repo.Note
+	if len(fields) == 0 {
+		// an empty patch changes nothing but still has to match the stored version
+		updated, err = h.storage.Note().Get(c.Request.Context(), int64(id), userID)
+		if err == nil && updated.Version != version {
+			err = repo.ErrVersionMismatch
+		}
+	} else {
+		updated, err = h.storage.Note().Patch(c.Request.Context(), &repo.NotePatch{
+			ID:      int64(id),
+			UserID:  userID,
+			Version: version,
+			Fields:  fields,
+		})
+	}
+	if err != nil {
+		storageError(c, err, ErrNoteNotFound)
+		return
+	}
+
+	setETag(c, updated.Version)
+	c.JSON(http.StatusOK, parseNoteModel(updated))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id} [delete]
 // @Summary Delete a note
-// @Description Delete a note
+// @Description Move a note to the trash
 // @Tags note
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
 // @Success 200 {object} models.ResponseOK
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) DeleteNote(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+	err = h.storage.Note().Delete(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, ErrNoteNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully deleted",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/trash [get]
+// @Summary Get notes in the trash
+// @Description Get notes in the trash
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAllNotesParams false "Filter"
+// @Success 200 {object} models.GetAllNotesResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetNotesTrash(c *gin.Context) {
+	req, err := validateGetAllNoteParams(c)
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	params, err := getAllNotesParams(req, getAuthPayload(c).UserID)
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+	params.Deleted = true
+
+	result, err := h.storage.Note().GetAll(c.Request.Context(), params)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, getNoteResponse(result))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/restore [post]
+// @Summary Restore a note
+// @Description Restore a note from the trash
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.Note
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) RestoreNote(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
 		return
 	}
-	err = h.storage.Note().Delete(int64(id))
+
+	resp, err := h.storage.Note().Restore(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		storageError(c, err, ErrNoteNotFound)
+		return
+	}
+
+	setETag(c, resp.Version)
+	c.JSON(http.StatusOK, parseNoteModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/permanent [delete]
+// @Summary Delete a note permanently
+// @Description Delete a note permanently, it can not be restored afterwards
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) PurgeNote(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.storage.Note().Purge(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, ErrNoteNotFound)
 		return
 	}
 
@@ -188,56 +388,149 @@ func getNoteResponse(data *repo.GetAllNotesResult) *models.GetAllNotesResponse {
 
 func validateGetAllNoteParams(c *gin.Context) (*models.GetAllNotesParams, error) {
 	var (
-		limit      int = 10
-		page       int = 1
-		err        error
-		userID     int
-		sortByDate string = "desc"
+		searchMode string = searchModeTitle
 	)
 
-	if c.Query("limit") != "" {
-		limit, err = strconv.Atoi(c.Query("limit"))
+	page, limit, err := pageParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := sortOrder(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var notebookID int
+	if c.Query("notebook_id") != "" {
+		notebookID, err = strconv.Atoi(c.Query("notebook_id"))
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if c.Query("page") != "" {
-		page, err = strconv.Atoi(c.Query("page"))
+	var includeDescendants bool
+	if c.Query("include_descendants") != "" {
+		includeDescendants, err = strconv.ParseBool(c.Query("include_descendants"))
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if c.Query("user_id") != "" {
-		userID, err = strconv.Atoi(c.Query("user_id"))
+	var includeShared bool
+	if c.Query("include_shared") != "" {
+		includeShared, err = strconv.ParseBool(c.Query("include_shared"))
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if c.Query("sort_by_date") != "" &&
-		(c.Query("sort_by_date") == "desc" || c.Query("sort_by_date") == "asc") {
-		sortByDate = c.Query("sort_by_date")
+	if c.Query("search_mode") != "" {
+		searchMode = c.Query("search_mode")
+		if searchMode != searchModeTitle && searchMode != searchModeFullText {
+			return nil, ErrUnknownSearchMode
+		}
 	}
 
 	return &models.GetAllNotesParams{
-		Limit:      int32(limit),
-		Page:       int32(page),
-		Search:     c.Query("search"),
-		UserID:     int64(userID),
-		SortByData: sortByDate,
+		Limit:              limit,
+		Page:               page,
+		Search:             c.Query("search"),
+		SearchMode:         searchMode,
+		SortBy:             c.Query("sort_by"),
+		SortOrder:          order,
+		TagsAny:            c.Query("tags_any"),
+		TagsAll:            c.Query("tags_all"),
+		TagsExclude:        c.Query("tags_exclude"),
+		NotebookID:         int64(notebookID),
+		IncludeDescendants: includeDescendants,
+		IncludeShared:      includeShared,
 	}, nil
 }
 
+func getAllNotesParams(req *models.GetAllNotesParams, userID int64) (*repo.GetAllNotesParams, error) {
+	params := repo.GetAllNotesParams{
+		Page:          req.Page,
+		Limit:         req.Limit,
+		Search:        req.Search,
+		UserID:        userID,
+		SortBy:        req.SortBy,
+		SortOrder:     req.SortOrder,
+		FullText:      req.SearchMode == searchModeFullText,
+		IncludeShared: req.IncludeShared,
+	}
+
+	if req.NotebookID != 0 {
+		params.NotebookID = &req.NotebookID
+		params.IncludeDescendants = req.IncludeDescendants
+	}
+
+	var err error
+
+	params.TagsAny, err = parseIDList(req.TagsAny)
+	if err != nil {
+		return nil, err
+	}
+
+	params.TagsAll, err = parseIDList(req.TagsAll)
+	if err != nil {
+		return nil, err
+	}
+
+	params.TagsExclude, err = parseIDList(req.TagsExclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return &params, nil
+}
+
+// parseIDList parses comma separated ids, duplicates are dropped
+func parseIDList(value string) ([]int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	seen := make(map[int64]bool)
+	result := make([]int64, 0)
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+
+	return result, nil
+}
+
 func parseNoteModel(note *repo.Note) models.Note {
 	return models.Note{
 		ID:          note.ID,
 		UserID:      note.UserID,
+		NotebookID:  note.NotebookID,
 		Title:       note.Title,
 		Description: note.Description,
+		Version:     note.Version,
+		Tags:        parseNoteTagsModel(note.Tags),
 		CreatedAt:   note.CreatedAt,
 		UpdatedAt:   note.UpdatedAt,
 		DeletedAt:   note.DeletedAt,
+		Highlight:   parseNoteHighlightModel(note.Highlight),
+	}
+}
+
+func parseNoteHighlightModel(highlight *repo.NoteHighlight) *models.NoteHighlight {
+	if highlight == nil {
+		return nil
+	}
+
+	return &models.NoteHighlight{
+		Title:       highlight.Title,
+		Description: highlight.Description,
 	}
 }
@@ -0,0 +1,361 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	noteLinkKeyPrefix      = "note_link:"
+	noteLinkViewsKeyPrefix = "note_link_views:"
+	noteLinkPasswordHeader = "X-Link-Password"
+	// maxLinkPasswordFailures wrong passwords lock a link for linkPasswordWindow,
+	// the password is not checked at all while it is locked
+	linkPasswordKeyPrefix   = "note_link_password_failures:"
+	linkPasswordWindow      = 15 * time.Minute
+	maxLinkPasswordFailures = 10
+)
+
+var (
+	ErrNoteLinkNotFound  = errors.New("share link not found")
+	ErrWrongLinkPassword = errors.New("share link password is missing or wrong")
+	ErrLinkPasswordLimit = errors.New("too many wrong passwords for the share link, try again later")
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/links [post]
+// @Summary Create a share link
+// @Description Create a public read-only link to a note, the note is served at /s/{token} without authorization.
+// @Description The link can expire, ask for a password in the X-Link-Password header and stop after max_views views
+// @Tags note-link
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param link body models.CreateNoteLinkRequest true "Link"
+// @Success 201 {object} models.CreateNoteLinkResponse
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) CreateNoteLink(c *gin.Context) {
+	var req models.CreateNoteLinkRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		problemResponse(c, http.StatusBadRequest, ErrExpiryInPast)
+		return
+	}
+
+	var password *string
+	if req.Password != "" {
+		hashed, err := utils.HashPassword(req.Password, h.cfg.PasswordHashCost)
+		if err != nil {
+			problemResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+		password = &hashed
+	}
+
+	token, err := utils.RandomString(32)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp, err := h.storage.NoteLink().Create(c.Request.Context(), &repo.NoteLink{
+		NoteID:    int64(id),
+		UserID:    getAuthPayload(c).UserID,
+		TokenHash: utils.HashToken(token),
+		Password:  password,
+		ExpiresAt: req.ExpiresAt,
+		MaxViews:  req.MaxViews,
+	})
+	if err != nil {
+		storageError(c, err, ErrNoteNotFound)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreateNoteLinkResponse{
+		NoteLink: parseNoteLinkModel(resp),
+		Token:    token,
+		Path:     "/s/" + token,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/links [get]
+// @Summary Get all share links
+// @Description Get the share links of the current user, revoked links are kept in the list
+// @Tags note-link
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllNoteLinksResponse
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetAllNoteLinks(c *gin.Context) {
+	result, err := h.storage.NoteLink().GetAll(c.Request.Context(), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	response := models.GetAllNoteLinksResponse{
+		Links: make([]*models.NoteLink, 0),
+		Count: len(result),
+	}
+
+	for _, link := range result {
+		l := parseNoteLinkModel(link)
+		response.Links = append(response.Links, &l)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/links/{id} [delete]
+// @Summary Revoke a share link
+// @Description Revoke a share link, it stops working immediately
+// @Tags note-link
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) RevokeNoteLink(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	link, err := h.storage.NoteLink().Revoke(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, ErrNoteLinkNotFound)
+		return
+	}
+
+	err = h.inMemory.Delete(c.Request.Context(), noteLinkKeyPrefix+link.TokenHash)
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.inMemory.Delete(c.Request.Context(), noteLinkViewsKeyPrefix+strconv.FormatInt(link.ID, 10))
+	if err != nil {
+		problemResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully revoked",
+	})
+}
+
+// @Router /s/{token} [get]
+// @Summary Open a share link
+// @Description Get the note behind a share link, it is served at /s/{token} outside of /v1 and needs no authorization.
+// @Description Links with a password expect it in the X-Link-Password header
+// @Tags note-link
+// @Accept json
+// @Produce json
+// @Param token path string true "Token"
+// @Param X-Link-Password header string false "Password of the link"
+// @Success 200 {object} models.PublicNote
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 410 {object} models.Problem
+// @Failure 429 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetPublicNote(c *gin.Context) {
+	tokenHash := utils.HashToken(c.Param("token"))
+
+	link, err := h.getNoteLink(c.Request.Context(), tokenHash)
+	if err != nil {
+		storageError(c, err, ErrNoteLinkNotFound)
+		return
+	}
+
+	if link.Password != nil {
+		err = h.checkLinkPassword(c.Request.Context(), tokenHash, c.GetHeader(noteLinkPasswordHeader), *link.Password)
+		if errors.Is(err, ErrWrongLinkPassword) {
+			problemResponse(c, http.StatusUnauthorized, err)
+			return
+		}
+		if errors.Is(err, ErrLinkPasswordLimit) {
+			problemResponse(c, http.StatusTooManyRequests, err)
+			return
+		}
+		if err != nil {
+			problemResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	note, err := h.storage.Note().Get(c.Request.Context(), link.NoteID, link.UserID)
+	if err != nil {
+		storageError(c, err, ErrNoteNotFound)
+		return
+	}
+
+	err = h.addNoteLinkView(c.Request.Context(), link)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PublicNote{
+		Title:       note.Title,
+		Description: note.Description,
+		CreatedAt:   note.CreatedAt,
+		UpdatedAt:   note.UpdatedAt,
+	})
+}
+
+// checkLinkPassword compares the password with the hash of the link and counts the
+// failures of the link, once there are too many it refuses without running bcrypt
+func (h *handlerV1) checkLinkPassword(ctx context.Context, tokenHash, password, hash string) error {
+	key := linkPasswordKeyPrefix + tokenHash
+
+	failures, err := h.inMemory.Get(ctx, key)
+	if err == nil {
+		count, err := strconv.ParseInt(failures, 10, 64)
+		if err != nil {
+			return err
+		}
+		if count >= maxLinkPasswordFailures {
+			return ErrLinkPasswordLimit
+		}
+	} else if !errors.Is(err, storage.ErrKeyNotFound) {
+		return err
+	}
+
+	err = utils.CheckPassword(password, hash)
+	if err != nil {
+		_, err = h.countAttempt(ctx, key, linkPasswordWindow)
+		if err != nil {
+			return err
+		}
+
+		return ErrWrongLinkPassword
+	}
+
+	return nil
+}
+
+// getNoteLink reads the link from the in-memory storage and falls back to postgres,
+// only usable links are cached and they expire together with the link
+func (h *handlerV1) getNoteLink(ctx context.Context, tokenHash string) (*repo.NoteLink, error) {
+	key := noteLinkKeyPrefix + tokenHash
+
+	cached, err := h.inMemory.Get(ctx, key)
+	if err == nil {
+		var link repo.NoteLink
+		err = json.Unmarshal([]byte(cached), &link)
+		if err != nil {
+			return nil, err
+		}
+
+		return &link, nil
+	}
+	if !errors.Is(err, storage.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	link, err := h.storage.NoteLink().GetByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := noteLinkTTL(link)
+	if link.RevokedAt != nil || ttl < 0 {
+		return nil, repo.ErrLinkUnavailable
+	}
+
+	value, err := json.Marshal(link)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.inMemory.Set(ctx, key, string(value), ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// addNoteLinkView counts the view in the in-memory storage first so links that used
+// up their views are turned away without a write, postgres still checks the limit
+// atomically and has the final word
+func (h *handlerV1) addNoteLinkView(ctx context.Context, link *repo.NoteLink) error {
+	if link.MaxViews != nil {
+		key := noteLinkViewsKeyPrefix + strconv.FormatInt(link.ID, 10)
+
+		_, err := h.inMemory.Get(ctx, key)
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			err = h.inMemory.Set(ctx, key, strconv.FormatInt(link.ViewCount, 10), noteLinkTTL(link))
+		}
+		if err != nil {
+			return err
+		}
+
+		views, err := h.inMemory.Incr(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if views > *link.MaxViews {
+			return repo.ErrLinkUnavailable
+		}
+	}
+
+	_, err := h.storage.NoteLink().AddView(ctx, link.ID)
+	return err
+}
+
+// noteLinkTTL is zero for links without expiry and negative for expired ones
+func noteLinkTTL(link *repo.NoteLink) time.Duration {
+	if link.ExpiresAt == nil {
+		return 0
+	}
+
+	ttl := time.Until(*link.ExpiresAt)
+	if ttl <= 0 {
+		return -1
+	}
+
+	return ttl
+}
+
+func parseNoteLinkModel(link *repo.NoteLink) models.NoteLink {
+	return models.NoteLink{
+		ID:          link.ID,
+		NoteID:      link.NoteID,
+		HasPassword: link.Password != nil,
+		ExpiresAt:   link.ExpiresAt,
+		MaxViews:    link.MaxViews,
+		ViewCount:   link.ViewCount,
+		RevokedAt:   link.RevokedAt,
+		CreatedAt:   link.CreatedAt,
+	}
+}
@@ -0,0 +1,163 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createNoteLink(t *testing.T, ts *testServer, token string, noteID int64, req models.CreateNoteLinkRequest) models.CreateNoteLinkResponse {
+	rec := ts.doAuth(http.MethodPost, fmt.Sprintf("/v1/notes/%d/links", noteID), token, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var link models.CreateNoteLinkResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &link))
+
+	return link
+}
+
+func TestPublicNoteLink(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	_, otherToken := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+
+	link := createNoteLink(t, ts, token, note.ID, models.CreateNoteLinkRequest{})
+	require.Equal(t, "/s/"+link.Token, link.Path)
+	require.False(t, link.HasPassword)
+
+	rec := ts.do(http.MethodGet, link.Path, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var public models.PublicNote
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &public))
+	require.Equal(t, note.Title, public.Title)
+	require.Equal(t, note.Description, public.Description)
+
+	rec = ts.do(http.MethodGet, "/s/unknown", nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/links", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var links models.GetAllNoteLinksResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &links))
+	require.Equal(t, 1, links.Count)
+	require.Equal(t, int64(1), links.Links[0].ViewCount)
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("/v1/links/%d", link.ID), otherToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("/v1/links/%d", link.ID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.do(http.MethodGet, link.Path, nil)
+	require.Equal(t, http.StatusGone, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, fmt.Sprintf("/v1/notes/%d/links", note.ID), otherToken, models.CreateNoteLinkRequest{})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPublicNoteLinkPassword(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+
+	link := createNoteLink(t, ts, token, note.ID, models.CreateNoteLinkRequest{Password: "open sesame"})
+	require.True(t, link.HasPassword)
+
+	rec := ts.do(http.MethodGet, link.Path, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.doHeaders(http.MethodGet, link.Path, "", map[string]string{"X-Link-Password": "wrong"}, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.doHeaders(http.MethodGet, link.Path, "", map[string]string{"X-Link-Password": "open sesame"}, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestPublicNoteLinkPasswordLimit(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+
+	link := createNoteLink(t, ts, token, note.ID, models.CreateNoteLinkRequest{Password: "open sesame"})
+	wrong := map[string]string{"X-Link-Password": "wrong"}
+
+	for i := 0; i < 10; i++ {
+		rec := ts.doHeaders(http.MethodGet, link.Path, "", wrong, nil)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	// the right password is refused as well while the link is locked
+	rec := ts.doHeaders(http.MethodGet, link.Path, "", map[string]string{"X-Link-Password": "open sesame"}, nil)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	require.NoError(t, ts.inMemory.Delete(context.Background(), "note_link_password_failures:"+utils.HashToken(link.Token)))
+
+	rec = ts.doHeaders(http.MethodGet, link.Path, "", map[string]string{"X-Link-Password": "open sesame"}, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestPublicNoteLinkMaxViews(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+
+	maxViews := int64(2)
+	link := createNoteLink(t, ts, token, note.ID, models.CreateNoteLinkRequest{MaxViews: &maxViews})
+
+	for i := 0; i < 2; i++ {
+		rec := ts.do(http.MethodGet, link.Path, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	rec := ts.do(http.MethodGet, link.Path, nil)
+	require.Equal(t, http.StatusGone, rec.Code)
+
+	// postgres keeps the limit when the in-memory counter is lost
+	require.NoError(t, ts.inMemory.Delete(context.Background(), fmt.Sprintf("note_link_views:%d", link.ID)))
+
+	rec = ts.do(http.MethodGet, link.Path, nil)
+	require.Equal(t, http.StatusGone, rec.Code)
+}
+
+func TestPublicNoteLinkExpiry(t *testing.T) {
+	ts := newTestServer()
+	user, token := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+
+	past := time.Now().Add(-time.Minute)
+	rec := ts.doAuth(http.MethodPost, fmt.Sprintf("/v1/notes/%d/links", note.ID), token, models.CreateNoteLinkRequest{
+		ExpiresAt: &past,
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	future := time.Now().Add(time.Hour)
+	link := createNoteLink(t, ts, token, note.ID, models.CreateNoteLinkRequest{ExpiresAt: &future})
+
+	rec = ts.do(http.MethodGet, link.Path, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// the api refuses an expiry in the past, store one directly
+	plain, err := utils.RandomString(32)
+	require.NoError(t, err)
+	_, err = ts.storage.NoteLink().Create(context.Background(), &repo.NoteLink{
+		NoteID:    note.ID,
+		UserID:    int64(user.ID),
+		TokenHash: utils.HashToken(plain),
+		ExpiresAt: &past,
+	})
+	require.NoError(t, err)
+
+	rec = ts.do(http.MethodGet, "/s/"+plain, nil)
+	require.Equal(t, http.StatusGone, rec.Code)
+}
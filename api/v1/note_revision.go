@@ -0,0 +1,206 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/revisions [get]
+// @Summary Get the revisions of a note
+// @Description Get the revisions of a note, newest first
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.GetAllNoteRevisionsResponse
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetAllNoteRevisions(c *gin.Context) {
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.storage.NoteRevision().GetAll(c.Request.Context(), int64(noteID), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	if len(result) == 0 {
+		problemResponse(c, http.StatusNotFound, ErrNoteNotFound)
+		return
+	}
+
+	response := models.GetAllNoteRevisionsResponse{
+		Revisions: make([]*models.NoteRevision, 0),
+		Count:     len(result),
+	}
+
+	for _, revision := range result {
+		r := parseNoteRevisionModel(revision)
+		response.Revisions = append(response.Revisions, &r)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/revisions/{revision_id} [get]
+// @Summary Get a revision of a note
+// @Description Get a revision of a note
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param revision_id path int true "Revision ID"
+// @Success 200 {object} models.NoteRevision
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetNoteRevision(c *gin.Context) {
+	revision, ok := h.getNoteRevision(c, c.Param("revision_id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNoteRevisionModel(revision))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/revisions/diff [get]
+// @Summary Compare two revisions of a note
+// @Description Get a line-level diff of the title and description between two revisions
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param filter query models.NoteRevisionDiffParams true "Revisions"
+// @Success 200 {object} models.NoteRevisionDiff
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetNoteRevisionDiff(c *gin.Context) {
+	from, ok := h.getNoteRevision(c, c.Query("from"))
+	if !ok {
+		return
+	}
+
+	to, ok := h.getNoteRevision(c, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NoteRevisionDiff{
+		From:        from.ID,
+		To:          to.ID,
+		Title:       parseDiffModel(utils.DiffLines(from.Title, to.Title)),
+		Description: parseDiffModel(utils.DiffLines(from.Description, to.Description)),
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/revisions/{revision_id}/restore [post]
+// @Summary Restore a revision of a note
+// @Description Bring the note back to the content of a revision, the restore itself is recorded as a new revision
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param revision_id path int true "Revision ID"
+// @Success 200 {object} models.Note
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) RestoreNoteRevision(c *gin.Context) {
+	revision, ok := h.getNoteRevision(c, c.Param("revision_id"))
+	if !ok {
+		return
+	}
+
+	userID := getAuthPayload(c).UserID
+
+	// reading the version and updating in one transaction keeps a concurrent edit
+	// from failing the restore with a version mismatch, the transaction is retried instead
+	var updated *repo.Note
+	err := h.storage.WithTx(c.Request.Context(), func(strg storage.StorageI) error {
+		note, err := strg.Note().Get(c.Request.Context(), revision.NoteID, userID)
+		if err != nil {
+			return err
+		}
+
+		updated, err = strg.Note().Update(c.Request.Context(), &repo.Note{
+			ID:          revision.NoteID,
+			UserID:      userID,
+			Title:       revision.Title,
+			Description: revision.Description,
+			Version:     note.Version,
+		})
+		return err
+	})
+	if err != nil {
+		storageError(c, err, ErrNoteNotFound)
+		return
+	}
+
+	setETag(c, updated.Version)
+	c.JSON(http.StatusOK, parseNoteModel(updated))
+}
+
+// getNoteRevision loads a revision of the note in the path and writes
+// the error response itself, ok is false when the handler should stop
+func (h *handlerV1) getNoteRevision(c *gin.Context, revisionID string) (*repo.NoteRevision, bool) {
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(revisionID)
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	revision, err := h.storage.NoteRevision().Get(c.Request.Context(), int64(id), int64(noteID), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, ErrRevisionNotFound)
+		return nil, false
+	}
+
+	return revision, true
+}
+
+func parseNoteRevisionModel(revision *repo.NoteRevision) models.NoteRevision {
+	return models.NoteRevision{
+		ID:          revision.ID,
+		NoteID:      revision.NoteID,
+		AuthorID:    revision.AuthorID,
+		Title:       revision.Title,
+		Description: revision.Description,
+		CreatedAt:   revision.CreatedAt,
+	}
+}
+
+func parseDiffModel(lines []utils.DiffLine) []*models.DiffLine {
+	result := make([]*models.DiffLine, 0, len(lines))
+
+	for _, line := range lines {
+		result = append(result, &models.DiffLine{
+			Op:   line.Op,
+			Text: line.Text,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,109 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func getRevisions(t *testing.T, ts *testServer, noteID int64, token string) models.GetAllNoteRevisionsResponse {
+	rec := ts.doAuth(http.MethodGet, fmt.Sprintf("/v1/notes/%d/revisions", noteID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp models.GetAllNoteRevisionsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	return resp
+}
+
+func TestNoteRevisions(t *testing.T) {
+	ts := newTestServer()
+	user, token := loggedInUser(t, ts)
+	_, otherToken := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+	path := fmt.Sprintf("/v1/notes/%d", note.ID)
+
+	rec := ts.doHeaders(http.MethodPut, path, token, ifMatch(note.Version), models.UpdateNote{
+		Title:       "second",
+		Description: "line one\nline two",
+	})
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var updated models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	require.NotNil(t, updated.UpdatedAt)
+
+	revisions := getRevisions(t, ts, note.ID, token)
+	require.Equal(t, 2, revisions.Count)
+	require.Equal(t, "second", revisions.Revisions[0].Title)
+	require.Equal(t, note.Title, revisions.Revisions[1].Title)
+	require.NotNil(t, revisions.Revisions[0].AuthorID)
+	require.Equal(t, int64(user.ID), *revisions.Revisions[0].AuthorID)
+
+	first := revisions.Revisions[1]
+
+	rec = ts.doAuth(http.MethodGet, fmt.Sprintf("%s/revisions/%d", path, first.ID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, fmt.Sprintf("%s/revisions/%d", path, first.ID), otherToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, fmt.Sprintf("/v1/notes/%d/revisions", note.ID), otherToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, fmt.Sprintf("%s/revisions/%d/restore", path, first.ID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var restored models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &restored))
+	require.Equal(t, note.Title, restored.Title)
+	require.Equal(t, note.Description, restored.Description)
+
+	revisions = getRevisions(t, ts, note.ID, token)
+	require.Equal(t, 3, revisions.Count)
+	require.Equal(t, note.Title, revisions.Revisions[0].Title)
+}
+
+func TestNoteRevisionDiff(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	rec := ts.doAuth(http.MethodPost, "/v1/notes", token, models.CreateNoteRequest{
+		Title:       "title",
+		Description: "a\nb\nc",
+	})
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var note models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	path := fmt.Sprintf("/v1/notes/%d", note.ID)
+
+	rec = ts.doHeaders(http.MethodPut, path, token, ifMatch(note.Version), models.UpdateNote{
+		Title:       "title",
+		Description: "a\nc\nd",
+	})
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	revisions := getRevisions(t, ts, note.ID, token)
+	to, from := revisions.Revisions[0], revisions.Revisions[1]
+
+	rec = ts.doAuth(http.MethodGet, fmt.Sprintf("%s/revisions/diff?from=%d&to=%d", path, from.ID, to.ID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var diff models.NoteRevisionDiff
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &diff))
+	require.Equal(t, []*models.DiffLine{{Op: "equal", Text: "title"}}, diff.Title)
+	require.Equal(t, []*models.DiffLine{
+		{Op: "equal", Text: "a"},
+		{Op: "delete", Text: "b"},
+		{Op: "equal", Text: "c"},
+		{Op: "insert", Text: "d"},
+	}, diff.Description)
+
+	rec = ts.doAuth(http.MethodGet, fmt.Sprintf("%s/revisions/diff?from=%d&to=999", path, from.ID), token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,216 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrShareNotFound = errors.New("share not found")
+	// ErrShareTargetNotFound is the same for a note the user does not own and an unknown
+	// email, so sharing can not be used to find out which emails are registered
+	ErrShareTargetNotFound = errors.New("note or user with this email not found")
+	ErrShareWithYourself   = errors.New("note can not be shared with its owner")
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/shares [post]
+// @Summary Share a note
+// @Description Give another user viewer or editor access to a note, viewers can read the note
+// @Description and its revisions, editors can also update it. Only the owner can share a note,
+// @Description an unknown email gets the same 404 as a note the user does not own
+// @Tags note-share
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param share body models.CreateNoteShareRequest true "Share"
+// @Success 201 {object} models.NoteShare
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) CreateNoteShare(c *gin.Context) {
+	var req models.CreateNoteShareRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ownerID := getAuthPayload(c).UserID
+
+	note, err := h.storage.Note().Get(c.Request.Context(), int64(id), ownerID)
+	if err == nil && note.UserID != ownerID {
+		err = repo.ErrNotFound
+	}
+	if err != nil {
+		storageError(c, err, ErrShareTargetNotFound)
+		return
+	}
+
+	user, err := h.storage.User().GetByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		storageError(c, err, ErrShareTargetNotFound)
+		return
+	}
+
+	if user.ID == ownerID {
+		problemResponse(c, http.StatusBadRequest, ErrShareWithYourself)
+		return
+	}
+
+	resp, err := h.storage.NoteShare().Create(c.Request.Context(), ownerID, &repo.NoteShare{
+		NoteID:     int64(id),
+		UserID:     user.ID,
+		Permission: req.Permission,
+	})
+	if err != nil {
+		storageError(c, err, ErrShareTargetNotFound)
+		return
+	}
+
+	c.JSON(http.StatusCreated, parseNoteShareModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/shares [get]
+// @Summary Get the shares of a note
+// @Description Get the users a note is shared with, only the owner can list them
+// @Tags note-share
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.GetAllNoteSharesResponse
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetAllNoteShares(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.storage.NoteShare().GetAll(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, ErrNoteNotFound)
+		return
+	}
+
+	response := models.GetAllNoteSharesResponse{
+		Shares: make([]*models.NoteShare, 0),
+		Count:  len(result),
+	}
+
+	for _, share := range result {
+		s := parseNoteShareModel(share)
+		response.Shares = append(response.Shares, &s)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/shares/{user_id} [put]
+// @Summary Change the permission of a share
+// @Description Change the permission of a user the note is shared with
+// @Tags note-share
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param user_id path int true "User ID"
+// @Param share body models.UpdateNoteShareRequest true "Share"
+// @Success 200 {object} models.NoteShare
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) UpdateNoteShare(c *gin.Context) {
+	var req models.UpdateNoteShareRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.storage.NoteShare().Update(c.Request.Context(), getAuthPayload(c).UserID, &repo.NoteShare{
+		NoteID:     int64(id),
+		UserID:     int64(userID),
+		Permission: req.Permission,
+	})
+	if err != nil {
+		storageError(c, err, ErrShareNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNoteShareModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/shares/{user_id} [delete]
+// @Summary Revoke a share
+// @Description Take back the access of a user to the note
+// @Tags note-share
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) DeleteNoteShare(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.storage.NoteShare().Delete(c.Request.Context(), int64(id), getAuthPayload(c).UserID, int64(userID))
+	if err != nil {
+		storageError(c, err, ErrShareNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully deleted",
+	})
+}
+
+func parseNoteShareModel(share *repo.NoteShare) models.NoteShare {
+	return models.NoteShare{
+		NoteID:     share.NoteID,
+		UserID:     share.UserID,
+		Email:      share.Email,
+		Permission: share.Permission,
+		CreatedAt:  share.CreatedAt,
+		UpdatedAt:  share.UpdatedAt,
+	}
+}
@@ -0,0 +1,154 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func shareNote(t *testing.T, ts *testServer, token string, noteID int64, email, permission string) models.NoteShare {
+	rec := ts.doAuth(http.MethodPost, fmt.Sprintf("/v1/notes/%d/shares", noteID), token, models.CreateNoteShareRequest{
+		Email:      email,
+		Permission: permission,
+	})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var share models.NoteShare
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &share))
+
+	return share
+}
+
+func TestNoteSharePermissions(t *testing.T) {
+	ts := newTestServer()
+	owner, ownerToken := loggedInUser(t, ts)
+	viewer, viewerToken := loggedInUser(t, ts)
+	editor, editorToken := loggedInUser(t, ts)
+	_, strangerToken := loggedInUser(t, ts)
+
+	note := createNote(t, ts, ownerToken)
+	path := fmt.Sprintf("/v1/notes/%d", note.ID)
+
+	shareNote(t, ts, ownerToken, note.ID, viewer.Email, "viewer")
+	share := shareNote(t, ts, ownerToken, note.ID, editor.Email, "editor")
+	require.Equal(t, int64(editor.ID), share.UserID)
+	require.Equal(t, editor.Email, share.Email)
+
+	rec := ts.doAuth(http.MethodGet, path, viewerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = ts.doAuth(http.MethodGet, path+"/revisions", viewerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = ts.doAuth(http.MethodGet, path, strangerToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	update := models.UpdateNote{Title: "shared", Description: "changed by the editor"}
+	rec = ts.doHeaders(http.MethodPut, path, viewerToken, ifMatch(note.Version), update)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	rec = ts.doHeaders(http.MethodPatch, path, viewerToken, ifMatch(note.Version), map[string]interface{}{"title": "x"})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPut, path, editorToken, ifMatch(note.Version), update)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var updated models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	require.Equal(t, int64(owner.ID), updated.UserID)
+	require.Equal(t, update.Title, updated.Title)
+
+	rec = ts.doAuth(http.MethodDelete, path, editorToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	rec = ts.doAuth(http.MethodGet, path+"/shares", editorToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodPut, fmt.Sprintf("%s/shares/%d", path, viewer.ID), ownerToken, models.UpdateNoteShareRequest{
+		Permission: "editor",
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = ts.doHeaders(http.MethodPut, path, viewerToken, ifMatch(updated.Version), update)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("%s/shares/%d", path, viewer.ID), ownerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = ts.doAuth(http.MethodGet, path, viewerToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path+"/shares", ownerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var shares models.GetAllNoteSharesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shares))
+	require.Equal(t, 1, shares.Count)
+	require.Equal(t, int64(editor.ID), shares.Shares[0].UserID)
+}
+
+func TestCreateNoteShareErrors(t *testing.T) {
+	ts := newTestServer()
+	owner, ownerToken := loggedInUser(t, ts)
+	other, otherToken := loggedInUser(t, ts)
+
+	note := createNote(t, ts, ownerToken)
+	path := fmt.Sprintf("/v1/notes/%d/shares", note.ID)
+
+	tests := []struct {
+		name  string
+		token string
+		body  models.CreateNoteShareRequest
+		code  int
+	}{
+		{"unknown email", ownerToken, models.CreateNoteShareRequest{Email: "nobody@example.com", Permission: "viewer"}, http.StatusNotFound},
+		{"owner", ownerToken, models.CreateNoteShareRequest{Email: owner.Email, Permission: "viewer"}, http.StatusBadRequest},
+		{"bad permission", ownerToken, models.CreateNoteShareRequest{Email: other.Email, Permission: "admin"}, http.StatusUnprocessableEntity},
+		{"not the owner", otherToken, models.CreateNoteShareRequest{Email: owner.Email, Permission: "viewer"}, http.StatusNotFound},
+		{"not the owner and unknown email", otherToken, models.CreateNoteShareRequest{Email: "nobody@example.com", Permission: "viewer"}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.doAuth(http.MethodPost, path, tt.token, tt.body)
+			require.Equal(t, tt.code, rec.Code, rec.Body.String())
+		})
+	}
+
+	// an unknown email can not be told apart from a note of someone else
+	unknown := decodeProblem(t, ts.doAuth(http.MethodPost, path, ownerToken, models.CreateNoteShareRequest{Email: "nobody@example.com", Permission: "viewer"}))
+	notOwned := decodeProblem(t, ts.doAuth(http.MethodPost, path, otherToken, models.CreateNoteShareRequest{Email: owner.Email, Permission: "viewer"}))
+	require.Equal(t, notOwned.Code, unknown.Code)
+	require.Equal(t, notOwned.Detail, unknown.Detail)
+
+	shareNote(t, ts, ownerToken, note.ID, other.Email, "viewer")
+	rec := ts.doAuth(http.MethodPost, path, ownerToken, models.CreateNoteShareRequest{
+		Email:      other.Email,
+		Permission: "editor",
+	})
+	require.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestGetAllNotesIncludeShared(t *testing.T) {
+	ts := newTestServer()
+	_, ownerToken := loggedInUser(t, ts)
+	viewer, viewerToken := loggedInUser(t, ts)
+
+	shared := createNote(t, ts, ownerToken)
+	createNote(t, ts, ownerToken)
+	own := createNote(t, ts, viewerToken)
+	shareNote(t, ts, ownerToken, shared.ID, viewer.Email, "viewer")
+
+	var list models.GetAllNotesResponse
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notes", viewerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.ElementsMatch(t, []int64{own.ID}, noteIDs(list))
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes?include_shared=true", viewerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.ElementsMatch(t, []int64{own.ID, shared.ID}, noteIDs(list))
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes?include_shared=maybe", viewerToken, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,338 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func loggedInUser(t *testing.T, ts *testServer) (models.User, string) {
+	user := verifiedUser(t, ts)
+	tokens := login(t, ts, user)
+
+	return user, tokens.AccessToken
+}
+
+func createNote(t *testing.T, ts *testServer, token string) models.Note {
+	rec := ts.doAuth(http.MethodPost, "/v1/notes", token, models.CreateNoteRequest{
+		Title:       faker.Word(),
+		Description: faker.Sentence(),
+	})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var note models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+
+	return note
+}
+
+func TestCreateNoteOwner(t *testing.T) {
+	ts := newTestServer()
+	user, token := loggedInUser(t, ts)
+
+	note := createNote(t, ts, token)
+	require.Equal(t, int64(user.ID), note.UserID)
+}
+
+func TestCreateNoteValidation(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	tests := []struct {
+		name    string
+		req     models.CreateNoteRequest
+		field   string
+		message string
+	}{
+		{"missing title", models.CreateNoteRequest{Description: "text"}, "title", "is required"},
+		{"blank title", models.CreateNoteRequest{Title: "   "}, "title", "must not be blank"},
+		{"long title", models.CreateNoteRequest{Title: strings.Repeat("a", 61)}, "title", "must be at most 60 characters long"},
+		{"long description", models.CreateNoteRequest{Title: "title", Description: strings.Repeat("b", 101)}, "description", "must be at most 100 characters long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.doAuth(http.MethodPost, "/v1/notes", token, tt.req)
+			require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+			require.Equal(t, []models.FieldError{{Field: tt.field, Message: tt.message}}, decodeProblem(t, rec).Errors)
+		})
+	}
+
+	rec := ts.doAuth(http.MethodPost, "/v1/notes", token, models.CreateNoteRequest{
+		Title:       strings.Repeat("ü", 60),
+		Description: strings.Repeat("ö", 100),
+	})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func TestNoteOwnership(t *testing.T) {
+	ts := newTestServer()
+	_, ownerToken := loggedInUser(t, ts)
+	_, otherToken := loggedInUser(t, ts)
+
+	note := createNote(t, ts, ownerToken)
+	path := fmt.Sprintf("/v1/notes/%d", note.ID)
+
+	rec := ts.doAuth(http.MethodGet, path, otherToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPut, path, otherToken, ifMatch(note.Version), models.UpdateNote{
+		Title:       "stolen",
+		Description: "stolen",
+	})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodDelete, path, otherToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes", otherToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var list models.GetAllNotesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Empty(t, list.Notes)
+
+	rec = ts.doAuth(http.MethodGet, path, ownerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, note.Title, got.Title)
+}
+
+func getNotes(t *testing.T, ts *testServer, path, token string) models.GetAllNotesResponse {
+	rec := ts.doAuth(http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var list models.GetAllNotesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+
+	return list
+}
+
+func TestNoteTrash(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+	path := fmt.Sprintf("/v1/notes/%d", note.ID)
+
+	rec := ts.doAuth(http.MethodDelete, path, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.Empty(t, getNotes(t, ts, "/v1/notes", token).Notes)
+
+	trash := getNotes(t, ts, "/v1/notes/trash", token)
+	require.Len(t, trash.Notes, 1)
+	require.NotNil(t, trash.Notes[0].DeletedAt)
+
+	rec = ts.doAuth(http.MethodDelete, path, token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, path+"/restore", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = ts.doAuth(http.MethodPost, path+"/restore", token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, getNotes(t, ts, "/v1/notes/trash", token).Notes)
+}
+
+func TestPurgeNote(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	_, otherToken := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+	path := fmt.Sprintf("/v1/notes/%d", note.ID)
+
+	rec := ts.doAuth(http.MethodDelete, path+"/permanent", otherToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodDelete, path+"/permanent", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, path+"/restore", token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Empty(t, getNotes(t, ts, "/v1/notes/trash", token).Notes)
+}
+
+func ifMatch(version int64) map[string]string {
+	return map[string]string{"If-Match": fmt.Sprintf(`"%d"`, version)}
+}
+
+func TestUpdateNoteIfMatch(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+	path := fmt.Sprintf("/v1/notes/%d", note.ID)
+
+	rec := ts.doAuth(http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.Equal(t, `"1"`, etag)
+
+	update := models.UpdateNote{Title: "first device", Description: "first"}
+
+	rec = ts.doAuth(http.MethodPut, path, token, update)
+	require.Equal(t, http.StatusPreconditionRequired, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPut, path, token, map[string]string{"If-Match": "abc"}, update)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// a weak ETag never matches, even with the current version
+	rec = ts.doHeaders(http.MethodPut, path, token, map[string]string{"If-Match": "W/" + etag}, update)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPut, path, token, map[string]string{"If-Match": etag}, update)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	rec = ts.doHeaders(http.MethodPut, path, token, map[string]string{"If-Match": etag}, models.UpdateNote{
+		Title:       "second device",
+		Description: "second",
+	})
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, "first device", got.Title)
+	require.Equal(t, int64(2), got.Version)
+}
+
+func TestPatchNote(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	note := createNote(t, ts, token)
+	path := fmt.Sprintf("/v1/notes/%d", note.ID)
+
+	rec := ts.doHeaders(http.MethodPatch, path, token, ifMatch(note.Version), map[string]interface{}{
+		"title": "patched",
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var got models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, "patched", got.Title)
+	require.Equal(t, note.Description, got.Description)
+	require.Equal(t, note.Version+1, got.Version)
+
+	rec = ts.doHeaders(http.MethodPatch, path, token, ifMatch(got.Version), map[string]interface{}{
+		"title": nil,
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPatch, path, token, ifMatch(got.Version), map[string]interface{}{
+		"user_id": 2,
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPatch, path, token, ifMatch(got.Version), []string{"title"})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPatch, path, token, ifMatch(note.Version), map[string]interface{}{
+		"description": "stale",
+	})
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPatch, path, token, ifMatch(got.Version), map[string]interface{}{})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, fmt.Sprintf(`"%d"`, got.Version), rec.Header().Get("ETag"))
+
+	rec = ts.doHeaders(http.MethodPatch, path, token, ifMatch(note.Version), map[string]interface{}{})
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
+
+func TestGetAllNotesFullTextSearch(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	create := func(title, description string) models.Note {
+		rec := ts.doAuth(http.MethodPost, "/v1/notes", token, models.CreateNoteRequest{
+			Title:       title,
+			Description: description,
+		})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var note models.Note
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+		return note
+	}
+
+	inDescription := create("groceries", "buy milk and bread")
+	inTitle := create("milk prices", "compare the shops")
+	create("gym", "leg day")
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notes?search_mode=fulltext&search=milk", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var list models.GetAllNotesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Equal(t, []int64{inTitle.ID, inDescription.ID}, noteIDs(list))
+	require.NotNil(t, list.Notes[0].Highlight)
+	require.Equal(t, "<mark>milk</mark> prices", list.Notes[0].Highlight.Title)
+	require.Contains(t, list.Notes[1].Highlight.Description, "<mark>milk</mark>")
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotContains(t, rec.Body.String(), `"highlight"`)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes?search_mode=regex&search=milk", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetAllNotesPage(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	for i := 0; i < 3; i++ {
+		createNote(t, ts, token)
+	}
+
+	for _, query := range []string{"page=0", "page=-1", "limit=0", "limit=-5"} {
+		rec := ts.doAuth(http.MethodGet, "/v1/notes?"+query, token, nil)
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notes?page=2&limit=2", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var list models.GetAllNotesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Notes, 1)
+	require.Equal(t, int32(3), list.Count)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes?limit=100000", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestGetAllNotesSort(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notes?sort_by=title&sort_order=asc", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes?sort_order=sideways", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes?search_mode=fulltext&search=milk&sort_order=sideways", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes?sort_by=user_id", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes/trash?sort_by=title%3BDROP", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,346 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrEmptyNotebookName      = errors.New("notebook name can not be empty")
+	ErrParentNotebookNotFound = errors.New("parent notebook not found")
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/notebooks [post]
+// @Summary Create a notebook
+// @Description Create a notebook, parent_id nests it in another notebook
+// @Tags notebook
+// @Accept json
+// @Produce json
+// @Param notebook body models.CreateNotebookRequest true "Notebook"
+// @Success 201 {object} models.Notebook
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) CreateNotebook(c *gin.Context) {
+	var req models.CreateNotebookRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		problemResponse(c, http.StatusBadRequest, ErrEmptyNotebookName)
+		return
+	}
+
+	resp, err := h.storage.Notebook().Create(c.Request.Context(), &repo.Notebook{
+		UserID:   getAuthPayload(c).UserID,
+		ParentID: req.ParentID,
+		Name:     name,
+	})
+	if !h.notebookErrorResponse(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, parseNotebookModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notebooks/{id} [get]
+// @Summary Get notebook by id
+// @Description Get notebook by id
+// @Tags notebook
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.Notebook
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetNotebook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.storage.Notebook().Get(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
+	if !h.notebookErrorResponse(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNotebookModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notebooks [get]
+// @Summary Get all notebooks
+// @Description Get all notebooks of the current user as a flat list
+// @Tags notebook
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllNotebooksResponse
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetAllNotebooks(c *gin.Context) {
+	result, err := h.storage.Notebook().GetAll(c.Request.Context(), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	response := models.GetAllNotebooksResponse{
+		Notebooks: make([]*models.Notebook, 0),
+		Count:     len(result),
+	}
+
+	for _, notebook := range result {
+		n := parseNotebookModel(notebook)
+		response.Notebooks = append(response.Notebooks, &n)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notebooks/tree [get]
+// @Summary Get the notebook tree
+// @Description Get the full hierarchy of notebooks with the note counts of every notebook
+// @Tags notebook
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetNotebookTreeResponse
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetNotebookTree(c *gin.Context) {
+	result, err := h.storage.Notebook().GetAll(c.Request.Context(), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.GetNotebookTreeResponse{
+		Notebooks: buildNotebookTree(result),
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notebooks/{id} [put]
+// @Summary Rename a notebook
+// @Description Rename a notebook
+// @Tags notebook
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param notebook body models.RenameNotebookRequest true "Notebook"
+// @Success 200 {object} models.Notebook
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) RenameNotebook(c *gin.Context) {
+	var req models.RenameNotebookRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		problemResponse(c, http.StatusBadRequest, ErrEmptyNotebookName)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.storage.Notebook().Rename(c.Request.Context(), int64(id), getAuthPayload(c).UserID, name)
+	if !h.notebookErrorResponse(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNotebookModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notebooks/{id}/move [post]
+// @Summary Move a notebook
+// @Description Move a notebook under another notebook, a null parent_id moves it to the top level
+// @Tags notebook
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param parent body models.MoveNotebookRequest true "Parent"
+// @Success 200 {object} models.Notebook
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) MoveNotebook(c *gin.Context) {
+	var req models.MoveNotebookRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.storage.Notebook().Move(c.Request.Context(), int64(id), getAuthPayload(c).UserID, req.ParentID)
+	if !h.notebookErrorResponse(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNotebookModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notebooks/{id} [delete]
+// @Summary Delete a notebook
+// @Description Delete a notebook with its nested notebooks, their notes are moved to the top level
+// @Tags notebook
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) DeleteNotebook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.storage.Notebook().Delete(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
+	if !h.notebookErrorResponse(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully deleted",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/move [post]
+// @Summary Move a note to a notebook
+// @Description Move a note to a notebook, a null notebook_id moves it to the top level
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param notebook body models.MoveNoteRequest true "Notebook"
+// @Success 200 {object} models.Note
+// @Failure 404 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) MoveNote(c *gin.Context) {
+	var req models.MoveNoteRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.storage.Note().Move(c.Request.Context(), int64(id), getAuthPayload(c).UserID, req.NotebookID)
+	if err != nil {
+		storageError(c, err, ErrNoteNotFound)
+		return
+	}
+
+	setETag(c, resp.Version)
+	c.JSON(http.StatusOK, parseNoteModel(resp))
+}
+
+// notebookErrorResponse writes the response for a failed notebook call,
+// it returns true when there was no error and the handler should go on
+func (h *handlerV1) notebookErrorResponse(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, repo.ErrNotebookNotFound) {
+		// the notebook in the path exists, it is the parent in the body that is missing
+		writeProblem(c, newProblem(c, http.StatusUnprocessableEntity, CodeNotebookNotFound, ErrParentNotebookNotFound.Error()))
+		return false
+	}
+
+	storageError(c, err, repo.ErrNotebookNotFound)
+	return false
+}
+
+// buildNotebookTree nests the flat list of notebooks under their parents
+// and sums the note counts of every subtree
+func buildNotebookTree(notebooks []*repo.Notebook) []*models.NotebookNode {
+	nodes := make(map[int64]*models.NotebookNode, len(notebooks))
+	for _, notebook := range notebooks {
+		nodes[notebook.ID] = &models.NotebookNode{
+			ID:        notebook.ID,
+			Name:      notebook.Name,
+			NoteCount: notebook.NoteCount,
+			Children:  make([]*models.NotebookNode, 0),
+		}
+	}
+
+	roots := make([]*models.NotebookNode, 0)
+	for _, notebook := range notebooks {
+		var parent *models.NotebookNode
+		if notebook.ParentID != nil {
+			parent = nodes[*notebook.ParentID]
+		}
+
+		if parent == nil {
+			roots = append(roots, nodes[notebook.ID])
+		} else {
+			parent.Children = append(parent.Children, nodes[notebook.ID])
+		}
+	}
+
+	for _, root := range roots {
+		sumNoteCounts(root)
+	}
+
+	return roots
+}
+
+func sumNoteCounts(node *models.NotebookNode) int64 {
+	node.TotalNoteCount = node.NoteCount
+	for _, child := range node.Children {
+		node.TotalNoteCount += sumNoteCounts(child)
+	}
+
+	return node.TotalNoteCount
+}
+
+func parseNotebookModel(notebook *repo.Notebook) models.Notebook {
+	return models.Notebook{
+		ID:        notebook.ID,
+		ParentID:  notebook.ParentID,
+		Name:      notebook.Name,
+		NoteCount: notebook.NoteCount,
+		CreatedAt: notebook.CreatedAt,
+		UpdatedAt: notebook.UpdatedAt,
+	}
+}
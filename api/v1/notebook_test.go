@@ -0,0 +1,144 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func createNotebook(t *testing.T, ts *testServer, token, name string, parentID *int64) models.Notebook {
+	rec := ts.doAuth(http.MethodPost, "/v1/notebooks", token, models.CreateNotebookRequest{
+		Name:     name,
+		ParentID: parentID,
+	})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var notebook models.Notebook
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notebook))
+
+	return notebook
+}
+
+func moveNote(t *testing.T, ts *testServer, token string, noteID int64, notebookID *int64) *httptest.ResponseRecorder {
+	return ts.doAuth(http.MethodPost, fmt.Sprintf("/v1/notes/%d/move", noteID), token, models.MoveNoteRequest{
+		NotebookID: notebookID,
+	})
+}
+
+func TestNotebookCycle(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	_, otherToken := loggedInUser(t, ts)
+
+	root := createNotebook(t, ts, token, "root", nil)
+	child := createNotebook(t, ts, token, "child", &root.ID)
+	grandchild := createNotebook(t, ts, token, "grandchild", &child.ID)
+	foreign := createNotebook(t, ts, otherToken, "foreign", nil)
+
+	path := fmt.Sprintf("/v1/notebooks/%d/move", root.ID)
+
+	rec := ts.doAuth(http.MethodPost, path, token, models.MoveNotebookRequest{ParentID: &root.ID})
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, path, token, models.MoveNotebookRequest{ParentID: &grandchild.ID})
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, path, token, models.MoveNotebookRequest{ParentID: &foreign.ID})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, "/v1/notebooks", token, models.CreateNotebookRequest{
+		Name:     "nested in foreign",
+		ParentID: &foreign.ID,
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, fmt.Sprintf("/v1/notebooks/%d/move", grandchild.ID), token, models.MoveNotebookRequest{})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var moved models.Notebook
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &moved))
+	require.Nil(t, moved.ParentID)
+
+	rec = ts.doAuth(http.MethodPost, path, token, models.MoveNotebookRequest{ParentID: &grandchild.ID})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestNotebookTree(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	work := createNotebook(t, ts, token, "work", nil)
+	projects := createNotebook(t, ts, token, "projects", &work.ID)
+	home := createNotebook(t, ts, token, "home", nil)
+
+	inWork := createNote(t, ts, token)
+	require.Equal(t, http.StatusOK, moveNote(t, ts, token, inWork.ID, &work.ID).Code)
+
+	inProjects := createNote(t, ts, token)
+	require.Equal(t, http.StatusOK, moveNote(t, ts, token, inProjects.ID, &projects.ID).Code)
+
+	topLevel := createNote(t, ts, token)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notebooks/tree", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var tree models.GetNotebookTreeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tree))
+	require.Equal(t, []*models.NotebookNode{
+		{ID: home.ID, Name: "home", Children: []*models.NotebookNode{}},
+		{ID: work.ID, Name: "work", NoteCount: 1, TotalNoteCount: 2, Children: []*models.NotebookNode{
+			{ID: projects.ID, Name: "projects", NoteCount: 1, TotalNoteCount: 1, Children: []*models.NotebookNode{}},
+		}},
+	}, tree.Notebooks)
+
+	list := getNotes(t, ts, fmt.Sprintf("/v1/notes?notebook_id=%d", work.ID), token)
+	require.ElementsMatch(t, []int64{inWork.ID}, noteIDs(list))
+
+	list = getNotes(t, ts, fmt.Sprintf("/v1/notes?notebook_id=%d&include_descendants=true", work.ID), token)
+	require.ElementsMatch(t, []int64{inWork.ID, inProjects.ID}, noteIDs(list))
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("/v1/notebooks/%d", work.ID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, fmt.Sprintf("/v1/notebooks/%d", projects.ID), token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	list = getNotes(t, ts, "/v1/notes", token)
+	require.ElementsMatch(t, []int64{inWork.ID, inProjects.ID, topLevel.ID}, noteIDs(list))
+	for _, note := range list.Notes {
+		require.Nil(t, note.NotebookID)
+	}
+}
+
+func TestMoveNote(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	_, otherToken := loggedInUser(t, ts)
+
+	note := createNote(t, ts, token)
+	notebook := createNotebook(t, ts, token, "inbox", nil)
+	foreign := createNotebook(t, ts, otherToken, "foreign", nil)
+
+	rec := moveNote(t, ts, token, note.ID, &foreign.ID)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = moveNote(t, ts, otherToken, note.ID, &foreign.ID)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = moveNote(t, ts, token, note.ID, &notebook.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var moved models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &moved))
+	require.Equal(t, notebook.ID, *moved.NotebookID)
+
+	rec = moveNote(t, ts, token, note.ID, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &moved))
+	require.Nil(t, moved.NotebookID)
+}
@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrInvalidMergePatch = errors.New("body must be a JSON merge patch object")
+
+// patchField describes a member of a merge patch document,
+// nullable members may be cleared by sending null and rules are the
+// same validation rules as the binding tags of the full update request
+type patchField struct {
+	column   string
+	nullable bool
+	rules    string
+}
+
+var userPatchFields = map[string]patchField{
+	"first_name":   {column: "first_name", rules: "notblank,max=30"},
+	"last_name":    {column: "last_name", rules: "notblank,max=30"},
+	"phone_number": {column: "phone_number", nullable: true, rules: "omitempty,max=20,phone"},
+	"email":        {column: "email", rules: "required,max=50,email"},
+	"image_url":    {column: "image_url", nullable: true, rules: "omitempty,httpurl"},
+}
+
+var notePatchFields = map[string]patchField{
+	"title":       {column: "title", rules: "notblank,max=60"},
+	"description": {column: "description", rules: "max=100"},
+}
+
+// parseMergePatch reads an RFC 7396 merge patch from the body and returns the columns
+// to change, members that are absent are left untouched and null clears the column.
+// It writes the error response itself, ok is false when the handler should stop
+func parseMergePatch(c *gin.Context, fields map[string]patchField) (map[string]interface{}, bool) {
+	var members map[string]json.RawMessage
+
+	body, err := c.GetRawData()
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	err = json.Unmarshal(body, &members)
+	if err != nil || members == nil {
+		problemResponse(c, http.StatusBadRequest, ErrInvalidMergePatch)
+		return nil, false
+	}
+
+	result := make(map[string]interface{}, len(members))
+	for name, raw := range members {
+		field, ok := fields[name]
+		if !ok {
+			fieldProblem(c, http.StatusBadRequest, name, "is not a known field")
+			return nil, false
+		}
+
+		if string(raw) == "null" {
+			if !field.nullable {
+				fieldProblem(c, http.StatusUnprocessableEntity, name, "can not be null")
+				return nil, false
+			}
+			result[field.column] = nil
+			continue
+		}
+
+		var value string
+		err = json.Unmarshal(raw, &value)
+		if err != nil {
+			fieldProblem(c, http.StatusBadRequest, name, "must be a string")
+			return nil, false
+		}
+
+		if message, ok := validateValue(value, field.rules); !ok {
+			fieldProblem(c, http.StatusUnprocessableEntity, name, message)
+			return nil, false
+		}
+		result[field.column] = value
+	}
+
+	return result, true
+}
@@ -0,0 +1,285 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrTagNotFound       = errors.New("tag not found")
+	ErrEmptyTagName      = errors.New("tag name can not be empty")
+	ErrMergeTagItself    = errors.New("tag can not be merged into itself")
+	ErrNoteOrTagNotFound = errors.New("note or tag not found")
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/tags [post]
+// @Summary Create a tag
+// @Description Create a tag, names are unique per user
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param tag body models.CreateTagRequest true "Tag"
+// @Success 201 {object} models.Tag
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) CreateTag(c *gin.Context) {
+	var req models.CreateTagRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		problemResponse(c, http.StatusBadRequest, ErrEmptyTagName)
+		return
+	}
+
+	resp, err := h.storage.Tag().Create(c.Request.Context(), &repo.Tag{
+		UserID: getAuthPayload(c).UserID,
+		Name:   name,
+	})
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusCreated, parseTagModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/tags [get]
+// @Summary Get all tags
+// @Description Get all tags of the current user with the number of notes in each
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllTagsResponse
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetAllTags(c *gin.Context) {
+	result, err := h.storage.Tag().GetAll(c.Request.Context(), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	response := models.GetAllTagsResponse{
+		Tags:  make([]*models.Tag, 0),
+		Count: len(result),
+	}
+
+	for _, tag := range result {
+		t := parseTagModel(tag)
+		response.Tags = append(response.Tags, &t)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/tags/{id} [put]
+// @Summary Rename a tag
+// @Description Rename a tag
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param tag body models.RenameTagRequest true "Tag"
+// @Success 200 {object} models.Tag
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) RenameTag(c *gin.Context) {
+	var req models.RenameTagRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		problemResponse(c, http.StatusBadRequest, ErrEmptyTagName)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.storage.Tag().Rename(c.Request.Context(), int64(id), getAuthPayload(c).UserID, name)
+	if err != nil {
+		storageError(c, err, ErrTagNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, parseTagModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/tags/{id}/merge [post]
+// @Summary Merge a tag into another
+// @Description Move the notes of the tag to the target tag and delete the tag
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param target body models.MergeTagRequest true "Target"
+// @Success 200 {object} models.Tag
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) MergeTag(c *gin.Context) {
+	var req models.MergeTagRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if int64(id) == req.TargetID {
+		problemResponse(c, http.StatusBadRequest, ErrMergeTagItself)
+		return
+	}
+
+	resp, err := h.storage.Tag().Merge(c.Request.Context(), int64(id), req.TargetID, getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, ErrTagNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, parseTagModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/tags/{id} [delete]
+// @Summary Delete a tag
+// @Description Delete a tag, the notes themselves are kept
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) DeleteTag(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.storage.Tag().Delete(c.Request.Context(), int64(id), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, ErrTagNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully deleted",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/tags/{tag_id} [put]
+// @Summary Attach a tag to a note
+// @Description Attach a tag to a note, attaching it twice has no effect
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param tag_id path int true "Tag ID"
+// @Success 200 {object} models.Note
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) AttachNoteTag(c *gin.Context) {
+	h.changeNoteTag(c, h.storage.Tag().Attach)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/notes/{id}/tags/{tag_id} [delete]
+// @Summary Detach a tag from a note
+// @Description Detach a tag from a note
+// @Tags note
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param tag_id path int true "Tag ID"
+// @Success 200 {object} models.Note
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) DetachNoteTag(c *gin.Context) {
+	h.changeNoteTag(c, h.storage.Tag().Detach)
+}
+
+// changeNoteTag runs attach or detach for the ids in the path and responds with the note
+func (h *handlerV1) changeNoteTag(c *gin.Context, change func(ctx context.Context, noteID, tagID, userID int64) error) {
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	tagID, err := strconv.Atoi(c.Param("tag_id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID := getAuthPayload(c).UserID
+
+	err = change(c.Request.Context(), int64(noteID), int64(tagID), userID)
+	if err != nil {
+		storageError(c, err, ErrNoteOrTagNotFound)
+		return
+	}
+
+	note, err := h.storage.Note().Get(c.Request.Context(), int64(noteID), userID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, parseNoteModel(note))
+}
+
+func parseTagModel(tag *repo.Tag) models.Tag {
+	return models.Tag{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		NoteCount: tag.NoteCount,
+		CreatedAt: tag.CreatedAt,
+	}
+}
+
+func parseNoteTagsModel(tags []*repo.Tag) []*models.NoteTag {
+	result := make([]*models.NoteTag, 0, len(tags))
+
+	for _, tag := range tags {
+		result = append(result, &models.NoteTag{
+			ID:   tag.ID,
+			Name: tag.Name,
+		})
+	}
+
+	return result
+}
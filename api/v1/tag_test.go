@@ -0,0 +1,183 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func createTag(t *testing.T, ts *testServer, token, name string) models.Tag {
+	rec := ts.doAuth(http.MethodPost, "/v1/tags", token, models.CreateTagRequest{Name: name})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var tag models.Tag
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tag))
+
+	return tag
+}
+
+func attachTag(t *testing.T, ts *testServer, token string, noteID, tagID int64) models.Note {
+	rec := ts.doAuth(http.MethodPut, fmt.Sprintf("/v1/notes/%d/tags/%d", noteID, tagID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var note models.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+
+	return note
+}
+
+func noteIDs(list models.GetAllNotesResponse) []int64 {
+	ids := make([]int64, 0, len(list.Notes))
+	for _, note := range list.Notes {
+		ids = append(ids, note.ID)
+	}
+	return ids
+}
+
+func TestTags(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	_, otherToken := loggedInUser(t, ts)
+
+	work := createTag(t, ts, token, "work")
+	createTag(t, ts, otherToken, "work")
+
+	rec := ts.doAuth(http.MethodPost, "/v1/tags", token, models.CreateTagRequest{Name: "work"})
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, "/v1/tags", token, models.CreateTagRequest{Name: "  "})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodPut, fmt.Sprintf("/v1/tags/%d", work.ID), token, models.RenameTagRequest{Name: "job"})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodPut, fmt.Sprintf("/v1/tags/%d", work.ID), otherToken, models.RenameTagRequest{Name: "stolen"})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/tags", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var list models.GetAllTagsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Equal(t, 1, list.Count)
+	require.Equal(t, "job", list.Tags[0].Name)
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("/v1/tags/%d", work.ID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("/v1/tags/%d", work.ID), token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAttachNoteTag(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+	_, otherToken := loggedInUser(t, ts)
+
+	note := createNote(t, ts, token)
+	tag := createTag(t, ts, token, "home")
+	otherTag := createTag(t, ts, otherToken, "home")
+
+	got := attachTag(t, ts, token, note.ID, tag.ID)
+	require.Equal(t, []*models.NoteTag{{ID: tag.ID, Name: "home"}}, got.Tags)
+
+	got = attachTag(t, ts, token, note.ID, tag.ID)
+	require.Len(t, got.Tags, 1)
+
+	rec := ts.doAuth(http.MethodPut, fmt.Sprintf("/v1/notes/%d/tags/%d", note.ID, otherTag.ID), token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodPut, fmt.Sprintf("/v1/notes/%d/tags/%d", note.ID, otherTag.ID), otherToken, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, fmt.Sprintf("/v1/notes/%d", note.ID), token, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got.Tags, 1)
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("/v1/notes/%d/tags/%d", note.ID, tag.ID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Empty(t, got.Tags)
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("/v1/notes/%d/tags/%d", note.ID, tag.ID), token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestNoteTagFilters(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	a := createTag(t, ts, token, "a")
+	b := createTag(t, ts, token, "b")
+
+	onlyA := createNote(t, ts, token)
+	attachTag(t, ts, token, onlyA.ID, a.ID)
+
+	both := createNote(t, ts, token)
+	attachTag(t, ts, token, both.ID, a.ID)
+	attachTag(t, ts, token, both.ID, b.ID)
+
+	none := createNote(t, ts, token)
+
+	testCases := []struct {
+		query string
+		want  []int64
+	}{
+		{query: "", want: []int64{onlyA.ID, both.ID, none.ID}},
+		{query: fmt.Sprintf("tags_any=%d,%d", a.ID, b.ID), want: []int64{onlyA.ID, both.ID}},
+		{query: fmt.Sprintf("tags_all=%d,%d", a.ID, b.ID), want: []int64{both.ID}},
+		{query: fmt.Sprintf("tags_exclude=%d", b.ID), want: []int64{onlyA.ID, none.ID}},
+		{query: fmt.Sprintf("tags_any=%d&tags_exclude=%d", a.ID, b.ID), want: []int64{onlyA.ID}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			list := getNotes(t, ts, "/v1/notes?"+tc.query, token)
+			require.ElementsMatch(t, tc.want, noteIDs(list))
+		})
+	}
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notes?tags_any=x", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestMergeTag(t *testing.T) {
+	ts := newTestServer()
+	_, token := loggedInUser(t, ts)
+
+	source := createTag(t, ts, token, "todo")
+	target := createTag(t, ts, token, "tasks")
+
+	first := createNote(t, ts, token)
+	attachTag(t, ts, token, first.ID, source.ID)
+
+	second := createNote(t, ts, token)
+	attachTag(t, ts, token, second.ID, source.ID)
+	attachTag(t, ts, token, second.ID, target.ID)
+
+	path := fmt.Sprintf("/v1/tags/%d/merge", source.ID)
+
+	rec := ts.doAuth(http.MethodPost, path, token, models.MergeTagRequest{TargetID: source.ID})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, path, token, models.MergeTagRequest{TargetID: 999})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = ts.doAuth(http.MethodPost, path, token, models.MergeTagRequest{TargetID: target.ID})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var merged models.Tag
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &merged))
+	require.Equal(t, target.ID, merged.ID)
+	require.Equal(t, int64(2), merged.NoteCount)
+
+	list := getNotes(t, ts, fmt.Sprintf("/v1/notes?tags_all=%d", target.ID), token)
+	require.ElementsMatch(t, []int64{first.ID, second.ID}, noteIDs(list))
+	for _, note := range list.Notes {
+		require.Len(t, note.Tags, 1)
+	}
+}
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -9,42 +10,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// @Router /users [post]
-// @Summary Create a user
-// @Description Create a user
-// @Tags user
-// @Accept json
-// @Produce json
-// @Param user body models.CreateUserRequest true "User"
-// @Success 201 {object} models.User
-// @Failure 500 {object} models.ErrorResponse
-func (h *handlerV1) CreateUser(c *gin.Context) {
-	var (
-		req models.CreateUserRequest
-	)
-
-	err := c.ShouldBindJSON(&req)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
-		return
-	}
-
-	resp, err := h.storage.User().Create(&repo.User{
-		FirstName:   req.FirstName,
-		LastName:    req.LastName,
-		PhoneNumber: req.PhoneNumber,
-		Email:       req.Email,
-		ImageURL:    req.ImageURL,
-	})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	c.JSON(http.StatusCreated, parseUserModel(resp))
-}
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUnknownRole  = errors.New("unknown role")
+)
 
-// @Router /users/{id} [get]
+// @Security ApiKeyAuth
+// @Router /v1/users/{id} [get]
 // @Summary Get user by id
 // @Description Get user by id
 // @Tags user
@@ -52,89 +24,171 @@ func (h *handlerV1) CreateUser(c *gin.Context) {
 // @Produce json
 // @Param id path int true "ID"
 // @Success 200 {object} models.User
-// @Failure 500 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) GetUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
 		return
 	}
 
-	resp, err := h.storage.User().Get(int64(id))
+	resp, err := h.storage.User().Get(c.Request.Context(), int64(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		storageError(c, err, ErrUserNotFound)
 		return
 	}
 
+	setETag(c, resp.Version)
 	c.JSON(http.StatusOK, parseUserModel(resp))
 }
 
-// @Router /users [get]
+// @Security ApiKeyAuth
+// @Router /v1/users [get]
 // @Summary Get all users
-// @Description Get all users
+// @Description Get all users, search matches a part of the first name, last name or email
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param filter query models.GetAllUserParams false "Filter"
 // @Success 200 {object} models.GetAllUsersResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) GetAllUsers(c *gin.Context) {
 	req, err := validateGetAllUserParams(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := h.storage.User().GetAll(&repo.GetAllUsersParams{
-		Page:   req.Page,
-		Limit:  req.Limit,
-		Search: req.Search,
+	result, err := h.storage.User().GetAll(c.Request.Context(), &repo.GetAllUsersParams{
+		Page:      req.Page,
+		Limit:     req.Limit,
+		Search:    req.Search,
+		SortBy:    req.SortBy,
+		SortOrder: req.SortOrder,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		storageError(c, err, nil)
 		return
 	}
 
 	c.JSON(http.StatusOK, getUsersResponse(result))
 }
 
-// @Router /users/{id} [put]
+// @Security ApiKeyAuth
+// @Router /v1/users/{id} [put]
 // @Summary Update a user
-// @Description Update a user
+// @Description Update a user, If-Match must hold the ETag of the user the change is based on
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
-// @Param user body models.CreateUserRequest true "User"
+// @Param If-Match header string true "ETag of the user"
+// @Param user body models.UpdateUserRequest true "User"
 // @Success 200 {object} models.User
-// @Failure 500 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) UpdateUser(c *gin.Context) {
-	var req repo.User
+	var req models.UpdateUserRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	h.updateUser(c, int64(id), &req)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/users/{id} [patch]
+// @Summary Patch a user
+// @Description Change only the fields present in the body (RFC 7396 JSON merge patch), If-Match must hold the ETag of the user
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param If-Match header string true "ETag of the user"
+// @Param user body models.PatchUserRequest true "Merge patch"
+// @Success 200 {object} models.User
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) PatchUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	h.patchUser(c, int64(id))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/users/{id}/role [put]
+// @Summary Change the role of a user
+// @Description Change the role of a user
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param role body models.UpdateRoleRequest true "Role"
+// @Success 200 {object} models.User
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) UpdateUserRole(c *gin.Context) {
+	var req models.UpdateRoleRequest
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		bindingError(c, err)
+		return
+	}
+
+	if req.Role != repo.RoleUser && req.Role != repo.RoleAdmin {
+		problemResponse(c, http.StatusBadRequest, ErrUnknownRole)
 		return
 	}
 
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
 		return
 	}
 
-	req.ID = int64(id)
+	err = h.storage.User().UpdateRole(c.Request.Context(), int64(id), req.Role)
+	if err != nil {
+		storageError(c, err, ErrUserNotFound)
+		return
+	}
 
-	updated, err := h.storage.User().Update(&req)
+	user, err := h.storage.User().Get(c.Request.Context(), int64(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		storageError(c, err, nil)
 		return
 	}
 
-	c.JSON(http.StatusCreated, parseUserModel(updated))
+	setETag(c, user.Version)
+	c.JSON(http.StatusOK, parseUserModel(user))
 }
 
-// @Router /users/{id} [delete]
+// @Security ApiKeyAuth
+// @Router /v1/users/{id} [delete]
 // @Summary Delete a user
 // @Description Delete a user
 // @Tags user
@@ -142,16 +196,23 @@ func (h *handlerV1) UpdateUser(c *gin.Context) {
 // @Produce json
 // @Param id path int true "ID"
 // @Success 200 {object} models.ResponseOK
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 500 {object} models.Problem
 func (h *handlerV1) DeleteUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		problemResponse(c, http.StatusBadRequest, err)
+		return
+	}
+	err = h.storage.User().Delete(c.Request.Context(), int64(id))
+	if err != nil {
+		storageError(c, err, ErrUserNotFound)
 		return
 	}
-	err = h.storage.User().Delete(int64(id))
+
+	// the tokens of the user are signed and outlive the row, end their session
+	err = h.invalidateSessions(c.Request.Context(), int64(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		problemResponse(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -160,6 +221,135 @@ func (h *handlerV1) DeleteUser(c *gin.Context) {
 	})
 }
 
+// @Security ApiKeyAuth
+// @Router /v1/me [get]
+// @Summary Get the current user
+// @Description Get the profile of the authenticated user
+// @Tags me
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.User
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) GetMe(c *gin.Context) {
+	resp, err := h.storage.User().Get(c.Request.Context(), getAuthPayload(c).UserID)
+	if err != nil {
+		storageError(c, err, nil)
+		return
+	}
+
+	setETag(c, resp.Version)
+	c.JSON(http.StatusOK, parseUserModel(resp))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/me [put]
+// @Summary Update the current user
+// @Description Update the profile of the authenticated user, If-Match must hold the ETag of the profile the change is based on
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param If-Match header string true "ETag of the user"
+// @Param user body models.UpdateUserRequest true "User"
+// @Success 200 {object} models.User
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 400 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) UpdateMe(c *gin.Context) {
+	var req models.UpdateUserRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	h.updateUser(c, getAuthPayload(c).UserID, &req)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/me [patch]
+// @Summary Patch the current user
+// @Description Change only the fields present in the body (RFC 7396 JSON merge patch), If-Match must hold the ETag of the profile
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param If-Match header string true "ETag of the user"
+// @Param user body models.PatchUserRequest true "Merge patch"
+// @Success 200 {object} models.User
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 412 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
+func (h *handlerV1) PatchMe(c *gin.Context) {
+	h.patchUser(c, getAuthPayload(c).UserID)
+}
+
+func (h *handlerV1) updateUser(c *gin.Context, id int64, req *models.UpdateUserRequest) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.storage.User().Update(c.Request.Context(), &repo.User{
+		ID:          id,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		PhoneNumber: req.PhoneNumber,
+		Email:       req.Email,
+		ImageURL:    req.ImageURL,
+		Version:     version,
+	})
+	if err != nil {
+		storageError(c, err, ErrUserNotFound)
+		return
+	}
+
+	setETag(c, updated.Version)
+	c.JSON(http.StatusOK, parseUserModel(updated))
+}
+
+func (h *handlerV1) patchUser(c *gin.Context, id int64) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseMergePatch(c, userPatchFields)
+	if !ok {
+		return
+	}
+
+	var (
+		updated *repo.User
+		err     error
+	)
+	if len(fields) == 0 {
+		// an empty patch changes nothing but still has to match the stored version
+		updated, err = h.storage.User().Get(c.Request.Context(), id)
+		if err == nil && updated.Version != version {
+			err = repo.ErrVersionMismatch
+		}
+	} else {
+		updated, err = h.storage.User().Patch(c.Request.Context(), &repo.UserPatch{
+			ID:      id,
+			Version: version,
+			Fields:  fields,
+		})
+	}
+	if err != nil {
+		storageError(c, err, ErrUserNotFound)
+		return
+	}
+
+	setETag(c, updated.Version)
+	c.JSON(http.StatusOK, parseUserModel(updated))
+}
+
 func getUsersResponse(data *repo.GetAllUsersResult) *models.GetAllUsersResponse {
 	response := models.GetAllUsersResponse{
 		Users: make([]*models.User, 0),
@@ -175,30 +365,22 @@ func getUsersResponse(data *repo.GetAllUsersResult) *models.GetAllUsersResponse
 }
 
 func validateGetAllUserParams(c *gin.Context) (*models.GetAllUserParams, error) {
-	var (
-		limit int = 10
-		page  int = 1
-		err   error
-	)
-
-	if c.Query("limit") != "" {
-		limit, err = strconv.Atoi(c.Query("limit"))
-		if err != nil {
-			return nil, err
-		}
+	page, limit, err := pageParams(c)
+	if err != nil {
+		return nil, err
 	}
 
-	if c.Query("page") != "" {
-		page, err = strconv.Atoi(c.Query("page"))
-		if err != nil {
-			return nil, err
-		}
+	order, err := sortOrder(c)
+	if err != nil {
+		return nil, err
 	}
 
 	return &models.GetAllUserParams{
-		Limit:  int32(limit),
-		Page:   int32(page),
-		Search: c.Query("search"),
+		Limit:     limit,
+		Page:      page,
+		Search:    c.Query("search"),
+		SortBy:    c.Query("sort_by"),
+		SortOrder: order,
 	}, nil
 }
 
@@ -210,6 +392,9 @@ func parseUserModel(user *repo.User) models.User {
 		PhoneNumber: user.PhoneNumber,
 		Email:       user.Email,
 		ImageURL:    user.ImageURL,
+		Role:        user.Role,
+		IsActive:    user.IsActive,
+		Version:     user.Version,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		DeletedAt:   user.DeletedAt,
@@ -0,0 +1,234 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/burxondv/note-template/api/models"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func adminUser(t *testing.T, ts *testServer) (models.User, string) {
+	user, token := loggedInUser(t, ts)
+	require.NoError(t, ts.storage.User().UpdateRole(context.Background(), int64(user.ID), repo.RoleAdmin))
+
+	return user, token
+}
+
+func TestMe(t *testing.T) {
+	ts := newTestServer()
+	user, token := loggedInUser(t, ts)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/me", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var me models.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &me))
+	require.Equal(t, user.ID, me.ID)
+	require.Equal(t, repo.RoleUser, me.Role)
+
+	rec = ts.doHeaders(http.MethodPut, "/v1/me", token, map[string]string{"If-Match": rec.Header().Get("ETag")}, models.UpdateUserRequest{
+		FirstName: "Changed",
+		LastName:  user.LastName,
+		Email:     user.Email,
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &me))
+	require.Equal(t, "Changed", me.FirstName)
+	require.Equal(t, repo.RoleUser, me.Role)
+}
+
+func TestUsersAdminOnly(t *testing.T) {
+	ts := newTestServer()
+	other, _ := loggedInUser(t, ts)
+	_, token := loggedInUser(t, ts)
+
+	path := fmt.Sprintf("/v1/users/%d", other.ID)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/users", token, nil)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = ts.doAuth(http.MethodPut, path, token, models.UpdateUserRequest{FirstName: "X"})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = ts.doAuth(http.MethodPut, path+"/role", token, models.UpdateRoleRequest{Role: repo.RoleAdmin})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = ts.doAuth(http.MethodDelete, path, token, nil)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAdminManagesUsers(t *testing.T) {
+	ts := newTestServer()
+	other, _ := loggedInUser(t, ts)
+	_, token := adminUser(t, ts)
+
+	path := fmt.Sprintf("/v1/users/%d", other.ID)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/users", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var list models.GetAllUsersResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Users, 2)
+
+	rec = ts.doAuth(http.MethodPut, path+"/role", token, models.UpdateRoleRequest{Role: "root"})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodPut, path+"/role", token, models.UpdateRoleRequest{Role: repo.RoleAdmin})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var updated models.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	require.Equal(t, repo.RoleAdmin, updated.Role)
+
+	rec = ts.doAuth(http.MethodDelete, path, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeletedUserTokens(t *testing.T) {
+	ts := newTestServer()
+	other := verifiedUser(t, ts)
+	tokens := login(t, ts, other)
+	_, token := adminUser(t, ts)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/notes", tokens.AccessToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doAuth(http.MethodDelete, fmt.Sprintf("/v1/users/%d", other.ID), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = ts.doAuth(http.MethodGet, "/v1/notes", tokens.AccessToken, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = ts.do(http.MethodPost, "/v1/auth/refresh", models.RefreshRequest{
+		RefreshToken: tokens.RefreshToken,
+	})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestUpdateMeIfMatch(t *testing.T) {
+	ts := newTestServer()
+	user, token := loggedInUser(t, ts)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/me", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	update := models.UpdateUserRequest{
+		FirstName: "Changed",
+		LastName:  user.LastName,
+		Email:     user.Email,
+	}
+
+	rec = ts.doAuth(http.MethodPut, "/v1/me", token, update)
+	require.Equal(t, http.StatusPreconditionRequired, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPut, "/v1/me", token, map[string]string{"If-Match": etag}, update)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NotEqual(t, etag, rec.Header().Get("ETag"))
+
+	rec = ts.doHeaders(http.MethodPut, "/v1/me", token, map[string]string{"If-Match": etag}, update)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
+
+func TestPatchMe(t *testing.T) {
+	ts := newTestServer()
+	user, token := loggedInUser(t, ts)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/me", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPatch, "/v1/me", token, map[string]string{"If-Match": rec.Header().Get("ETag")}, map[string]interface{}{
+		"first_name": "Patched",
+		"image_url":  nil,
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var me models.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &me))
+	require.Equal(t, "Patched", me.FirstName)
+	require.Equal(t, user.LastName, me.LastName)
+	require.Equal(t, user.Email, me.Email)
+	require.Empty(t, me.ImageURL)
+
+	rec = ts.doHeaders(http.MethodPatch, "/v1/me", token, ifMatch(me.Version), map[string]interface{}{
+		"email": nil,
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPatch, "/v1/me", token, ifMatch(me.Version), map[string]interface{}{
+		"phone_number": "12345",
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, "phone_number", decodeProblem(t, rec).Errors[0].Field)
+
+	rec = ts.doAuth(http.MethodPatch, "/v1/me", token, map[string]interface{}{
+		"first_name": "NoIfMatch",
+	})
+	require.Equal(t, http.StatusPreconditionRequired, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPatch, "/v1/me", token, ifMatch(me.Version), map[string]interface{}{})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = ts.doHeaders(http.MethodPatch, "/v1/me", token, ifMatch(me.Version-1), map[string]interface{}{})
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
+
+func TestAdminPatchUser(t *testing.T) {
+	ts := newTestServer()
+	other, _ := loggedInUser(t, ts)
+	_, token := adminUser(t, ts)
+	path := fmt.Sprintf("/v1/users/%d", other.ID)
+
+	rec := ts.doAuth(http.MethodGet, path, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = ts.doHeaders(http.MethodPatch, path, token, map[string]string{"If-Match": rec.Header().Get("ETag")}, map[string]interface{}{
+		"last_name": "Patched",
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var user models.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &user))
+	require.Equal(t, "Patched", user.LastName)
+	require.Equal(t, other.FirstName, user.FirstName)
+	require.Equal(t, other.Email, user.Email)
+
+	rec = ts.doHeaders(http.MethodPatch, "/v1/users/999", token, ifMatch(1), map[string]interface{}{
+		"last_name": "Nobody",
+	})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetAllUsersSort(t *testing.T) {
+	ts := newTestServer()
+	_, token := adminUser(t, ts)
+
+	rec := ts.doAuth(http.MethodGet, "/v1/users?sort_by=email&sort_order=asc", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = ts.doAuth(http.MethodGet, "/v1/users?sort_by=password", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/users?sort_by=email&sort_order=desc%3BDROP", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/users?page=0", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = ts.doAuth(http.MethodGet, "/v1/users?limit=0", token, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultListLimit = 10
+	// maxListLimit caps the limit of the list endpoints, a larger one is lowered to it
+	maxListLimit = 100
+)
+
+var (
+	ErrInvalidPage      = errors.New("page must be a positive number")
+	ErrInvalidLimit     = errors.New("limit must be a positive number")
+	ErrInvalidSortOrder = errors.New("sort_order must be asc or desc")
+)
+
+// phoneRegexp matches E.164 numbers, a plus and up to 15 digits without a leading zero
+var phoneRegexp = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+var registerValidationOnce sync.Once
+
+// registerValidation adds the custom rules to the validator of gin
+// and makes it report fields by their json names
+func registerValidation() {
+	registerValidationOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		v.RegisterTagNameFunc(jsonFieldName)
+		_ = v.RegisterValidation("notblank", validateNotBlank)
+		_ = v.RegisterValidation("phone", validatePhone)
+		_ = v.RegisterValidation("httpurl", validateHTTPURL)
+	})
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// validateNotBlank fails strings made only of whitespace
+func validateNotBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
+
+// validatePhone accepts phone numbers in the E.164 format, e.g. +998901234567
+func validatePhone(fl validator.FieldLevel) bool {
+	return phoneRegexp.MatchString(fl.Field().String())
+}
+
+// validateHTTPURL accepts absolute http and https urls
+func validateHTTPURL(fl validator.FieldLevel) bool {
+	u, err := url.Parse(fl.Field().String())
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// bindingError writes the response for a failed ShouldBind call, a body that can't be
+// decoded is a 400 and a well formed body that breaks the validation rules is a 422
+func bindingError(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		problemResponse(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	problemResponse(c, http.StatusBadRequest, err)
+}
+
+// validateValue checks a single value against validation rules like the ones of the binding tags,
+// it returns the message of the first rule that fails
+func validateValue(value interface{}, rules string) (string, bool) {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok || rules == "" {
+		return "", true
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(v.Var(value, rules), &validationErrs) {
+		return fieldMessage(validationErrs[0]), false
+	}
+
+	return "", true
+}
+
+// pageParams reads the page and limit query parameters of the list endpoints
+func pageParams(c *gin.Context) (page, limit int32, err error) {
+	page, limit = 1, defaultListLimit
+
+	if c.Query("limit") != "" {
+		value, err := strconv.ParseInt(c.Query("limit"), 10, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+		if value < 1 {
+			return 0, 0, ErrInvalidLimit
+		}
+		if value > maxListLimit {
+			value = maxListLimit
+		}
+		limit = int32(value)
+	}
+
+	if c.Query("page") != "" {
+		value, err := strconv.ParseInt(c.Query("page"), 10, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+		if value < 1 {
+			return 0, 0, ErrInvalidPage
+		}
+		page = int32(value)
+	}
+
+	return page, limit, nil
+}
+
+// sortOrder reads the sort_order query parameter, empty leaves the default to the storage
+func sortOrder(c *gin.Context) (string, error) {
+	order := c.Query("sort_order")
+	if order != "" && order != "asc" && order != "desc" {
+		return "", ErrInvalidSortOrder
+	}
+
+	return order, nil
+}
@@ -1,16 +1,47 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The storages StorageDriver selects from
+const (
+	StoragePostgres = "postgres"
+	StorageSqlite   = "sqlite"
+	// StorageMemory keeps everything in the process and loses it on exit, for demos
+	StorageMemory = "memory"
 )
 
+// MinAuthSecretKeyLength is the shortest AUTH_SECRET_KEY the tokens are signed with
+const MinAuthSecretKeyLength = 32
+
 type Config struct {
 	HttpPort      string
+	StorageDriver string
 	Postgres      PostgresConfig
+	Sqlite        SqliteConfig
 	Smtp          Smtp
 	Redis         Redis
 	AuthSecretKey string
+
+	AccessTokenDuration  time.Duration
+	RefreshTokenDuration time.Duration
+	PasswordHashCost     int
+
+	// RequestTimeout bounds the handling of a request and QueryTimeout every
+	// call to the storage, the zero value disables the deadline
+	RequestTimeout time.Duration
+	QueryTimeout   time.Duration
+
+	// AutoMigrate applies the pending migrations on start, without it the server
+	// refuses to start until the schema is migrated with the migrate command
+	AutoMigrate bool
 }
 
 type PostgresConfig struct {
@@ -21,7 +52,15 @@ type PostgresConfig struct {
 	Database string
 }
 
+// SqliteConfig is used when StorageDriver is sqlite, Path is the database file
+// which is created when it does not exist
+type SqliteConfig struct {
+	Path string
+}
+
 type Smtp struct {
+	Host     string
+	Port     string
 	Sender   string
 	Password string
 }
@@ -36,8 +75,20 @@ func Load(path string) Config {
 	conf := viper.New()
 	conf.AutomaticEnv()
 
+	conf.SetDefault("STORAGE_DRIVER", StoragePostgres)
+	conf.SetDefault("SQLITE_PATH", "note.db")
+	conf.SetDefault("AUTO_MIGRATE", false)
+	conf.SetDefault("SMTP_HOST", "smtp.gmail.com")
+	conf.SetDefault("SMTP_PORT", "587")
+	conf.SetDefault("ACCESS_TOKEN_DURATION", "15m")
+	conf.SetDefault("REFRESH_TOKEN_DURATION", "168h")
+	conf.SetDefault("PASSWORD_HASH_COST", 12)
+	conf.SetDefault("REQUEST_TIMEOUT", "30s")
+	conf.SetDefault("QUERY_TIMEOUT", "5s")
+
 	cfg := Config{
-		HttpPort: conf.GetString("HTTP_PORT"),
+		HttpPort:      conf.GetString("HTTP_PORT"),
+		StorageDriver: conf.GetString("STORAGE_DRIVER"),
 		Postgres: PostgresConfig{
 			Host:     conf.GetString("POSTGRES_HOST"),
 			Port:     conf.GetString("POSTGRES_PORT"),
@@ -45,7 +96,12 @@ func Load(path string) Config {
 			Password: conf.GetString("POSTGRES_PASSWORD"),
 			Database: conf.GetString("POSTGRES_DATABASE"),
 		},
+		Sqlite: SqliteConfig{
+			Path: conf.GetString("SQLITE_PATH"),
+		},
 		Smtp: Smtp{
+			Host:     conf.GetString("SMTP_HOST"),
+			Port:     conf.GetString("SMTP_PORT"),
 			Sender:   conf.GetString("SMTP_SENDER"),
 			Password: conf.GetString("SMTP_PASSWORD"),
 		},
@@ -53,7 +109,32 @@ func Load(path string) Config {
 			Addr: conf.GetString("REDIS_ADDR"),
 		},
 		AuthSecretKey: conf.GetString("AUTH_SECRET_KEY"),
+
+		AccessTokenDuration:  conf.GetDuration("ACCESS_TOKEN_DURATION"),
+		RefreshTokenDuration: conf.GetDuration("REFRESH_TOKEN_DURATION"),
+		PasswordHashCost:     conf.GetInt("PASSWORD_HASH_COST"),
+
+		RequestTimeout: conf.GetDuration("REQUEST_TIMEOUT"),
+		QueryTimeout:   conf.GetDuration("QUERY_TIMEOUT"),
+
+		AutoMigrate: conf.GetBool("AUTO_MIGRATE"),
 	}
 
 	return cfg
 }
+
+// Validate reports the settings the server can not safely run with
+func (c *Config) Validate() error {
+	if c.AuthSecretKey == "" {
+		return errors.New("AUTH_SECRET_KEY is not set")
+	}
+	if len(c.AuthSecretKey) < MinAuthSecretKeyLength {
+		return fmt.Errorf("AUTH_SECRET_KEY is shorter than %d bytes", MinAuthSecretKeyLength)
+	}
+	// bcrypt quietly hashes with its default cost below MinCost and refuses above MaxCost
+	if c.PasswordHashCost < bcrypt.MinCost || c.PasswordHashCost > bcrypt.MaxCost {
+		return fmt.Errorf("PASSWORD_HASH_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/burxondv/note-template/config"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() config.Config {
+		return config.Config{
+			AuthSecretKey:    strings.Repeat("k", config.MinAuthSecretKeyLength),
+			PasswordHashCost: 12,
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		change  func(cfg *config.Config)
+		wantErr bool
+	}{
+		{"valid", func(cfg *config.Config) {}, false},
+		{"no secret", func(cfg *config.Config) { cfg.AuthSecretKey = "" }, true},
+		{"short secret", func(cfg *config.Config) { cfg.AuthSecretKey = "short" }, true},
+		{"min cost", func(cfg *config.Config) { cfg.PasswordHashCost = bcrypt.MinCost }, false},
+		{"max cost", func(cfg *config.Config) { cfg.PasswordHashCost = bcrypt.MaxCost }, false},
+		{"cost below min", func(cfg *config.Config) { cfg.PasswordHashCost = bcrypt.MinCost - 1 }, true},
+		{"cost above max", func(cfg *config.Config) { cfg.PasswordHashCost = bcrypt.MaxCost + 1 }, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := valid()
+			tc.change(&cfg)
+
+			err := cfg.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
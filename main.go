@@ -1,45 +1,76 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	"github.com/burxondv/note-template/api"
 	"github.com/burxondv/note-template/config"
+	"github.com/burxondv/note-template/pkg/email"
 	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/postgres"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/burxondv/note-template/storage/schema"
+	"github.com/burxondv/note-template/storage/sqlite"
 	"github.com/go-redis/redis/v9"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
+const usage = `usage:
+	note-template                          run the api server
+	note-template promote-admin <email>    give the admin role to a user
+	note-template migrate up [n]           apply all the pending migrations or the next n
+	note-template migrate down [n]         revert the last n migrations, one by default
+	note-template migrate status           print the schema version
+	note-template migrate force <version>  set the version of a dirty schema after fixing it by hand`
+
 func main() {
 
 	cfg := config.Load(".")
 
-	psqlUrl := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.Postgres.Host,
-		cfg.Postgres.Port,
-		cfg.Postgres.User,
-		cfg.Postgres.Password,
-		cfg.Postgres.Database,
-	)
+	// a bad config is refused before the database is touched or migrated
+	err := cfg.Validate()
+	if err != nil {
+		log.Fatalf("refusing to start: %v", err)
+	}
 
-	psqlConn, err := sqlx.Connect("postgres", psqlUrl)
+	strg, migrator, err := openStorage(&cfg)
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
+	if len(os.Args) > 1 {
+		err = runCommand(strg, migrator, os.Args[1:])
+		migrator.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err = checkSchema(&cfg, migrator)
+	migrator.Close()
+	if err != nil {
+		log.Fatalf("refusing to start: %v", err)
+	}
+
 	rdb := redis.NewClient(&redis.Options{
 		Addr: cfg.Redis.Addr,
 	})
 
-	strg := storage.NewStoragePg(psqlConn)
-	inMemory := storage.NewInMemoryStorage(rdb)
+	inMemory := storage.NewInMemoryStorage(rdb, cfg.QueryTimeout)
+	mailer := email.NewSmtpMailer(cfg.Smtp)
 
 	apiServer := api.New(&api.RouterOptions{
 		Cfg:      &cfg,
 		Storage:  strg,
 		InMemory: inMemory,
+		Mailer:   mailer,
 	})
 
 	err = apiServer.Run(cfg.HttpPort)
@@ -49,3 +80,185 @@ func main() {
 
 	log.Print("server stopped")
 }
+
+// openStorage connects the storage STORAGE_DRIVER selects and the migrator of its schema
+func openStorage(cfg *config.Config) (storage.StorageI, *schema.Migrator, error) {
+	switch cfg.StorageDriver {
+	case config.StoragePostgres:
+		psqlUrl := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+			cfg.Postgres.Host,
+			cfg.Postgres.Port,
+			cfg.Postgres.User,
+			cfg.Postgres.Password,
+			cfg.Postgres.Database,
+		)
+
+		psqlConn, err := sqlx.Connect("postgres", psqlUrl)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		migrator, err := postgres.NewMigrator(context.Background(), psqlConn.DB)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return storage.NewStoragePg(psqlConn, cfg.QueryTimeout), migrator, nil
+	case config.StorageSqlite:
+		db, err := sqlite.Open(cfg.Sqlite.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		migrator, err := sqlite.NewMigrator(db.DB)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return storage.NewStorageSqlite(db, cfg.QueryTimeout), migrator, nil
+	case config.StorageMemory:
+		// nothing outlives the process, so there is no schema to migrate
+		return storage.NewStorageMemory(), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q, use %s, %s or %s",
+			cfg.StorageDriver, config.StoragePostgres, config.StorageSqlite, config.StorageMemory)
+	}
+}
+
+// checkSchema refuses a schema the code was not written for. With AUTO_MIGRATE the
+// pending migrations are applied first, a schema that is ahead of the code or dirty
+// is never migrated on start
+func checkSchema(cfg *config.Config, migrator *schema.Migrator) error {
+	if migrator == nil {
+		return nil
+	}
+
+	if cfg.AutoMigrate {
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		if !status.Dirty && status.Version < status.Expected {
+			log.Printf("migrating schema from version %d to %d", status.Version, status.Expected)
+
+			err = migrator.Up(0)
+			if err != nil {
+				return fmt.Errorf("failed to migrate: %w", err)
+			}
+		}
+	}
+
+	err := migrator.Check()
+	if err != nil {
+		return fmt.Errorf("%w, run the migrate command or set AUTO_MIGRATE", err)
+	}
+
+	return nil
+}
+
+func runCommand(strg storage.StorageI, migrator *schema.Migrator, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(migrator, args[1:])
+	case "promote-admin":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		return promoteAdmin(strg, args[1])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// promoteAdmin is used to bootstrap the first admin, the rest can be managed through the api
+func promoteAdmin(strg storage.StorageI, email string) error {
+	ctx := context.Background()
+
+	user, err := strg.User().GetByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to find user %s: %v", email, err)
+	}
+
+	err = strg.User().UpdateRole(ctx, user.ID, repo.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to promote user %s: %v", email, err)
+	}
+
+	log.Printf("user %s is now an admin", email)
+	return nil
+}
+
+// runMigrate applies the migrations embedded in the binary and prints the version
+// the schema is left at
+func runMigrate(migrator *schema.Migrator, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New(usage)
+	}
+	if migrator == nil {
+		return errors.New("the memory storage has no schema to migrate")
+	}
+
+	var err error
+	switch args[0] {
+	case "up":
+		var n int
+		n, err = migrationCount(args[1:], 0)
+		if err == nil {
+			err = migrator.Up(n)
+		}
+	case "down":
+		var n int
+		n, err = migrationCount(args[1:], 1)
+		if err == nil {
+			err = migrator.Down(n)
+		}
+	case "force":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+
+		var version int
+		version, err = strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = migrator.Force(version)
+	case "status":
+		if len(args) != 1 {
+			return errors.New(usage)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	dirty := ""
+	if status.Dirty {
+		dirty = " (dirty)"
+	}
+	log.Printf("schema version %d%s, the code expects %d", status.Version, dirty, status.Expected)
+
+	return nil
+}
+
+// migrationCount parses the optional number of migrations of up and down
+func migrationCount(args []string, fallback int) (int, error) {
+	if len(args) == 0 {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %q", args[0])
+	}
+
+	return n, nil
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS note_links (
+    id SERIAL PRIMARY KEY,
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    password VARCHAR,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    max_views INTEGER CHECK (max_views > 0),
+    view_count INTEGER NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS note_links_user_id_idx ON note_links(user_id);
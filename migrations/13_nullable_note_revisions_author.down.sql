@@ -0,0 +1,7 @@
+DELETE FROM note_revisions WHERE author_id IS NULL;
+
+ALTER TABLE note_revisions DROP CONSTRAINT IF EXISTS note_revisions_author_id_fkey;
+ALTER TABLE note_revisions ADD CONSTRAINT note_revisions_author_id_fkey
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE note_revisions ALTER COLUMN author_id SET NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE note_revisions ALTER COLUMN author_id DROP NOT NULL;
+
+ALTER TABLE note_revisions DROP CONSTRAINT IF EXISTS note_revisions_author_id_fkey;
+ALTER TABLE note_revisions ADD CONSTRAINT note_revisions_author_id_fkey
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS note_revisions (
+    id SERIAL PRIMARY KEY,
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(60) NOT NULL,
+    description VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS note_revisions_note_id_idx ON note_revisions(note_id);
+
+INSERT INTO note_revisions(note_id, author_id, title, description, created_at)
+SELECT id, user_id, title, description, COALESCE(updated_at, created_at) FROM notes;
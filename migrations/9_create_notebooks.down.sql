@@ -0,0 +1,5 @@
+ALTER TABLE notes DROP COLUMN IF EXISTS notebook_id;
+
+DROP TABLE IF EXISTS notebooks;
+
+DROP FUNCTION IF EXISTS notebooks_check_parent();
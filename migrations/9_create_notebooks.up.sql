@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS notebooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES notebooks(id) ON DELETE CASCADE,
+    name VARCHAR(60) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS notebooks_user_id_idx ON notebooks(user_id);
+CREATE INDEX IF NOT EXISTS notebooks_parent_id_idx ON notebooks(parent_id);
+
+-- a notebook may only be placed under a notebook of the same user that is not
+-- one of its own descendants, the advisory lock serializes concurrent moves of a user
+CREATE OR REPLACE FUNCTION notebooks_check_parent() RETURNS trigger AS $$
+BEGIN
+    IF NEW.parent_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(NEW.user_id);
+
+    IF NOT EXISTS (SELECT 1 FROM notebooks WHERE id = NEW.parent_id AND user_id = NEW.user_id) THEN
+        RAISE EXCEPTION 'parent notebook % not found', NEW.parent_id
+            USING ERRCODE = 'foreign_key_violation';
+    END IF;
+
+    IF EXISTS (
+        WITH RECURSIVE ancestors AS (
+            SELECT id, parent_id FROM notebooks WHERE id = NEW.parent_id
+            UNION ALL
+            SELECT n.id, n.parent_id FROM notebooks n JOIN ancestors a ON n.id = a.parent_id
+        )
+        SELECT 1 FROM ancestors WHERE id = NEW.id
+    ) THEN
+        RAISE EXCEPTION 'notebook % can not be moved into itself or its descendants', NEW.id
+            USING ERRCODE = 'check_violation';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notebooks_check_parent ON notebooks;
+CREATE TRIGGER notebooks_check_parent
+    BEFORE INSERT OR UPDATE OF parent_id ON notebooks
+    FOR EACH ROW EXECUTE PROCEDURE notebooks_check_parent();
+
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS notebook_id INTEGER REFERENCES notebooks(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS notes_notebook_id_idx ON notes(notebook_id);
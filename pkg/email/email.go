@@ -0,0 +1,66 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/smtp"
+	"strings"
+
+	"github.com/burxondv/note-template/config"
+	"github.com/burxondv/note-template/templates"
+)
+
+const (
+	VerificationEmail  = "verification_email.html"
+	ResetPasswordEmail = "reset_password_email.html"
+)
+
+type Mailer interface {
+	Send(to []string, subject, body string) error
+}
+
+type smtpMailer struct {
+	cfg config.Smtp
+}
+
+func NewSmtpMailer(cfg config.Smtp) Mailer {
+	return &smtpMailer{
+		cfg: cfg,
+	}
+}
+
+func (m *smtpMailer) Send(to []string, subject, body string) error {
+	msg := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=\"UTF-8\"\r\n\r\n%s",
+		m.cfg.Sender,
+		strings.Join(to, ", "),
+		subject,
+		body,
+	)
+
+	auth := smtp.PlainAuth("", m.cfg.Sender, m.cfg.Password, m.cfg.Host)
+
+	err := smtp.SendMail(m.cfg.Host+":"+m.cfg.Port, auth, m.cfg.Sender, to, []byte(msg))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RenderTemplate executes one of the embedded html templates with data
+func RenderTemplate(name string, data interface{}) (string, error) {
+	t, err := template.ParseFS(templates.FS, name)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	err = t.Execute(&body, data)
+	if err != nil {
+		return "", err
+	}
+
+	return body.String(), nil
+}
@@ -0,0 +1,67 @@
+package utils
+
+import "strings"
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+type DiffLine struct {
+	Op   string
+	Text string
+}
+
+// DiffLines returns a line-level diff turning a into b,
+// it is based on the longest common subsequence of lines
+func DiffLines(a, b string) []DiffLine {
+	from := strings.Split(a, "\n")
+	to := strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of from[i:] and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	result := make([]DiffLine, 0, len(from)+len(to))
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			result = append(result, DiffLine{Op: DiffEqual, Text: from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, DiffLine{Op: DiffDelete, Text: from[i]})
+			i++
+		default:
+			result = append(result, DiffLine{Op: DiffInsert, Text: to[j]})
+			j++
+		}
+	}
+
+	for ; i < len(from); i++ {
+		result = append(result, DiffLine{Op: DiffDelete, Text: from[i]})
+	}
+
+	for ; j < len(to); j++ {
+		result = append(result, DiffLine{Op: DiffInsert, Text: to[j]})
+	}
+
+	return result
+}
@@ -0,0 +1,60 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/burxondv/note-template/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	testCases := []struct {
+		name string
+		a, b string
+		want []utils.DiffLine
+	}{
+		{
+			name: "equal",
+			a:    "a\nb",
+			b:    "a\nb",
+			want: []utils.DiffLine{
+				{Op: utils.DiffEqual, Text: "a"},
+				{Op: utils.DiffEqual, Text: "b"},
+			},
+		},
+		{
+			name: "insert at the end",
+			a:    "a",
+			b:    "a\nb",
+			want: []utils.DiffLine{
+				{Op: utils.DiffEqual, Text: "a"},
+				{Op: utils.DiffInsert, Text: "b"},
+			},
+		},
+		{
+			name: "delete in the middle",
+			a:    "a\nb\nc",
+			b:    "a\nc",
+			want: []utils.DiffLine{
+				{Op: utils.DiffEqual, Text: "a"},
+				{Op: utils.DiffDelete, Text: "b"},
+				{Op: utils.DiffEqual, Text: "c"},
+			},
+		},
+		{
+			name: "replace",
+			a:    "old",
+			b:    "new",
+			want: []utils.DiffLine{
+				{Op: utils.DiffDelete, Text: "old"},
+				{Op: utils.DiffInsert, Text: "new"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, utils.DiffLines(tc.a, tc.b))
+		})
+	}
+}
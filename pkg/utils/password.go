@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"errors"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores everything after 72 bytes
+)
+
+var (
+	ErrPasswordTooShort = errors.New("password must be at least 8 characters long")
+	ErrPasswordTooLong  = errors.New("password must be at most 72 bytes long")
+	ErrPasswordTooWeak  = errors.New("password must contain an uppercase letter, a lowercase letter and a digit")
+	ErrWrongPassword    = errors.New("wrong password")
+)
+
+// ValidatePassword checks that the password is strong enough to be stored
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	if len(password) > maxPasswordLength {
+		return ErrPasswordTooLong
+	}
+
+	var upper, lower, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+
+	if !upper || !lower || !digit {
+		return ErrPasswordTooWeak
+	}
+
+	return nil
+}
+
+func HashPassword(password string, cost int) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hashed), nil
+}
+
+func CheckPassword(password, hashed string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	if err != nil {
+		return ErrWrongPassword
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether the hash was made with a different cost than the configured one
+func NeedsRehash(hashed string, cost int) bool {
+	hashCost, err := bcrypt.Cost([]byte(hashed))
+	if err != nil {
+		return true
+	}
+
+	return hashCost != cost
+}
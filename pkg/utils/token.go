@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+	ApiToken     = "api"
+)
+
+var ErrInvalidToken = errors.New("token is invalid")
+
+type Payload struct {
+	jwt.RegisteredClaims
+	UserID  int64    `json:"user_id"`
+	Session string   `json:"session,omitempty"`
+	Type    string   `json:"type"`
+	Scopes  []string `json:"scopes,omitempty"`
+}
+
+// CreateToken signs a new token of the given type for the user,
+// session ties the token to the user's current login session
+func CreateToken(secret string, userID int64, session, tokenType string, duration time.Duration) (string, *Payload, error) {
+	tokenID, err := RandomString(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	payload := &Payload{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   fmt.Sprint(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+		UserID:  userID,
+		Session: session,
+		Type:    tokenType,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString([]byte(secret))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, payload, nil
+}
+
+// VerifyToken checks the signature and expiry of the token and returns its payload
+func VerifyToken(secret, token string) (*Payload, error) {
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return []byte(secret), nil
+	}
+
+	var payload Payload
+	_, err := jwt.ParseWithClaims(token, &payload, keyFunc)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &payload, nil
+}
+
+// RandomString returns a hex encoded string of n random bytes
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded sha256 of an opaque token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
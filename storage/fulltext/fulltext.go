@@ -0,0 +1,108 @@
+// Package fulltext approximates the postgres full-text search of notes for the
+// storages that have none: every word of the search must appear in the title or
+// the description and words starting with a minus must not. Words match as
+// case-insensitive prefixes, quotes and the or operator are ignored
+package fulltext
+
+import (
+	"html"
+	"strings"
+)
+
+// The weights of the title and the description match the default weights
+// ts_rank gives to the A and B parts of search_vector
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+// Query is a parsed search, its zero value matches nothing
+type Query struct {
+	include []string
+	exclude []string
+}
+
+// Parse splits search into the words a note must and must not contain
+func Parse(search string) Query {
+	var q Query
+	for _, word := range strings.Fields(strings.ToLower(search)) {
+		word = strings.Trim(word, `"`)
+		if word == "" || word == "or" {
+			continue
+		}
+
+		if strings.HasPrefix(word, "-") {
+			if word = strings.TrimPrefix(word, "-"); word != "" {
+				q.exclude = append(q.exclude, word)
+			}
+			continue
+		}
+
+		q.include = append(q.include, word)
+	}
+
+	return q
+}
+
+// Rank returns the relevance of the note and false when it does not match the query
+func (q Query) Rank(title, description string) (float64, bool) {
+	if len(q.include) == 0 {
+		return 0, false
+	}
+
+	for _, word := range q.exclude {
+		if countWord(title, word)+countWord(description, word) > 0 {
+			return 0, false
+		}
+	}
+
+	var rank float64
+	for _, word := range q.include {
+		inTitle := countWord(title, word)
+		inDescription := countWord(description, word)
+		if inTitle+inDescription == 0 {
+			return 0, false
+		}
+
+		rank += titleWeight*float64(inTitle) + descriptionWeight*float64(inDescription)
+	}
+
+	return rank, true
+}
+
+// Highlight wraps the words of text that match the query in <mark> tags, the text
+// is HTML-escaped so the tags are the only markup in the result
+func (q Query) Highlight(text string) string {
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = html.EscapeString(w)
+		for _, word := range q.include {
+			if matchWord(w, word) {
+				words[i] = "<mark>" + words[i] + "</mark>"
+				break
+			}
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+func countWord(text, word string) int {
+	count := 0
+	for _, w := range strings.Fields(text) {
+		if matchWord(w, word) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// matchWord compares the word of a text without the punctuation around it
+func matchWord(w, word string) bool {
+	w = strings.TrimFunc(strings.ToLower(w), func(r rune) bool {
+		return strings.ContainsRune(`.,;:!?"'()[]{}<>`, r)
+	})
+
+	return strings.HasPrefix(w, word)
+}
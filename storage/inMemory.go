@@ -2,27 +2,54 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/go-redis/redis/v9"
 )
+
+var ErrKeyNotFound = errors.New("key not found")
+
 type InMemoryStorageI interface {
-	Set(key, value string, exp time.Duration) error
-	Get(key string) (string, error)
+	Set(ctx context.Context, key, value string, exp time.Duration) error
+	// SetNX stores the value only when the key does not exist and reports whether it did,
+	// so only one of concurrent callers claims the key
+	SetNX(ctx context.Context, key, value string, exp time.Duration) (bool, error)
+	Get(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+	// GetDelete returns the value and removes the key in one step, so only one of
+	// concurrent callers gets it, the others get ErrKeyNotFound
+	GetDelete(ctx context.Context, key string) (string, error)
+	// Incr adds one to the integer stored at key, a missing key counts from zero
+	Incr(ctx context.Context, key string) (int64, error)
 }
 
 type storageRedis struct {
-	client *redis.Client
+	client       *redis.Client
+	queryTimeout time.Duration
 }
 
-func NewInMemoryStorage(rdb *redis.Client) InMemoryStorageI {
+// NewInMemoryStorage wraps rdb, queryTimeout bounds every command and zero disables it
+func NewInMemoryStorage(rdb *redis.Client, queryTimeout time.Duration) InMemoryStorageI {
 	return &storageRedis{
-		client: rdb,
+		client:       rdb,
+		queryTimeout: queryTimeout,
 	}
 }
 
-func (r *storageRedis) Set(key, value string, exp time.Duration) error {
-	err := r.client.Set(context.Background(), key, value, exp).Err()
+func (r *storageRedis) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
+
+func (r *storageRedis) Set(ctx context.Context, key, value string, exp time.Duration) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := r.client.Set(ctx, key, value, exp).Err()
 	if err != nil {
 		return err
 	}
@@ -30,11 +57,58 @@ func (r *storageRedis) Set(key, value string, exp time.Duration) error {
 	return nil
 }
 
-func (r *storageRedis) Get(key string) (string, error) {
-	val, err := r.client.Get(context.Background(), key).Result()
+func (r *storageRedis) SetNX(ctx context.Context, key, value string, exp time.Duration) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.client.SetNX(ctx, key, value, exp).Result()
+}
+
+func (r *storageRedis) Get(ctx context.Context, key string) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	val, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrKeyNotFound
+	}
 	if err != nil {
 		return "", err
 	}
 
 	return val, nil
-}
\ No newline at end of file
+}
+
+func (r *storageRedis) Delete(ctx context.Context, key string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := r.client.Del(ctx, key).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *storageRedis) GetDelete(ctx context.Context, key string) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	val, err := r.client.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrKeyNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return val, nil
+}
+
+func (r *storageRedis) Incr(ctx context.Context, key string) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.client.Incr(ctx, key).Result()
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type apiTokenRepo struct {
+	db *DB
+}
+
+func NewApiToken(db *DB) repo.ApiTokenStorageI {
+	return &apiTokenRepo{
+		db: db,
+	}
+}
+
+// copyToken returns a copy of the token that does not share the scopes with the stored row
+func copyToken(token repo.ApiToken) *repo.ApiToken {
+	token.Scopes = append([]string{}, token.Scopes...)
+	return &token
+}
+
+func (tr *apiTokenRepo) Create(ctx context.Context, token *repo.ApiToken) (*repo.ApiToken, error) {
+	err := tr.db.write(ctx, func(t *tables) error {
+		for _, other := range t.apiTokens {
+			if other.TokenHash == token.TokenHash {
+				return uniqueViolation("api_tokens_token_hash_key")
+			}
+		}
+		if _, ok := t.users[token.UserID]; !ok {
+			return foreignKeyViolation("api_tokens", "api_tokens_user_id_fkey")
+		}
+
+		row := *copyToken(*token)
+		row.ID = t.nextID("api_tokens")
+		row.LastUsedAt = nil
+		row.CreatedAt = time.Now()
+		t.apiTokens[row.ID] = row
+
+		token.ID = row.ID
+		token.CreatedAt = row.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (tr *apiTokenRepo) GetByHash(ctx context.Context, tokenHash string) (*repo.ApiToken, error) {
+	var result *repo.ApiToken
+	err := tr.db.read(ctx, func(t *tables) error {
+		for _, token := range t.apiTokens {
+			if token.TokenHash == tokenHash {
+				result = copyToken(token)
+				return nil
+			}
+		}
+
+		return repo.ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (tr *apiTokenRepo) GetAll(ctx context.Context, userID int64) ([]*repo.ApiToken, error) {
+	result := make([]*repo.ApiToken, 0)
+	err := tr.db.read(ctx, func(t *tables) error {
+		for _, token := range t.apiTokens {
+			if token.UserID == userID {
+				result = append(result, copyToken(token))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if c := compareTimes(result[i].CreatedAt, result[j].CreatedAt); c != 0 {
+			return c > 0
+		}
+
+		return result[i].ID > result[j].ID
+	})
+
+	return result, nil
+}
+
+func (tr *apiTokenRepo) UpdateLastUsed(ctx context.Context, id int64) error {
+	return tr.db.write(ctx, func(t *tables) error {
+		token, ok := t.apiTokens[id]
+		if !ok {
+			return nil
+		}
+
+		token.LastUsedAt = now()
+		t.apiTokens[id] = token
+		return nil
+	})
+}
+
+func (tr *apiTokenRepo) Delete(ctx context.Context, id, userID int64) error {
+	return tr.db.write(ctx, func(t *tables) error {
+		token, ok := t.apiTokens[id]
+		if !ok || token.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		delete(t.apiTokens, id)
+		return nil
+	})
+}
@@ -0,0 +1,171 @@
+// Package memory keeps the data in maps guarded by a lock. Its repos follow the
+// semantics of the postgres repos, including the unique and foreign key constraints,
+// so it can stand in for postgres in tests and local runs
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+// maxTxAttempts matches the number of times postgres.RunInTx runs a transaction
+const maxTxAttempts = 3
+
+// errSerialization is returned when a transaction kept losing to concurrent writes
+var errSerialization = errors.New("could not serialize access due to concurrent update")
+
+type noteTag struct {
+	noteID int64
+	tagID  int64
+}
+
+type noteUser struct {
+	noteID int64
+	userID int64
+}
+
+// tables holds the rows by value, the pointer and slice members of a row are
+// never changed in place so a copy of the maps is a full snapshot
+type tables struct {
+	ids       map[string]int64
+	users     map[int64]repo.User
+	notes     map[int64]repo.Note
+	revisions map[int64]repo.NoteRevision
+	apiTokens map[int64]repo.ApiToken
+	tags      map[int64]repo.Tag
+	noteTags  map[noteTag]bool
+	notebooks map[int64]repo.Notebook
+	shares    map[noteUser]repo.NoteShare
+	links     map[int64]repo.NoteLink
+}
+
+func newTables() *tables {
+	return &tables{
+		ids:       make(map[string]int64),
+		users:     make(map[int64]repo.User),
+		notes:     make(map[int64]repo.Note),
+		revisions: make(map[int64]repo.NoteRevision),
+		apiTokens: make(map[int64]repo.ApiToken),
+		tags:      make(map[int64]repo.Tag),
+		noteTags:  make(map[noteTag]bool),
+		notebooks: make(map[int64]repo.Notebook),
+		shares:    make(map[noteUser]repo.NoteShare),
+		links:     make(map[int64]repo.NoteLink),
+	}
+}
+
+func (t *tables) clone() *tables {
+	return &tables{
+		ids:       cloneMap(t.ids),
+		users:     cloneMap(t.users),
+		notes:     cloneMap(t.notes),
+		revisions: cloneMap(t.revisions),
+		apiTokens: cloneMap(t.apiTokens),
+		tags:      cloneMap(t.tags),
+		noteTags:  cloneMap(t.noteTags),
+		notebooks: cloneMap(t.notebooks),
+		shares:    cloneMap(t.shares),
+		links:     cloneMap(t.links),
+	}
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	result := make(map[K]V, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+
+	return result
+}
+
+// nextID works like a SERIAL column, ids are never reused
+func (t *tables) nextID(table string) int64 {
+	t.ids[table]++
+	return t.ids[table]
+}
+
+// DB is the in-memory database shared by the repos, it is safe for concurrent use
+type DB struct {
+	mu     sync.RWMutex
+	tables *tables
+	// writes counts the changes, a transaction is committed only when
+	// nothing was written since it took its snapshot
+	writes uint64
+}
+
+func NewDB() *DB {
+	return &DB{
+		tables: newTables(),
+	}
+}
+
+// read runs fn under the read lock, fn must not change the tables
+func (db *DB) read(ctx context.Context, fn func(t *tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return fn(db.tables)
+}
+
+// write runs fn under the write lock, fn checks everything before it changes
+// the tables so a returned error leaves them untouched
+func (db *DB) write(ctx context.Context, fn func(t *tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	err := fn(db.tables)
+	if err == nil {
+		db.writes++
+	}
+
+	return err
+}
+
+// RunInTx runs fn on a snapshot of db and swaps the snapshot in when fn returns nil.
+// A transaction that raced with another write is run again like a serialization
+// failure in postgres, up to maxTxAttempts times
+func RunInTx(ctx context.Context, db *DB, fn func(tx *DB) error) error {
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		db.mu.RLock()
+		tx := &DB{tables: db.tables.clone()}
+		base := db.writes
+		db.mu.RUnlock()
+
+		err := fn(tx)
+		if err != nil {
+			return err
+		}
+
+		if tx.writes == 0 {
+			return nil
+		}
+
+		db.mu.Lock()
+		if db.writes == base {
+			db.tables = tx.tables
+			db.writes++
+			db.mu.Unlock()
+			return nil
+		}
+		db.mu.Unlock()
+
+		if attempt == maxTxAttempts {
+			return errSerialization
+		}
+	}
+}
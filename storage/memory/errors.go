@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+// uniqueViolation is the error a unique constraint of the postgres schema raises
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("%w: duplicate key value violates unique constraint %q", repo.ErrConflict, constraint)
+}
+
+// foreignKeyViolation is the error a foreign key of the postgres schema raises
+func foreignKeyViolation(table, constraint string) error {
+	return fmt.Errorf("%w: insert, update or delete on table %q violates foreign key constraint %q", repo.ErrInvalidReference, table, constraint)
+}
+
+// patchString returns the value a patch sets a text column to, nil clears a nullable column
+func patchString(column string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+
+	return "", fmt.Errorf("column %q can not be set to %T", column, value)
+}
+
+func patchError(column string) error {
+	return fmt.Errorf("column %q can not be patched", column)
+}
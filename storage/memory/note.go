@@ -0,0 +1,441 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/burxondv/note-template/storage/fulltext"
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type noteRepo struct {
+	db *DB
+}
+
+func NewNote(db *DB) repo.NoteStorageI {
+	return &noteRepo{
+		db: db,
+	}
+}
+
+// canRead is true when the user owns the note or the note is shared with them
+func (t *tables) canRead(note repo.Note, userID int64) bool {
+	if note.UserID == userID {
+		return true
+	}
+
+	_, ok := t.shares[noteUser{noteID: note.ID, userID: userID}]
+	return ok
+}
+
+// canWrite is like canRead but accepts only the editors
+func (t *tables) canWrite(note repo.Note, userID int64) bool {
+	if note.UserID == userID {
+		return true
+	}
+
+	share, ok := t.shares[noteUser{noteID: note.ID, userID: userID}]
+	return ok && share.Permission == repo.PermissionEditor
+}
+
+// activeNote returns the note when it is not in the trash and the user owns it
+func (t *tables) activeNote(id, userID int64) (repo.Note, bool) {
+	note, ok := t.notes[id]
+	if !ok || note.DeletedAt != nil || note.UserID != userID {
+		return repo.Note{}, false
+	}
+
+	return note, true
+}
+
+// withTags returns a copy of the note with its tags ordered by name
+func (t *tables) withTags(note repo.Note) *repo.Note {
+	note.Tags = make([]*repo.Tag, 0)
+	for key := range t.noteTags {
+		if key.noteID != note.ID {
+			continue
+		}
+
+		tag := t.tags[key.tagID]
+		tag.NoteCount = 0
+		note.Tags = append(note.Tags, &tag)
+	}
+
+	sort.Slice(note.Tags, func(i, j int) bool {
+		return note.Tags[i].Name < note.Tags[j].Name
+	})
+
+	return &note
+}
+
+func (t *tables) addRevision(note repo.Note, authorID int64) {
+	id := t.nextID("note_revisions")
+	t.revisions[id] = repo.NoteRevision{
+		ID:          id,
+		NoteID:      note.ID,
+		AuthorID:    &authorID,
+		Title:       note.Title,
+		Description: note.Description,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// deleteNote removes the note with the rows that cascade from it
+func (t *tables) deleteNote(id int64) {
+	for revisionID, revision := range t.revisions {
+		if revision.NoteID == id {
+			delete(t.revisions, revisionID)
+		}
+	}
+	for key := range t.noteTags {
+		if key.noteID == id {
+			delete(t.noteTags, key)
+		}
+	}
+	for key := range t.shares {
+		if key.noteID == id {
+			delete(t.shares, key)
+		}
+	}
+	for linkID, link := range t.links {
+		if link.NoteID == id {
+			delete(t.links, linkID)
+		}
+	}
+
+	delete(t.notes, id)
+}
+
+func (nr *noteRepo) Create(ctx context.Context, note *repo.Note) (*repo.Note, error) {
+	err := nr.db.write(ctx, func(t *tables) error {
+		if _, ok := t.users[note.UserID]; !ok {
+			return foreignKeyViolation("notes", "notes_user_id_fkey")
+		}
+
+		row := repo.Note{
+			ID:          t.nextID("notes"),
+			UserID:      note.UserID,
+			Title:       note.Title,
+			Description: note.Description,
+			Version:     1,
+			CreatedAt:   time.Now(),
+		}
+		t.notes[row.ID] = row
+		t.addRevision(row, row.UserID)
+
+		note.ID = row.ID
+		note.Version = row.Version
+		note.CreatedAt = row.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	note.Tags = make([]*repo.Tag, 0)
+	return note, nil
+}
+
+func (nr *noteRepo) Get(ctx context.Context, id, userID int64) (*repo.Note, error) {
+	var result *repo.Note
+	err := nr.db.read(ctx, func(t *tables) error {
+		note, ok := t.notes[id]
+		if !ok || note.DeletedAt != nil || !t.canRead(note, userID) {
+			return repo.ErrNotFound
+		}
+
+		result = t.withTags(note)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+var noteSortColumns = map[string]compareFunc[*repo.Note]{
+	"created_at": func(a, b *repo.Note) int { return compareTimes(a.CreatedAt, b.CreatedAt) },
+	"updated_at": func(a, b *repo.Note) int { return compareNullTimes(a.UpdatedAt, b.UpdatedAt) },
+	"title":      func(a, b *repo.Note) int { return compareStrings(a.Title, b.Title) },
+}
+
+func (nr *noteRepo) GetAll(ctx context.Context, params *repo.GetAllNotesParams) (*repo.GetAllNotesResult, error) {
+	var (
+		notes    = make([]*repo.Note, 0)
+		ranks    = make(map[int64]float64)
+		fullText = params.Search != "" && params.FullText
+		query    = fulltext.Parse(params.Search)
+	)
+
+	err := nr.db.read(ctx, func(t *tables) error {
+		var notebooks map[int64]bool
+		if params.NotebookID != nil && params.IncludeDescendants {
+			notebooks = t.notebookTree(*params.NotebookID)
+		} else if params.NotebookID != nil {
+			notebooks = map[int64]bool{*params.NotebookID: true}
+		}
+
+		for _, note := range t.notes {
+			if note.UserID != params.UserID {
+				_, shared := t.shares[noteUser{noteID: note.ID, userID: params.UserID}]
+				if !params.IncludeShared || params.Deleted || !shared {
+					continue
+				}
+			}
+
+			if (note.DeletedAt != nil) != params.Deleted {
+				continue
+			}
+
+			var highlight *repo.NoteHighlight
+			if fullText {
+				rank, ok := query.Rank(note.Title, note.Description)
+				if !ok {
+					continue
+				}
+
+				ranks[note.ID] = rank
+				highlight = &repo.NoteHighlight{
+					Title:       query.Highlight(note.Title),
+					Description: query.Highlight(note.Description),
+				}
+			} else if params.Search != "" && !containsFold(note.Title, params.Search) {
+				continue
+			}
+
+			if notebooks != nil && (note.NotebookID == nil || !notebooks[*note.NotebookID]) {
+				continue
+			}
+
+			if !t.matchTags(note.ID, params) {
+				continue
+			}
+
+			result := t.withTags(note)
+			result.Highlight = highlight
+			notes = append(notes, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := func(a, b *repo.Note) int {
+		return compareInts(b.ID, a.ID)
+	}
+
+	if fullText && params.SortBy == "" {
+		sort.Slice(notes, func(i, j int) bool {
+			a, b := notes[i], notes[j]
+			if ranks[a.ID] != ranks[b.ID] {
+				return ranks[a.ID] > ranks[b.ID]
+			}
+			if c := compareTimes(b.CreatedAt, a.CreatedAt); c != 0 {
+				return c < 0
+			}
+
+			return byID(a, b) < 0
+		})
+	} else {
+		sortBy := params.SortBy
+		if sortBy == "" {
+			sortBy = "created_at"
+		}
+
+		err = sortRows(notes, sortBy, params.SortOrder, noteSortColumns, byID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &repo.GetAllNotesResult{
+		Notes: page(notes, params.Page, params.Limit),
+		Count: int32(len(notes)),
+	}, nil
+}
+
+// matchTags applies the tag filters of params to the note
+func (t *tables) matchTags(noteID int64, params *repo.GetAllNotesParams) bool {
+	has := func(tagID int64) bool {
+		return t.noteTags[noteTag{noteID: noteID, tagID: tagID}]
+	}
+
+	if len(params.TagsAny) > 0 {
+		found := false
+		for _, tagID := range params.TagsAny {
+			found = found || has(tagID)
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, tagID := range params.TagsAll {
+		if !has(tagID) {
+			return false
+		}
+	}
+
+	for _, tagID := range params.TagsExclude {
+		if has(tagID) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// update applies change to the note after the same checks as the WHERE clause of
+// the postgres update and records the result as a revision of the acting user
+func (nr *noteRepo) update(ctx context.Context, id, userID, version int64, change func(n *repo.Note) error) (*repo.Note, error) {
+	var result *repo.Note
+	err := nr.db.write(ctx, func(t *tables) error {
+		note, ok := t.notes[id]
+		if !ok || note.DeletedAt != nil || !t.canRead(note, userID) {
+			return repo.ErrNotFound
+		}
+		if !t.canWrite(note, userID) {
+			return repo.ErrReadOnly
+		}
+		if note.Version != version {
+			return repo.ErrVersionMismatch
+		}
+
+		if err := change(&note); err != nil {
+			return err
+		}
+
+		note.Version++
+		note.UpdatedAt = now()
+		t.notes[id] = note
+		t.addRevision(note, userID)
+
+		result = t.withTags(note)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (nr *noteRepo) Update(ctx context.Context, note *repo.Note) (*repo.Note, error) {
+	return nr.update(ctx, note.ID, note.UserID, note.Version, func(n *repo.Note) error {
+		n.Title = note.Title
+		n.Description = note.Description
+		return nil
+	})
+}
+
+var notePatchColumns = map[string]func(n *repo.Note, value string){
+	"title":       func(n *repo.Note, value string) { n.Title = value },
+	"description": func(n *repo.Note, value string) { n.Description = value },
+}
+
+func (nr *noteRepo) Patch(ctx context.Context, patch *repo.NotePatch) (*repo.Note, error) {
+	for column := range patch.Fields {
+		if _, ok := notePatchColumns[column]; !ok {
+			return nil, patchError(column)
+		}
+	}
+
+	return nr.update(ctx, patch.ID, patch.UserID, patch.Version, func(n *repo.Note) error {
+		for column, value := range patch.Fields {
+			s, err := patchString(column, value)
+			if err != nil {
+				return err
+			}
+
+			notePatchColumns[column](n, s)
+		}
+
+		return nil
+	})
+}
+
+// Delete moves the note to the trash, it can be restored until it is purged
+func (nr *noteRepo) Delete(ctx context.Context, id, userID int64) error {
+	return nr.db.write(ctx, func(t *tables) error {
+		note, ok := t.activeNote(id, userID)
+		if !ok {
+			return repo.ErrNotFound
+		}
+
+		note.DeletedAt = now()
+		note.Version++
+		t.notes[id] = note
+		return nil
+	})
+}
+
+func (nr *noteRepo) Restore(ctx context.Context, id, userID int64) (*repo.Note, error) {
+	var result *repo.Note
+	err := nr.db.write(ctx, func(t *tables) error {
+		note, ok := t.notes[id]
+		if !ok || note.UserID != userID || note.DeletedAt == nil {
+			return repo.ErrNotFound
+		}
+
+		note.DeletedAt = nil
+		note.Version++
+		t.notes[id] = note
+
+		result = t.withTags(note)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (nr *noteRepo) Move(ctx context.Context, id, userID int64, notebookID *int64) (*repo.Note, error) {
+	var result *repo.Note
+	err := nr.db.write(ctx, func(t *tables) error {
+		note, ok := t.activeNote(id, userID)
+		if !ok {
+			return repo.ErrNotFound
+		}
+
+		if notebookID != nil {
+			notebook, ok := t.notebooks[*notebookID]
+			if !ok || notebook.UserID != userID {
+				return repo.ErrNotebookNotFound
+			}
+
+			copied := *notebookID
+			notebookID = &copied
+		}
+
+		note.NotebookID = notebookID
+		note.Version++
+		t.notes[id] = note
+
+		result = t.withTags(note)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Purge deletes the note permanently whether it is in the trash or not
+func (nr *noteRepo) Purge(ctx context.Context, id, userID int64) error {
+	return nr.db.write(ctx, func(t *tables) error {
+		note, ok := t.notes[id]
+		if !ok || note.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		t.deleteNote(id)
+		return nil
+	})
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type noteLinkRepo struct {
+	db *DB
+}
+
+func NewNoteLink(db *DB) repo.NoteLinkStorageI {
+	return &noteLinkRepo{
+		db: db,
+	}
+}
+
+// Create adds the link only when the note belongs to the user and is not in the trash
+func (lr *noteLinkRepo) Create(ctx context.Context, link *repo.NoteLink) (*repo.NoteLink, error) {
+	err := lr.db.write(ctx, func(t *tables) error {
+		if _, ok := t.activeNote(link.NoteID, link.UserID); !ok {
+			return repo.ErrNotFound
+		}
+
+		for _, other := range t.links {
+			if other.TokenHash == link.TokenHash {
+				return uniqueViolation("note_links_token_hash_key")
+			}
+		}
+
+		row := repo.NoteLink{
+			ID:        t.nextID("note_links"),
+			NoteID:    link.NoteID,
+			UserID:    link.UserID,
+			TokenHash: link.TokenHash,
+			Password:  link.Password,
+			ExpiresAt: link.ExpiresAt,
+			MaxViews:  link.MaxViews,
+			CreatedAt: time.Now(),
+		}
+		t.links[row.ID] = row
+
+		link.ID = row.ID
+		link.ViewCount = row.ViewCount
+		link.CreatedAt = row.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func (lr *noteLinkRepo) GetByHash(ctx context.Context, tokenHash string) (*repo.NoteLink, error) {
+	var result repo.NoteLink
+	err := lr.db.read(ctx, func(t *tables) error {
+		for _, link := range t.links {
+			if link.TokenHash == tokenHash {
+				result = link
+				return nil
+			}
+		}
+
+		return repo.ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (lr *noteLinkRepo) GetAll(ctx context.Context, userID int64) ([]*repo.NoteLink, error) {
+	result := make([]*repo.NoteLink, 0)
+	err := lr.db.read(ctx, func(t *tables) error {
+		for _, link := range t.links {
+			link := link
+			if link.UserID == userID {
+				result = append(result, &link)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if c := compareTimes(result[i].CreatedAt, result[j].CreatedAt); c != 0 {
+			return c > 0
+		}
+
+		return result[i].ID > result[j].ID
+	})
+
+	return result, nil
+}
+
+// AddView counts a view only while the link is not revoked, expired or out of views
+func (lr *noteLinkRepo) AddView(ctx context.Context, id int64) (*repo.NoteLink, error) {
+	var result repo.NoteLink
+	err := lr.db.write(ctx, func(t *tables) error {
+		link, ok := t.links[id]
+		switch {
+		case !ok,
+			link.RevokedAt != nil,
+			link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now()),
+			link.MaxViews != nil && link.ViewCount >= *link.MaxViews:
+			return repo.ErrLinkUnavailable
+		}
+
+		link.ViewCount++
+		t.links[id] = link
+
+		result = link
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Revoke keeps the link in the list of the owner, it just stops working
+func (lr *noteLinkRepo) Revoke(ctx context.Context, id, userID int64) (*repo.NoteLink, error) {
+	var result repo.NoteLink
+	err := lr.db.write(ctx, func(t *tables) error {
+		link, ok := t.links[id]
+		if !ok || link.UserID != userID || link.RevokedAt != nil {
+			return repo.ErrNotFound
+		}
+
+		link.RevokedAt = now()
+		t.links[id] = link
+
+		result = link
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
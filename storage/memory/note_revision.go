@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type noteRevisionRepo struct {
+	db *DB
+}
+
+func NewNoteRevision(db *DB) repo.NoteRevisionStorageI {
+	return &noteRevisionRepo{
+		db: db,
+	}
+}
+
+// readableNote is true when the note is not in the trash and the user may read it
+func (t *tables) readableNote(noteID, userID int64) bool {
+	note, ok := t.notes[noteID]
+	return ok && note.DeletedAt == nil && t.canRead(note, userID)
+}
+
+func (rr *noteRevisionRepo) Get(ctx context.Context, id, noteID, userID int64) (*repo.NoteRevision, error) {
+	var result repo.NoteRevision
+	err := rr.db.read(ctx, func(t *tables) error {
+		revision, ok := t.revisions[id]
+		if !ok || revision.NoteID != noteID || !t.readableNote(noteID, userID) {
+			return repo.ErrNotFound
+		}
+
+		result = revision
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (rr *noteRevisionRepo) GetAll(ctx context.Context, noteID, userID int64) ([]*repo.NoteRevision, error) {
+	result := make([]*repo.NoteRevision, 0)
+	err := rr.db.read(ctx, func(t *tables) error {
+		if !t.readableNote(noteID, userID) {
+			return nil
+		}
+
+		for _, revision := range t.revisions {
+			revision := revision
+			if revision.NoteID == noteID {
+				result = append(result, &revision)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+
+	return result, nil
+}
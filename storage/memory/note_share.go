@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type noteShareRepo struct {
+	db *DB
+}
+
+func NewNoteShare(db *DB) repo.NoteShareStorageI {
+	return &noteShareRepo{
+		db: db,
+	}
+}
+
+// withEmail returns a copy of the share with the email of the user it is shared with
+func (t *tables) withEmail(share repo.NoteShare) *repo.NoteShare {
+	share.Email = t.users[share.UserID].Email
+	return &share
+}
+
+func (sr *noteShareRepo) Create(ctx context.Context, ownerID int64, share *repo.NoteShare) (*repo.NoteShare, error) {
+	var result *repo.NoteShare
+	err := sr.db.write(ctx, func(t *tables) error {
+		if _, ok := t.activeNote(share.NoteID, ownerID); !ok {
+			return repo.ErrNotFound
+		}
+
+		key := noteUser{noteID: share.NoteID, userID: share.UserID}
+		if _, ok := t.shares[key]; ok {
+			return repo.ErrShareExists
+		}
+		if _, ok := t.users[share.UserID]; !ok {
+			return foreignKeyViolation("note_shares", "note_shares_user_id_fkey")
+		}
+
+		row := repo.NoteShare{
+			NoteID:     share.NoteID,
+			UserID:     share.UserID,
+			Permission: share.Permission,
+			CreatedAt:  time.Now(),
+		}
+		t.shares[key] = row
+
+		result = t.withEmail(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (sr *noteShareRepo) GetAll(ctx context.Context, noteID, ownerID int64) ([]*repo.NoteShare, error) {
+	result := make([]*repo.NoteShare, 0)
+	err := sr.db.read(ctx, func(t *tables) error {
+		if _, ok := t.activeNote(noteID, ownerID); !ok {
+			return repo.ErrNotFound
+		}
+
+		for key, share := range t.shares {
+			if key.noteID == noteID {
+				result = append(result, t.withEmail(share))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if c := compareTimes(result[i].CreatedAt, result[j].CreatedAt); c != 0 {
+			return c < 0
+		}
+
+		return result[i].UserID < result[j].UserID
+	})
+
+	return result, nil
+}
+
+func (sr *noteShareRepo) Update(ctx context.Context, ownerID int64, share *repo.NoteShare) (*repo.NoteShare, error) {
+	var result *repo.NoteShare
+	err := sr.db.write(ctx, func(t *tables) error {
+		key := noteUser{noteID: share.NoteID, userID: share.UserID}
+		row, ok := t.shares[key]
+		if !ok {
+			return repo.ErrNotFound
+		}
+		if _, ok := t.activeNote(share.NoteID, ownerID); !ok {
+			return repo.ErrNotFound
+		}
+
+		row.Permission = share.Permission
+		row.UpdatedAt = now()
+		t.shares[key] = row
+
+		result = t.withEmail(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete works for notes in the trash as well
+func (sr *noteShareRepo) Delete(ctx context.Context, noteID, ownerID, userID int64) error {
+	return sr.db.write(ctx, func(t *tables) error {
+		key := noteUser{noteID: noteID, userID: userID}
+		if _, ok := t.shares[key]; !ok {
+			return repo.ErrNotFound
+		}
+		if note, ok := t.notes[noteID]; !ok || note.UserID != ownerID {
+			return repo.ErrNotFound
+		}
+
+		delete(t.shares, key)
+		return nil
+	})
+}
@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type notebookRepo struct {
+	db *DB
+}
+
+func NewNotebook(db *DB) repo.NotebookStorageI {
+	return &notebookRepo{
+		db: db,
+	}
+}
+
+// notebookTree returns the ids of the notebook and all its descendants
+func (t *tables) notebookTree(id int64) map[int64]bool {
+	tree := map[int64]bool{id: true}
+	for grown := true; grown; {
+		grown = false
+		for _, notebook := range t.notebooks {
+			if notebook.ParentID != nil && tree[*notebook.ParentID] && !tree[notebook.ID] {
+				tree[notebook.ID] = true
+				grown = true
+			}
+		}
+	}
+
+	return tree
+}
+
+// deleteNotebook removes the notebook with its descendants and moves their notes to the top level
+func (t *tables) deleteNotebook(id int64) {
+	tree := t.notebookTree(id)
+	for noteID, note := range t.notes {
+		if note.NotebookID != nil && tree[*note.NotebookID] {
+			note.NotebookID = nil
+			t.notes[noteID] = note
+		}
+	}
+
+	for notebookID := range tree {
+		delete(t.notebooks, notebookID)
+	}
+}
+
+// checkParent does what the notebooks_check_parent trigger does
+func (t *tables) checkParent(notebook repo.Notebook) error {
+	if notebook.ParentID == nil {
+		return nil
+	}
+
+	parent, ok := t.notebooks[*notebook.ParentID]
+	if !ok || parent.UserID != notebook.UserID {
+		return repo.ErrNotebookNotFound
+	}
+
+	if notebook.ID != 0 && t.notebookTree(notebook.ID)[parent.ID] {
+		return repo.ErrNotebookCycle
+	}
+
+	return nil
+}
+
+// withNoteCount returns a copy of the notebook with the number of its active notes
+func (t *tables) withNoteCount(notebook repo.Notebook) *repo.Notebook {
+	notebook.NoteCount = 0
+	for _, note := range t.notes {
+		if note.DeletedAt == nil && note.NotebookID != nil && *note.NotebookID == notebook.ID {
+			notebook.NoteCount++
+		}
+	}
+
+	return &notebook
+}
+
+func (nr *notebookRepo) Create(ctx context.Context, notebook *repo.Notebook) (*repo.Notebook, error) {
+	err := nr.db.write(ctx, func(t *tables) error {
+		row := repo.Notebook{
+			UserID:   notebook.UserID,
+			ParentID: notebook.ParentID,
+			Name:     notebook.Name,
+		}
+		if err := t.checkParent(row); err != nil {
+			return err
+		}
+		if _, ok := t.users[row.UserID]; !ok {
+			return foreignKeyViolation("notebooks", "notebooks_user_id_fkey")
+		}
+
+		if row.ParentID != nil {
+			parentID := *row.ParentID
+			row.ParentID = &parentID
+		}
+		row.ID = t.nextID("notebooks")
+		row.CreatedAt = time.Now()
+		t.notebooks[row.ID] = row
+
+		notebook.ID = row.ID
+		notebook.CreatedAt = row.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notebook, nil
+}
+
+func (nr *notebookRepo) Get(ctx context.Context, id, userID int64) (*repo.Notebook, error) {
+	var result *repo.Notebook
+	err := nr.db.read(ctx, func(t *tables) error {
+		notebook, ok := t.notebooks[id]
+		if !ok || notebook.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		result = t.withNoteCount(notebook)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (nr *notebookRepo) GetAll(ctx context.Context, userID int64) ([]*repo.Notebook, error) {
+	result := make([]*repo.Notebook, 0)
+	err := nr.db.read(ctx, func(t *tables) error {
+		for _, notebook := range t.notebooks {
+			if notebook.UserID == userID {
+				result = append(result, t.withNoteCount(notebook))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+// update applies change to the notebook of the user and runs the parent check on the result
+func (nr *notebookRepo) update(ctx context.Context, id, userID int64, change func(n *repo.Notebook)) (*repo.Notebook, error) {
+	var result *repo.Notebook
+	err := nr.db.write(ctx, func(t *tables) error {
+		notebook, ok := t.notebooks[id]
+		if !ok || notebook.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		change(&notebook)
+		if err := t.checkParent(notebook); err != nil {
+			return err
+		}
+
+		notebook.UpdatedAt = now()
+		t.notebooks[id] = notebook
+
+		result = t.withNoteCount(notebook)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (nr *notebookRepo) Rename(ctx context.Context, id, userID int64, name string) (*repo.Notebook, error) {
+	return nr.update(ctx, id, userID, func(n *repo.Notebook) {
+		n.Name = name
+	})
+}
+
+func (nr *notebookRepo) Move(ctx context.Context, id, userID int64, parentID *int64) (*repo.Notebook, error) {
+	if parentID != nil {
+		copied := *parentID
+		parentID = &copied
+	}
+
+	return nr.update(ctx, id, userID, func(n *repo.Notebook) {
+		n.ParentID = parentID
+	})
+}
+
+func (nr *notebookRepo) Delete(ctx context.Context, id, userID int64) error {
+	return nr.db.write(ctx, func(t *tables) error {
+		notebook, ok := t.notebooks[id]
+		if !ok || notebook.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		t.deleteNotebook(id)
+		return nil
+	})
+}
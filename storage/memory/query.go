@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+// compareFunc orders two rows, it returns a negative number when a goes before b
+// in ascending order, zero when they are equal and a positive number otherwise
+type compareFunc[T any] func(a, b T) int
+
+// sortRows orders rows by the column allowed maps the key to in the given direction,
+// direction is asc or desc like in the postgres repos and anything else is rejected
+// with ErrInvalidSort. Rows that compare equal are ordered by tiebreak
+func sortRows[T any](rows []T, key, direction string, allowed map[string]compareFunc[T], tiebreak compareFunc[T]) error {
+	compare, ok := allowed[key]
+	if !ok {
+		return repo.ErrInvalidSort
+	}
+
+	direction = strings.ToUpper(direction)
+	if direction == "" {
+		direction = "DESC"
+	}
+	if direction != "ASC" && direction != "DESC" {
+		return repo.ErrInvalidSort
+	}
+
+	desc := direction == "DESC"
+	sort.SliceStable(rows, func(i, j int) bool {
+		c := compare(rows[i], rows[j])
+		if desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+
+		return tiebreak(rows[i], rows[j]) < 0
+	})
+
+	return nil
+}
+
+// page returns the rows of the page, a limit of zero returns all of them
+func page[T any](rows []T, page, limit int32) []T {
+	if limit <= 0 {
+		return rows
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	offset := int64(page-1) * int64(limit)
+	if offset > int64(len(rows)) {
+		offset = int64(len(rows))
+	}
+
+	end := offset + int64(limit)
+	if end > int64(len(rows)) {
+		end = int64(len(rows))
+	}
+
+	return rows[offset:end]
+}
+
+func compareStrings(a, b string) int {
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+
+	return 0
+}
+
+// compareNullTimes puts NULL after every time like postgres does in ascending order
+func compareNullTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	return compareTimes(*a, *b)
+}
+
+// containsFold is ILIKE with a pattern that matches substr anywhere in s
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func now() *time.Time {
+	t := time.Now()
+	return &t
+}
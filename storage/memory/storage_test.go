@@ -0,0 +1,93 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/burxondv/note-template/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, storage.NewStorageMemory())
+}
+
+func TestConcurrentCreate(t *testing.T) {
+	strg := storage.NewStorageMemory()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		created   int
+		conflicts int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := strg.User().Create(context.Background(), &repo.User{
+				FirstName: "Same",
+				LastName:  "Email",
+				Email:     "same@example.com",
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, repo.ErrConflict):
+				conflicts++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, created)
+	require.Equal(t, 19, conflicts)
+}
+
+func TestCanceledContext(t *testing.T) {
+	strg := storage.NewStorageMemory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := strg.User().Get(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWithTxRetry(t *testing.T) {
+	strg := storage.NewStorageMemory()
+	ctx := context.Background()
+
+	user, err := strg.User().Create(ctx, &repo.User{FirstName: "Tx", LastName: "Retry", Email: "tx@example.com"})
+	require.NoError(t, err)
+
+	// a write outside the transaction after its snapshot makes the commit fail once
+	attempts := 0
+	err = strg.WithTx(ctx, func(tx storage.StorageI) error {
+		attempts++
+		if attempts == 1 {
+			if err := strg.User().Activate(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		return tx.User().UpdateRole(ctx, user.ID, repo.RoleAdmin)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+
+	stored, err := strg.User().Get(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, stored.IsActive)
+	require.Equal(t, repo.RoleAdmin, stored.Role)
+}
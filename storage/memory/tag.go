@@ -0,0 +1,215 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type tagRepo struct {
+	db *DB
+}
+
+func NewTag(db *DB) repo.TagStorageI {
+	return &tagRepo{
+		db: db,
+	}
+}
+
+// deleteTag removes the tag with the links to its notes
+func (t *tables) deleteTag(id int64) {
+	for key := range t.noteTags {
+		if key.tagID == id {
+			delete(t.noteTags, key)
+		}
+	}
+
+	delete(t.tags, id)
+}
+
+// tagNameTaken enforces the unique name of the tags of a user
+func (t *tables) tagNameTaken(tag repo.Tag) bool {
+	for _, other := range t.tags {
+		if other.ID != tag.ID && other.UserID == tag.UserID && other.Name == tag.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tagWithNoteCount returns a copy of the tag with the number of notes linked to it
+func (t *tables) tagWithNoteCount(tag repo.Tag) *repo.Tag {
+	tag.NoteCount = 0
+	for key := range t.noteTags {
+		if key.tagID == tag.ID {
+			tag.NoteCount++
+		}
+	}
+
+	return &tag
+}
+
+func (tr *tagRepo) Create(ctx context.Context, tag *repo.Tag) (*repo.Tag, error) {
+	err := tr.db.write(ctx, func(t *tables) error {
+		row := repo.Tag{
+			UserID: tag.UserID,
+			Name:   tag.Name,
+		}
+		if t.tagNameTaken(row) {
+			return repo.ErrTagExists
+		}
+		if _, ok := t.users[row.UserID]; !ok {
+			return foreignKeyViolation("tags", "tags_user_id_fkey")
+		}
+
+		row.ID = t.nextID("tags")
+		row.CreatedAt = time.Now()
+		t.tags[row.ID] = row
+
+		tag.ID = row.ID
+		tag.CreatedAt = row.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (tr *tagRepo) Get(ctx context.Context, id, userID int64) (*repo.Tag, error) {
+	var result *repo.Tag
+	err := tr.db.read(ctx, func(t *tables) error {
+		tag, ok := t.tags[id]
+		if !ok || tag.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		result = t.tagWithNoteCount(tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (tr *tagRepo) GetAll(ctx context.Context, userID int64) ([]*repo.Tag, error) {
+	result := make([]*repo.Tag, 0)
+	err := tr.db.read(ctx, func(t *tables) error {
+		for _, tag := range t.tags {
+			if tag.UserID == userID {
+				result = append(result, t.tagWithNoteCount(tag))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+func (tr *tagRepo) Rename(ctx context.Context, id, userID int64, name string) (*repo.Tag, error) {
+	var result *repo.Tag
+	err := tr.db.write(ctx, func(t *tables) error {
+		tag, ok := t.tags[id]
+		if !ok || tag.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		tag.Name = name
+		if t.tagNameTaken(tag) {
+			return repo.ErrTagExists
+		}
+
+		t.tags[id] = tag
+		result = t.tagWithNoteCount(tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Merge relinks the notes and drops the source tag, notes that already
+// had both tags keep a single link to the target
+func (tr *tagRepo) Merge(ctx context.Context, sourceID, targetID, userID int64) (*repo.Tag, error) {
+	err := tr.db.write(ctx, func(t *tables) error {
+		source, ok := t.tags[sourceID]
+		if !ok || source.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		target, ok := t.tags[targetID]
+		if !ok || target.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		for key := range t.noteTags {
+			if key.tagID == sourceID {
+				t.noteTags[noteTag{noteID: key.noteID, tagID: targetID}] = true
+			}
+		}
+
+		t.deleteTag(sourceID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.Get(ctx, targetID, userID)
+}
+
+func (tr *tagRepo) Delete(ctx context.Context, id, userID int64) error {
+	return tr.db.write(ctx, func(t *tables) error {
+		tag, ok := t.tags[id]
+		if !ok || tag.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		t.deleteTag(id)
+		return nil
+	})
+}
+
+func (tr *tagRepo) Attach(ctx context.Context, noteID, tagID, userID int64) error {
+	return tr.db.write(ctx, func(t *tables) error {
+		if _, ok := t.activeNote(noteID, userID); !ok {
+			return repo.ErrNotFound
+		}
+
+		tag, ok := t.tags[tagID]
+		if !ok || tag.UserID != userID {
+			return repo.ErrNotFound
+		}
+
+		t.noteTags[noteTag{noteID: noteID, tagID: tagID}] = true
+		return nil
+	})
+}
+
+func (tr *tagRepo) Detach(ctx context.Context, noteID, tagID, userID int64) error {
+	return tr.db.write(ctx, func(t *tables) error {
+		key := noteTag{noteID: noteID, tagID: tagID}
+		if _, ok := t.activeNote(noteID, userID); !ok || !t.noteTags[key] {
+			return repo.ErrNotFound
+		}
+
+		delete(t.noteTags, key)
+		return nil
+	})
+}
@@ -0,0 +1,309 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type userRepo struct {
+	db *DB
+}
+
+func NewUser(db *DB) repo.UserStorageI {
+	return &userRepo{
+		db: db,
+	}
+}
+
+// checkUnique enforces the unique email and phone number, an empty phone number is NULL
+func (t *tables) checkUnique(user *repo.User) error {
+	for _, other := range t.users {
+		if other.ID == user.ID {
+			continue
+		}
+
+		if other.Email == user.Email {
+			return uniqueViolation("users_email_key")
+		}
+		if user.PhoneNumber != "" && other.PhoneNumber == user.PhoneNumber {
+			return uniqueViolation("users_phone_number_key")
+		}
+	}
+
+	return nil
+}
+
+func (ur *userRepo) Create(ctx context.Context, user *repo.User) (*repo.User, error) {
+	if user.Role == "" {
+		user.Role = repo.RoleUser
+	}
+
+	err := ur.db.write(ctx, func(t *tables) error {
+		row := *user
+		row.ID = 0
+		if err := t.checkUnique(&row); err != nil {
+			return err
+		}
+
+		row.ID = t.nextID("users")
+		row.Version = 1
+		row.CreatedAt = time.Now()
+		row.UpdatedAt = nil
+		row.DeletedAt = nil
+		t.users[row.ID] = row
+
+		user.ID = row.ID
+		user.Version = row.Version
+		user.CreatedAt = row.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (ur *userRepo) Get(ctx context.Context, id int64) (*repo.User, error) {
+	var result repo.User
+	err := ur.db.read(ctx, func(t *tables) error {
+		user, ok := t.users[id]
+		if !ok {
+			return repo.ErrNotFound
+		}
+
+		result = user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (ur *userRepo) GetByEmail(ctx context.Context, email string) (*repo.User, error) {
+	var result repo.User
+	err := ur.db.read(ctx, func(t *tables) error {
+		for _, user := range t.users {
+			if user.Email == email {
+				result = user
+				return nil
+			}
+		}
+
+		return repo.ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+var userSortColumns = map[string]compareFunc[*repo.User]{
+	"created_at": func(a, b *repo.User) int { return compareTimes(a.CreatedAt, b.CreatedAt) },
+	"first_name": func(a, b *repo.User) int { return compareStrings(a.FirstName, b.FirstName) },
+	"last_name":  func(a, b *repo.User) int { return compareStrings(a.LastName, b.LastName) },
+	"email":      func(a, b *repo.User) int { return compareStrings(a.Email, b.Email) },
+}
+
+func (ur *userRepo) GetAll(ctx context.Context, params *repo.GetAllUsersParams) (*repo.GetAllUsersResult, error) {
+	users := make([]*repo.User, 0)
+	err := ur.db.read(ctx, func(t *tables) error {
+		for _, user := range t.users {
+			user := user
+			if params.Search != "" &&
+				!containsFold(user.FirstName, params.Search) &&
+				!containsFold(user.LastName, params.Search) &&
+				!containsFold(user.Email, params.Search) {
+				continue
+			}
+
+			users = append(users, &user)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortBy := params.SortBy
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+
+	err = sortRows(users, sortBy, params.SortOrder, userSortColumns, func(a, b *repo.User) int {
+		return compareInts(b.ID, a.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &repo.GetAllUsersResult{
+		Users: page(users, params.Page, params.Limit),
+		Count: int32(len(users)),
+	}, nil
+}
+
+// update applies change to the user when the version still matches the stored one
+func (ur *userRepo) update(ctx context.Context, id, version int64, change func(u *repo.User) error) (*repo.User, error) {
+	var result repo.User
+	err := ur.db.write(ctx, func(t *tables) error {
+		user, ok := t.users[id]
+		if !ok {
+			return repo.ErrNotFound
+		}
+		if user.Version != version {
+			return repo.ErrVersionMismatch
+		}
+
+		if err := change(&user); err != nil {
+			return err
+		}
+		if err := t.checkUnique(&user); err != nil {
+			return err
+		}
+
+		user.Version++
+		user.UpdatedAt = now()
+		t.users[id] = user
+
+		result = user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (ur *userRepo) Update(ctx context.Context, user *repo.User) (*repo.User, error) {
+	return ur.update(ctx, user.ID, user.Version, func(u *repo.User) error {
+		u.FirstName = user.FirstName
+		u.LastName = user.LastName
+		u.PhoneNumber = user.PhoneNumber
+		u.Email = user.Email
+		u.ImageURL = user.ImageURL
+		return nil
+	})
+}
+
+var userPatchColumns = map[string]func(u *repo.User, value string){
+	"first_name":   func(u *repo.User, value string) { u.FirstName = value },
+	"last_name":    func(u *repo.User, value string) { u.LastName = value },
+	"phone_number": func(u *repo.User, value string) { u.PhoneNumber = value },
+	"email":        func(u *repo.User, value string) { u.Email = value },
+	"image_url":    func(u *repo.User, value string) { u.ImageURL = value },
+}
+
+func (ur *userRepo) Patch(ctx context.Context, patch *repo.UserPatch) (*repo.User, error) {
+	for column := range patch.Fields {
+		if _, ok := userPatchColumns[column]; !ok {
+			return nil, patchError(column)
+		}
+	}
+
+	return ur.update(ctx, patch.ID, patch.Version, func(u *repo.User) error {
+		for column, value := range patch.Fields {
+			s, err := patchString(column, value)
+			if err != nil {
+				return err
+			}
+
+			userPatchColumns[column](u, s)
+		}
+
+		return nil
+	})
+}
+
+// Delete fails with ErrInvalidReference while the user has notes, the notes
+// reference the user without a cascade. Everything else of the user is deleted
+func (ur *userRepo) Delete(ctx context.Context, id int64) error {
+	return ur.db.write(ctx, func(t *tables) error {
+		if _, ok := t.users[id]; !ok {
+			return repo.ErrNotFound
+		}
+
+		for _, note := range t.notes {
+			if note.UserID == id {
+				return foreignKeyViolation("notes", "notes_user_id_fkey")
+			}
+		}
+
+		for tokenID, token := range t.apiTokens {
+			if token.UserID == id {
+				delete(t.apiTokens, tokenID)
+			}
+		}
+		for revisionID, revision := range t.revisions {
+			if revision.AuthorID != nil && *revision.AuthorID == id {
+				revision.AuthorID = nil
+				t.revisions[revisionID] = revision
+			}
+		}
+		for tagID, tag := range t.tags {
+			if tag.UserID == id {
+				t.deleteTag(tagID)
+			}
+		}
+		for notebookID, notebook := range t.notebooks {
+			if notebook.UserID == id {
+				t.deleteNotebook(notebookID)
+			}
+		}
+		for key := range t.shares {
+			if key.userID == id {
+				delete(t.shares, key)
+			}
+		}
+		for linkID, link := range t.links {
+			if link.UserID == id {
+				delete(t.links, linkID)
+			}
+		}
+
+		delete(t.users, id)
+		return nil
+	})
+}
+
+// change applies fn to the user and bumps the version, the user must exist
+func (ur *userRepo) change(ctx context.Context, id int64, fn func(u *repo.User)) error {
+	return ur.db.write(ctx, func(t *tables) error {
+		user, ok := t.users[id]
+		if !ok {
+			return repo.ErrNotFound
+		}
+
+		fn(&user)
+		user.Version++
+		t.users[id] = user
+		return nil
+	})
+}
+
+func (ur *userRepo) Activate(ctx context.Context, id int64) error {
+	return ur.change(ctx, id, func(u *repo.User) {
+		u.IsActive = true
+	})
+}
+
+func (ur *userRepo) UpdatePassword(ctx context.Context, id int64, password string) error {
+	return ur.change(ctx, id, func(u *repo.User) {
+		u.Password = password
+		u.UpdatedAt = now()
+	})
+}
+
+func (ur *userRepo) UpdateRole(ctx context.Context, id int64, role string) error {
+	return ur.change(ctx, id, func(u *repo.User) {
+		u.Role = role
+		u.UpdatedAt = now()
+	})
+}
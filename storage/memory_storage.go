@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/burxondv/note-template/storage/memory"
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+type storageMemory struct {
+	// db is nil when the repos run inside a transaction
+	db *memory.DB
+
+	userRepo     repo.UserStorageI
+	noteRepo     repo.NoteStorageI
+	apiTokenRepo repo.ApiTokenStorageI
+	revisionRepo repo.NoteRevisionStorageI
+	tagRepo      repo.TagStorageI
+	notebookRepo repo.NotebookStorageI
+	shareRepo    repo.NoteShareStorageI
+	linkRepo     repo.NoteLinkStorageI
+}
+
+// NewStorageMemory builds the repos on an empty in-memory database, the data is lost on exit
+func NewStorageMemory() StorageI {
+	db := memory.NewDB()
+
+	s := newStorageMemory(db)
+	s.db = db
+
+	return s
+}
+
+func newStorageMemory(db *memory.DB) *storageMemory {
+	return &storageMemory{
+		userRepo:     memory.NewUser(db),
+		noteRepo:     memory.NewNote(db),
+		apiTokenRepo: memory.NewApiToken(db),
+		revisionRepo: memory.NewNoteRevision(db),
+		tagRepo:      memory.NewTag(db),
+		notebookRepo: memory.NewNotebook(db),
+		shareRepo:    memory.NewNoteShare(db),
+		linkRepo:     memory.NewNoteLink(db),
+	}
+}
+
+func (s *storageMemory) WithTx(ctx context.Context, fn func(StorageI) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+
+	return memory.RunInTx(ctx, s.db, func(tx *memory.DB) error {
+		return fn(newStorageMemory(tx))
+	})
+}
+
+func (s *storageMemory) User() repo.UserStorageI {
+	return s.userRepo
+}
+
+func (s *storageMemory) Note() repo.NoteStorageI {
+	return s.noteRepo
+}
+
+func (s *storageMemory) ApiToken() repo.ApiTokenStorageI {
+	return s.apiTokenRepo
+}
+
+func (s *storageMemory) NoteRevision() repo.NoteRevisionStorageI {
+	return s.revisionRepo
+}
+
+func (s *storageMemory) Tag() repo.TagStorageI {
+	return s.tagRepo
+}
+
+func (s *storageMemory) Notebook() repo.NotebookStorageI {
+	return s.notebookRepo
+}
+
+func (s *storageMemory) NoteShare() repo.NoteShareStorageI {
+	return s.shareRepo
+}
+
+func (s *storageMemory) NoteLink() repo.NoteLinkStorageI {
+	return s.linkRepo
+}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type apiTokenRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewApiToken(db sqlx.ExtContext, queryTimeout time.Duration) repo.ApiTokenStorageI {
+	return &apiTokenRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (tr *apiTokenRepo) Create(ctx context.Context, token *repo.ApiToken) (*repo.ApiToken, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO api_tokens(
+			user_id,
+			name,
+			token_hash,
+			scopes,
+			expires_at
+		) VALUES($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	row := tr.db.QueryRowxContext(ctx,
+		query,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		strings.Join(token.Scopes, " "),
+		token.ExpiresAt,
+	)
+
+	err := row.Scan(
+		&token.ID,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return token, nil
+}
+
+func (tr *apiTokenRepo) GetByHash(ctx context.Context, tokenHash string) (*repo.ApiToken, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			token_hash,
+			scopes,
+			expires_at,
+			last_used_at,
+			created_at
+		FROM api_tokens
+		WHERE token_hash=$1
+	`
+
+	return scanApiToken(tr.db.QueryRowxContext(ctx, query, tokenHash))
+}
+
+func (tr *apiTokenRepo) GetAll(ctx context.Context, userID int64) ([]*repo.ApiToken, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			token_hash,
+			scopes,
+			expires_at,
+			last_used_at,
+			created_at
+		FROM api_tokens
+		WHERE user_id=$1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := tr.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := make([]*repo.ApiToken, 0)
+	for rows.Next() {
+		token, err := scanApiToken(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, token)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+func (tr *apiTokenRepo) UpdateLastUsed(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := "UPDATE api_tokens SET last_used_at=CURRENT_TIMESTAMP WHERE id=$1"
+
+	_, err := tr.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
+
+func (tr *apiTokenRepo) Delete(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := "DELETE FROM api_tokens WHERE id=$1 AND user_id=$2"
+
+	result, err := tr.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanApiToken(row scanner) (*repo.ApiToken, error) {
+	var (
+		result repo.ApiToken
+		scopes string
+	)
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Name,
+		&result.TokenHash,
+		&scopes,
+		&result.ExpiresAt,
+		&result.LastUsedAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	result.Scopes = strings.Fields(scopes)
+
+	return &result, nil
+}
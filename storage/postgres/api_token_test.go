@@ -0,0 +1,71 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createApiToken(t *testing.T, userID int64) *repo.ApiToken {
+	token, err := strg.ApiToken().Create(context.Background(), &repo.ApiToken{
+		UserID:    userID,
+		Name:      faker.Word(),
+		TokenHash: faker.UUIDDigit(),
+		Scopes:    []string{"notes:read", "notes:write"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	return token
+}
+
+func TestGetApiTokenByHash(t *testing.T) {
+	user := createUser(t)
+	c := createApiToken(t, user.ID)
+
+	token, err := strg.ApiToken().GetByHash(context.Background(), c.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, c.ID, token.ID)
+	require.Equal(t, c.Scopes, token.Scopes)
+	require.Nil(t, token.LastUsedAt)
+
+	err = strg.ApiToken().UpdateLastUsed(context.Background(), c.ID)
+	require.NoError(t, err)
+
+	token, err = strg.ApiToken().GetByHash(context.Background(), c.TokenHash)
+	require.NoError(t, err)
+	require.NotNil(t, token.LastUsedAt)
+
+	deleteUser(user.ID, t)
+}
+
+func TestGetAllApiTokens(t *testing.T) {
+	user := createUser(t)
+	createApiToken(t, user.ID)
+	createApiToken(t, user.ID)
+
+	tokens, err := strg.ApiToken().GetAll(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+
+	deleteUser(user.ID, t)
+}
+
+func TestDeleteApiToken(t *testing.T) {
+	user := createUser(t)
+	c := createApiToken(t, user.ID)
+
+	err := strg.ApiToken().Delete(context.Background(), c.ID, user.ID+1)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	err = strg.ApiToken().Delete(context.Background(), c.ID, user.ID)
+	require.NoError(t, err)
+
+	_, err = strg.ApiToken().GetByHash(context.Background(), c.TokenHash)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	deleteUser(user.ID, t)
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/lib/pq"
+)
+
+// postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+	queryCanceled       = "57014"
+)
+
+// dbError matches one of the kinds of storage/repo errors and keeps the driver error it came from
+type dbError struct {
+	kind error
+	err  error
+}
+
+func (e *dbError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *dbError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *dbError) Unwrap() error {
+	return e.err
+}
+
+// translateError turns a missing row and constraint violations into the errors of
+// storage/repo and a query canceled by its context into context.DeadlineExceeded,
+// everything else is returned as it is
+func translateError(err error) error {
+	var pqErr *pq.Error
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repo.ErrNotFound), errors.Is(err, repo.ErrConflict), errors.Is(err, repo.ErrInvalidReference):
+		return err
+	case errors.Is(err, sql.ErrNoRows):
+		return &dbError{kind: repo.ErrNotFound, err: err}
+	case errors.As(err, &pqErr) && pqErr.Code == uniqueViolation:
+		return &dbError{kind: repo.ErrConflict, err: err}
+	case errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation:
+		return &dbError{kind: repo.ErrInvalidReference, err: err}
+	case errors.As(err, &pqErr) && pqErr.Code == queryCanceled:
+		return &dbError{kind: context.DeadlineExceeded, err: err}
+	}
+
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
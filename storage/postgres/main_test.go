@@ -32,6 +32,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("failed to open connection: %v", err)
 	}
 
-	strg = storage.NewStoragePg(db)
+	strg = storage.NewStoragePg(db, cfg.QueryTimeout)
 	os.Exit(m.Run())
 }
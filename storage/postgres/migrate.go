@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/burxondv/note-template/migrations"
+	"github.com/burxondv/note-template/storage/schema"
+	migratepg "github.com/golang-migrate/migrate/v4/database/postgres"
+)
+
+// NewMigrator applies the migrations of the migrations directory to db. The migrations
+// run on a connection of their own taken from db, Close gives it back
+func NewMigrator(ctx context.Context, db *sql.DB) (*schema.Migrator, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := migratepg.WithConnection(ctx, conn, &migratepg.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	migrator, err := schema.New(migrations.FS, "postgres", driver, conn.Close)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return migrator, nil
+}
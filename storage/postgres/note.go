@@ -1,34 +1,58 @@
 package postgres
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/burxondv/note-template/storage/repo"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ts_headline delimits the matches of a full-text search with control characters,
+// markHighlight escapes the note text before it turns them into <mark> tags
+const (
+	headlineStart   = "\x02"
+	headlineStop    = "\x03"
+	headlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", MaxWords=35, MinWords=15, MaxFragments=3"
 )
 
 type noteRepo struct {
-	db *sqlx.DB
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
 }
 
-func NewNote(db *sqlx.DB) repo.NoteStorageI {
+func NewNote(db sqlx.ExtContext, queryTimeout time.Duration) repo.NoteStorageI {
 	return &noteRepo{
-		db: db,
+		db:           db,
+		queryTimeout: queryTimeout,
 	}
 }
 
-func (ur *noteRepo) Create(note *repo.Note) (*repo.Note, error) {
+func (ur *noteRepo) Create(ctx context.Context, note *repo.Note) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
 	query := `
-		INSERT INTO notes(
-			user_id,
-			title,
-			description
-		) VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		WITH note AS (
+			INSERT INTO notes(
+				user_id,
+				title,
+				description
+			) VALUES ($1, $2, $3)
+			RETURNING id, user_id, title, description, version, created_at
+		), revision AS (
+			INSERT INTO note_revisions(note_id, author_id, title, description)
+			SELECT id, user_id, title, description FROM note
+		)
+		SELECT id, version, created_at FROM note
 	`
 
-	row := ur.db.QueryRow(
+	row := ur.db.QueryRowxContext(ctx,
 		query,
 		note.UserID,
 		note.Title,
@@ -37,167 +61,510 @@ func (ur *noteRepo) Create(note *repo.Note) (*repo.Note, error) {
 
 	err := row.Scan(
 		&note.ID,
+		&note.Version,
 		&note.CreatedAt,
 	)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
+	note.Tags = make([]*repo.Tag, 0)
 
 	return note, nil
 }
 
-func (ur *noteRepo) Get(id int64) (*repo.Note, error) {
-	var result repo.Note
+func (ur *noteRepo) Get(ctx context.Context, id, userID int64) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
 
 	query := `
 		SELECT 
 		    id,
             user_id,
+			notebook_id,
             title,
 			description,
+			version,
             created_at,
 			updated_at,
 			deleted_at
 		FROM notes
-        WHERE id=$1
+        WHERE id=$1 AND deleted_at IS NULL AND ` + canReadNote("notes", 2) + `
 	`
 
-	row := ur.db.QueryRow(query, id)
-
-	err := row.Scan(
-		&result.ID,
-		&result.UserID,
-		&result.Title,
-		&result.Description,
-		&result.CreatedAt,
-		&result.UpdatedAt,
-		&result.DeletedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
+	return ur.scanNoteWithTags(ctx, ur.db.QueryRowxContext(ctx, query, id, userID))
+}
 
-	return &result, nil
+var noteSortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
 }
 
-func (ur *noteRepo) GetAll(params *repo.GetAllNotesParams) (*repo.GetAllNotesResult, error) {
+func (ur *noteRepo) GetAll(ctx context.Context, params *repo.GetAllNotesParams) (*repo.GetAllNotesResult, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
 	result := repo.GetAllNotesResult{
 		Notes: make([]*repo.Note, 0),
 	}
 
-	offset := (params.Page - 1) * params.Limit
-
-	limit := fmt.Sprintf(" LIMIT %d OFFSET %d", params.Limit, offset)
+	q := newSelectQuery(
+		"notes",
+		"id",
+		"user_id",
+		"notebook_id",
+		"title",
+		"description",
+		"version",
+		"created_at",
+		"updated_at",
+		"deleted_at",
+	)
 
-	filter := "WHERE true"
-	if params.Search != "" {
-		filter += " AND title ilike '%" + params.Search + "%' "
+	userID := q.arg(params.UserID)
+	if params.IncludeShared && !params.Deleted {
+		q.where(fmt.Sprintf("user_id=%[1]s OR id IN (SELECT note_id FROM note_shares WHERE user_id=%[1]s)", userID))
+	} else {
+		q.where("user_id=" + userID)
+	}
+	if params.Deleted {
+		q.where("deleted_at IS NOT NULL")
+	} else {
+		q.where("deleted_at IS NULL")
 	}
 
-	if params.UserID != 0 {
-		filter += fmt.Sprintf(" AND user_id=%d ", params.UserID)
+	var search string
+	if params.Search != "" && params.FullText {
+		search = "websearch_to_tsquery('english', " + q.arg(params.Search) + ")"
+		q.where("search_vector @@ " + search)
+		q.column("ts_headline('english', title, " + search + ", '" + headlineOptions + ", HighlightAll=true')")
+		q.column("ts_headline('english', description, " + search + ", '" + headlineOptions + "')")
+	} else if params.Search != "" {
+		q.where("title ILIKE " + q.arg(likePattern(params.Search)))
 	}
 
-	orderBy := " ORDER BY created_at desc "
-	if params.SortByData != "" {
-		orderBy = fmt.Sprintf(" ORDER BY created_at %s ", params.SortByData)
+	if params.NotebookID != nil && params.IncludeDescendants {
+		q.where(`notebook_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM notebooks WHERE id=` + q.arg(*params.NotebookID) + `
+				UNION ALL
+				SELECT nb.id FROM notebooks nb JOIN tree t ON nb.parent_id=t.id
+			)
+			SELECT id FROM tree
+		)`)
+	} else if params.NotebookID != nil {
+		q.where("notebook_id=" + q.arg(*params.NotebookID))
+	}
+	if len(params.TagsAny) > 0 {
+		q.where("id IN (SELECT note_id FROM note_tags WHERE tag_id = ANY(" + q.arg(pq.Array(params.TagsAny)) + "))")
+	}
+	if len(params.TagsAll) > 0 {
+		tags := q.arg(pq.Array(params.TagsAll))
+		q.where(`id IN (
+			SELECT note_id FROM note_tags WHERE tag_id = ANY(` + tags + `)
+			GROUP BY note_id HAVING count(DISTINCT tag_id) = cardinality(` + tags + `::int[])
+		)`)
+	}
+	if len(params.TagsExclude) > 0 {
+		q.where("id NOT IN (SELECT note_id FROM note_tags WHERE tag_id = ANY(" + q.arg(pq.Array(params.TagsExclude)) + "))")
 	}
 
-	query := `
-		SELECT
-			id,
-			user_id,
-			title,
-			description,
-			created_at,
-            updated_at,
-            deleted_at
-		FROM notes
-		` + filter + orderBy + limit
+	if search != "" && params.SortBy == "" {
+		q.sortBy("ts_rank(search_vector, " + search + ") DESC")
+		q.sortBy("created_at DESC")
+	} else {
+		sortBy := params.SortBy
+		if sortBy == "" {
+			sortBy = "created_at"
+		}
+
+		err := q.sort(sortBy, params.SortOrder, noteSortColumns)
+		if err != nil {
+			return nil, translateError(err)
+		}
+	}
+	q.sortBy("id DESC")
+	q.page(params.Page, params.Limit)
 
-	rows, err := ur.db.Query(query)
+	query, args := q.sql()
+	rows, err := ur.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	defer rows.Close()
 	for rows.Next() {
-		var u repo.Note
-
-		err := rows.Scan(
-			&u.ID,
-			&u.UserID,
-			&u.Title,
-			&u.Description,
-			&u.CreatedAt,
-			&u.UpdatedAt,
-			&u.DeletedAt,
-		)
+		var u *repo.Note
+		if search != "" {
+			u, err = scanNoteHighlight(rows)
+		} else {
+			u, err = scanNote(rows)
+		}
 		if err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 
-		result.Notes = append(result.Notes, &u)
+		result.Notes = append(result.Notes, u)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, translateError(err)
 	}
 
-	queryCount := `SELECT count(*) FROM notes ` + filter
-	err = ur.db.QueryRow(queryCount).Scan(&result.Count)
+	err = ur.loadTags(ctx, result.Notes...)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
+	}
+
+	queryCount, args := q.countSQL()
+	err = ur.db.QueryRowxContext(ctx, queryCount, args...).Scan(&result.Count)
+	if err != nil {
+		return nil, translateError(err)
 	}
 
 	return &result, nil
 }
 
-func (ur *noteRepo) Update(note *repo.Note) (*repo.Note, error) {
+// Update changes the note and records the new content as a revision in the same statement,
+// the version is checked in the WHERE clause so a concurrent update can not be overwritten.
+// The revision is authored by the acting user who is the owner or an editor
+func (ur *noteRepo) Update(ctx context.Context, note *repo.Note) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
 	query := `
-		UPDATE notes SET
-			title=$1,
-            description=$2
-		WHERE id=$3
-		RETURNING id, user_id, title, description, created_at, updated_at, deleted_at
+		WITH note AS (
+			UPDATE notes SET
+				title=$1,
+				description=$2,
+				version=version+1,
+				updated_at=CURRENT_TIMESTAMP
+			WHERE id=$3 AND version=$5 AND deleted_at IS NULL AND ` + canWriteNote("notes", 4) + `
+			RETURNING id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at
+		), revision AS (
+			INSERT INTO note_revisions(note_id, author_id, title, description)
+			SELECT id, $4, title, description FROM note
+		)
+		SELECT id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at FROM note
 	`
 
-	row := ur.db.QueryRow(
+	row := ur.db.QueryRowxContext(ctx,
 		query,
 		note.Title,
 		note.Description,
 		note.ID,
+		note.UserID,
+		note.Version,
 	)
 
+	result, err := ur.scanNoteWithTags(ctx, row)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, ur.updateError(ctx, note.ID, note.UserID)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result, nil
+}
+
+var notePatchColumns = map[string]patchColumn{
+	"title":       {},
+	"description": {},
+}
+
+// Patch updates only the given columns and records the result as a revision like Update does
+func (ur *noteRepo) Patch(ctx context.Context, patch *repo.NotePatch) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	set, args, err := setClause(patch.Fields, notePatchColumns)
+	if err != nil {
+		return nil, err
+	}
+	if set != "" {
+		set += ", "
+	}
+
+	n := len(args)
+	query := fmt.Sprintf(`
+		WITH note AS (
+			UPDATE notes SET
+				%sversion=version+1,
+				updated_at=CURRENT_TIMESTAMP
+			WHERE id=$%d AND version=$%d AND deleted_at IS NULL AND %s
+			RETURNING id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at
+		), revision AS (
+			INSERT INTO note_revisions(note_id, author_id, title, description)
+			SELECT id, $%d, title, description FROM note
+		)
+		SELECT id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at FROM note
+	`, set, n+1, n+3, canWriteNote("notes", n+2), n+2)
+
+	args = append(args, patch.ID, patch.UserID, patch.Version)
+
+	result, err := ur.scanNoteWithTags(ctx, ur.db.QueryRowxContext(ctx, query, args...))
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, ur.updateError(ctx, patch.ID, patch.UserID)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result, nil
+}
+
+// updateError tells apart a missing note, a read-only share and a stale version
+// after an update matched no rows
+func (ur *noteRepo) updateError(ctx context.Context, id, userID int64) error {
+	query := `
+		SELECT
+			` + canReadNote("notes", 2) + `,
+			` + canWriteNote("notes", 2) + `
+		FROM notes
+		WHERE id=$1 AND deleted_at IS NULL
+	`
+
+	var readable, writable bool
+	err := ur.db.QueryRowxContext(ctx, query, id, userID).Scan(&readable, &writable)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if !readable {
+		return repo.ErrNotFound
+	}
+	if !writable {
+		return repo.ErrReadOnly
+	}
+
+	return repo.ErrVersionMismatch
+}
+
+// Delete moves the note to the trash, it can be restored until it is purged
+func (ur *noteRepo) Delete(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notes SET
+			deleted_at=CURRENT_TIMESTAMP,
+			version=version+1
+		WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL
+	`
+
+	result, err := ur.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (ur *noteRepo) Restore(ctx context.Context, id, userID int64) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notes SET
+			deleted_at=NULL,
+			version=version+1
+		WHERE id=$1 AND user_id=$2 AND deleted_at IS NOT NULL
+		RETURNING id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at
+	`
+
+	return ur.scanNoteWithTags(ctx, ur.db.QueryRowxContext(ctx, query, id, userID))
+}
+
+// Move checks in the same statement that the notebook belongs to the owner of the note
+func (ur *noteRepo) Move(ctx context.Context, id, userID int64, notebookID *int64) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notes SET
+			notebook_id=$1,
+			version=version+1
+		WHERE id=$2 AND user_id=$3 AND deleted_at IS NULL
+			AND ($1::int IS NULL OR EXISTS (SELECT 1 FROM notebooks WHERE id=$1 AND user_id=$3))
+		RETURNING id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at
+	`
+
+	result, err := ur.scanNoteWithTags(ctx, ur.db.QueryRowxContext(ctx, query, notebookID, id, userID))
+	if !errors.Is(err, repo.ErrNotFound) {
+		return result, err
+	}
+
+	var owned bool
+	query = "SELECT EXISTS(SELECT 1 FROM notes WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL)"
+	err = ur.db.QueryRowxContext(ctx, query, id, userID).Scan(&owned)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if !owned {
+		return nil, repo.ErrNotFound
+	}
+
+	return nil, repo.ErrNotebookNotFound
+}
+
+// Purge deletes the note permanently whether it is in the trash or not
+func (ur *noteRepo) Purge(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := "DELETE FROM notes WHERE id=$1 AND user_id=$2"
+
+	result, err := ur.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanNote(row scanner) (*repo.Note, error) {
 	var result repo.Note
+
 	err := row.Scan(
 		&result.ID,
 		&result.UserID,
+		&result.NotebookID,
 		&result.Title,
 		&result.Description,
+		&result.Version,
 		&result.CreatedAt,
 		&result.UpdatedAt,
 		&result.DeletedAt,
 	)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
-	return note, nil
+	return &result, nil
 }
 
-func (ur *noteRepo) Delete(id int64) error {
-	query := "DELETE FROM notes WHERE id=$1"
+// markHighlight HTML-escapes a ts_headline fragment and wraps its matches in <mark> tags
+func markHighlight(fragment string) string {
+	fragment = html.EscapeString(fragment)
+	fragment = strings.ReplaceAll(fragment, headlineStart, "<mark>")
+	return strings.ReplaceAll(fragment, headlineStop, "</mark>")
+}
+
+func scanNoteHighlight(row scanner) (*repo.Note, error) {
+	var (
+		result    repo.Note
+		highlight repo.NoteHighlight
+	)
 
-	result, err := ur.db.Exec(query, id)
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.NotebookID,
+		&result.Title,
+		&result.Description,
+		&result.Version,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.DeletedAt,
+		&highlight.Title,
+		&highlight.Description,
+	)
 	if err != nil {
-		return err
+		return nil, translateError(err)
 	}
+	highlight.Title = markHighlight(highlight.Title)
+	highlight.Description = markHighlight(highlight.Description)
+	result.Highlight = &highlight
 
-	rowsCount, err := result.RowsAffected()
+	return &result, nil
+}
+
+func (ur *noteRepo) scanNoteWithTags(ctx context.Context, row scanner) (*repo.Note, error) {
+	note, err := scanNote(row)
 	if err != nil {
-		return err
+		return nil, translateError(err)
 	}
 
-	if rowsCount == 0 {
-		return sql.ErrNoRows
+	err = ur.loadTags(ctx, note)
+	if err != nil {
+		return nil, translateError(err)
 	}
 
-	return nil
+	return note, nil
+}
+
+// loadTags fills the tags of all the notes with a single query
+func (ur *noteRepo) loadTags(ctx context.Context, notes ...*repo.Note) error {
+	ids := make([]int64, 0, len(notes))
+	byID := make(map[int64]*repo.Note, len(notes))
+	for _, note := range notes {
+		note.Tags = make([]*repo.Tag, 0)
+		ids = append(ids, note.ID)
+		byID[note.ID] = note
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT
+			nt.note_id,
+			t.id,
+			t.user_id,
+			t.name,
+			t.created_at
+		FROM note_tags nt
+		JOIN tags t ON t.id=nt.tag_id
+		WHERE nt.note_id = ANY($1)
+		ORDER BY t.name
+	`
+
+	rows, err := ur.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return translateError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			noteID int64
+			tag    repo.Tag
+		)
+
+		err := rows.Scan(
+			&noteID,
+			&tag.ID,
+			&tag.UserID,
+			&tag.Name,
+			&tag.CreatedAt,
+		)
+		if err != nil {
+			return translateError(err)
+		}
+
+		byID[noteID].Tags = append(byID[noteID].Tags, &tag)
+	}
+
+	return translateError(rows.Err())
 }
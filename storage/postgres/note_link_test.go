@@ -0,0 +1,53 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteLinkViews(t *testing.T) {
+	note := createNote(t)
+	maxViews := int64(2)
+
+	link, err := strg.NoteLink().Create(context.Background(), &repo.NoteLink{
+		NoteID:    note.ID,
+		UserID:    note.UserID,
+		TokenHash: faker.UUIDDigit(),
+		MaxViews:  &maxViews,
+	})
+	require.NoError(t, err)
+
+	_, err = strg.NoteLink().Create(context.Background(), &repo.NoteLink{
+		NoteID:    note.ID,
+		UserID:    note.UserID + 1,
+		TokenHash: faker.UUIDDigit(),
+	})
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	for i := int64(1); i <= maxViews; i++ {
+		viewed, err := strg.NoteLink().AddView(context.Background(), link.ID)
+		require.NoError(t, err)
+		require.Equal(t, i, viewed.ViewCount)
+	}
+
+	_, err = strg.NoteLink().AddView(context.Background(), link.ID)
+	require.ErrorIs(t, err, repo.ErrLinkUnavailable)
+
+	got, err := strg.NoteLink().GetByHash(context.Background(), link.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, maxViews, got.ViewCount)
+	require.Nil(t, got.Password)
+
+	revoked, err := strg.NoteLink().Revoke(context.Background(), link.ID, note.UserID)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+
+	_, err = strg.NoteLink().Revoke(context.Background(), link.ID, note.UserID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	purgeNote(note.ID, note.UserID, t)
+}
@@ -0,0 +1,39 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteRevisions(t *testing.T) {
+	note := createNote(t)
+
+	revisions, err := strg.NoteRevision().GetAll(context.Background(), note.ID, note.UserID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, note.Title, revisions[0].Title)
+
+	note.Title = faker.Word()
+	updated, err := strg.Note().Update(context.Background(), note)
+	require.NoError(t, err)
+	require.NotNil(t, updated.UpdatedAt)
+
+	revisions, err = strg.NoteRevision().GetAll(context.Background(), note.ID, note.UserID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, note.Title, revisions[0].Title)
+	require.Equal(t, &note.UserID, revisions[0].AuthorID)
+
+	revision, err := strg.NoteRevision().Get(context.Background(), revisions[1].ID, note.ID, note.UserID)
+	require.NoError(t, err)
+	require.Equal(t, revisions[1].Title, revision.Title)
+
+	_, err = strg.NoteRevision().Get(context.Background(), revisions[1].ID, note.ID, note.UserID+1)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	purgeNote(note.ID, note.UserID, t)
+}
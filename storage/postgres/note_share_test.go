@@ -0,0 +1,74 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteShares(t *testing.T) {
+	note := createNote(t)
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	share, err := strg.NoteShare().Create(context.Background(), note.UserID, &repo.NoteShare{
+		NoteID:     note.ID,
+		UserID:     user.ID,
+		Permission: repo.PermissionViewer,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Email, share.Email)
+
+	_, err = strg.NoteShare().Create(context.Background(), note.UserID, share)
+	require.ErrorIs(t, err, repo.ErrShareExists)
+
+	_, err = strg.NoteShare().Create(context.Background(), user.ID, share)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	_, err = strg.Note().Get(context.Background(), note.ID, user.ID)
+	require.NoError(t, err)
+
+	_, err = strg.Note().Update(context.Background(), &repo.Note{
+		ID:      note.ID,
+		UserID:  user.ID,
+		Title:   "shared",
+		Version: note.Version,
+	})
+	require.ErrorIs(t, err, repo.ErrReadOnly)
+
+	share.Permission = repo.PermissionEditor
+	_, err = strg.NoteShare().Update(context.Background(), note.UserID, share)
+	require.NoError(t, err)
+
+	updated, err := strg.Note().Update(context.Background(), &repo.Note{
+		ID:      note.ID,
+		UserID:  user.ID,
+		Title:   "shared",
+		Version: note.Version,
+	})
+	require.NoError(t, err)
+	require.Equal(t, note.UserID, updated.UserID)
+
+	notes, err := strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:         10,
+		Page:          1,
+		UserID:        user.ID,
+		IncludeShared: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, notes.Notes, 1)
+
+	shares, err := strg.NoteShare().GetAll(context.Background(), note.ID, note.UserID)
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+
+	require.NoError(t, strg.NoteShare().Delete(context.Background(), note.ID, note.UserID, user.ID))
+	require.ErrorIs(t, strg.NoteShare().Delete(context.Background(), note.ID, note.UserID, user.ID), repo.ErrNotFound)
+
+	_, err = strg.Note().Get(context.Background(), note.ID, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	purgeNote(note.ID, note.UserID, t)
+}
@@ -1,6 +1,7 @@
 package postgres_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/burxondv/note-template/storage/repo"
@@ -9,7 +10,7 @@ import (
 )
 
 func createNote(t *testing.T) *repo.Note {
-	note, err := strg.Note().Create(&repo.Note{
+	note, err := strg.Note().Create(context.Background(), &repo.Note{
 		UserID:      3,
 		Title:       faker.Name(),
 		Description: faker.Sentence(),
@@ -20,8 +21,13 @@ func createNote(t *testing.T) *repo.Note {
 	return note
 }
 
-func deleteNote(id int64, t *testing.T) {
-	err := strg.Note().Delete(id)
+func deleteNote(id, userID int64, t *testing.T) {
+	err := strg.Note().Delete(context.Background(), id, userID)
+	require.NoError(t, err)
+}
+
+func purgeNote(id, userID int64, t *testing.T) {
+	err := strg.Note().Purge(context.Background(), id, userID)
 	require.NoError(t, err)
 }
 
@@ -32,21 +38,37 @@ func TestCreateNote(t *testing.T) {
 func TestGetNote(t *testing.T) {
 	c := createNote(t)
 
-	note, err := strg.Note().Get(c.ID)
+	note, err := strg.Note().Get(context.Background(), c.ID, c.UserID)
 	require.NoError(t, err)
 	require.NotEmpty(t, note)
 }
 
+func TestGetNoteOtherOwner(t *testing.T) {
+	c := createNote(t)
+
+	_, err := strg.Note().Get(context.Background(), c.ID, c.UserID+1)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	err = strg.Note().Delete(context.Background(), c.ID, c.UserID+1)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	deleteNote(c.ID, c.UserID, t)
+}
+
 func TestGetAllNote(t *testing.T) {
 	note := createNote(t)
 
-	notes, err := strg.Note().GetAll(&repo.GetAllNotesParams{
-		Limit: 10,
-		Page:  1,
+	notes, err := strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:  10,
+		Page:   1,
+		UserID: note.UserID,
 	})
 
 	require.NoError(t, err)
 	require.GreaterOrEqual(t, len(notes.Notes), 1)
+	for _, n := range notes.Notes {
+		require.Equal(t, note.UserID, n.UserID)
+	}
 
 	deleteUser(note.ID, t)
 }
@@ -54,13 +76,186 @@ func TestGetAllNote(t *testing.T) {
 func TestUpdateNote(t *testing.T) {
 	note := createNote(t)
 
-	note.UserID = 1
 	note.Title = faker.Name()
 	note.Description = faker.Sentence()
+
+	updated, err := strg.Note().Update(context.Background(), note)
+	require.NoError(t, err)
+	require.Equal(t, note.Version+1, updated.Version)
+
+	_, err = strg.Note().Update(context.Background(), note)
+	require.ErrorIs(t, err, repo.ErrVersionMismatch)
+
+	note.ID = -1
+	_, err = strg.Note().Update(context.Background(), note)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	purgeNote(updated.ID, updated.UserID, t)
 }
 
 func TestDeleteNote(t *testing.T) {
-	user := createNote(t)
+	note := createNote(t)
+
+	deleteNote(note.ID, note.UserID, t)
+}
+
+func TestRestoreNote(t *testing.T) {
+	c := createNote(t)
+
+	deleteNote(c.ID, c.UserID, t)
+
+	_, err := strg.Note().Get(context.Background(), c.ID, c.UserID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	trash, err := strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:   10,
+		Page:    1,
+		UserID:  c.UserID,
+		Deleted: true,
+	})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(trash.Notes), 1)
+	for _, n := range trash.Notes {
+		require.NotNil(t, n.DeletedAt)
+	}
+
+	note, err := strg.Note().Restore(context.Background(), c.ID, c.UserID)
+	require.NoError(t, err)
+	require.Nil(t, note.DeletedAt)
+
+	_, err = strg.Note().Restore(context.Background(), c.ID, c.UserID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	purgeNote(c.ID, c.UserID, t)
+}
+
+func TestPurgeNote(t *testing.T) {
+	c := createNote(t)
+
+	purgeNote(c.ID, c.UserID, t)
+
+	_, err := strg.Note().Restore(context.Background(), c.ID, c.UserID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
+
+func TestPatchNote(t *testing.T) {
+	c := createNote(t)
 
-	deleteNote(user.ID, t)
+	note, err := strg.Note().Patch(context.Background(), &repo.NotePatch{
+		ID:      c.ID,
+		UserID:  c.UserID,
+		Version: c.Version,
+		Fields:  map[string]interface{}{"title": "patched"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "patched", note.Title)
+	require.Equal(t, c.Description, note.Description)
+
+	revisions, err := strg.NoteRevision().GetAll(context.Background(), c.ID, c.UserID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+
+	_, err = strg.Note().Patch(context.Background(), &repo.NotePatch{
+		ID:      c.ID,
+		UserID:  c.UserID,
+		Version: note.Version,
+		Fields:  map[string]interface{}{"user_id": 1},
+	})
+	require.Error(t, err)
+
+	purgeNote(c.ID, c.UserID, t)
+}
+
+func TestGetAllNoteFullText(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	create := func(title, description string) *repo.Note {
+		note, err := strg.Note().Create(context.Background(), &repo.Note{
+			UserID:      user.ID,
+			Title:       title,
+			Description: description,
+		})
+		require.NoError(t, err)
+		return note
+	}
+
+	inDescription := create("groceries", "buy oat milk and bread")
+	inTitle := create("milk prices", "compare the shops")
+	create("milk shake", "with chocolate")
+
+	notes, err := strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:    10,
+		Page:     1,
+		UserID:   user.ID,
+		Search:   "milk -chocolate",
+		FullText: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), notes.Count)
+	require.Equal(t, inTitle.ID, notes.Notes[0].ID)
+	require.Equal(t, inDescription.ID, notes.Notes[1].ID)
+	require.Equal(t, "<mark>milk</mark> prices", notes.Notes[0].Highlight.Title)
+	require.Contains(t, notes.Notes[1].Highlight.Description, "<mark>milk</mark>")
+
+	notes, err = strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:    10,
+		Page:     1,
+		UserID:   user.ID,
+		Search:   `"milk shake"`,
+		FullText: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, notes.Notes, 1)
+}
+
+func TestGetAllNoteHostileSearch(t *testing.T) {
+	note := createNote(t)
+
+	searches := []string{
+		"'",
+		"%",
+		`\`,
+		"' OR '1'='1",
+		"'; DROP TABLE notes; --",
+		"$1",
+		"\x00",
+	}
+
+	for _, search := range searches {
+		for _, fullText := range []bool{false, true} {
+			notes, err := strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+				Limit:    10,
+				Page:     1,
+				UserID:   note.UserID,
+				Search:   search,
+				FullText: fullText,
+			})
+			if search == "\x00" {
+				// postgres rejects the zero byte in text, it still must not reach the SQL
+				continue
+			}
+			require.NoError(t, err, search)
+			require.Zero(t, notes.Count, search)
+		}
+	}
+
+	_, err := strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:     10,
+		Page:      1,
+		UserID:    note.UserID,
+		SortOrder: "desc; DROP TABLE notes",
+	})
+	require.ErrorIs(t, err, repo.ErrInvalidSort)
+
+	purgeNote(note.ID, note.UserID, t)
+}
+
+func TestCreateNoteUnknownUser(t *testing.T) {
+	_, err := strg.Note().Create(context.Background(), &repo.Note{
+		UserID:      -1,
+		Title:       faker.Name(),
+		Description: faker.Sentence(),
+	})
+	require.ErrorIs(t, err, repo.ErrInvalidReference)
 }
@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type notebookRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewNotebook(db sqlx.ExtContext, queryTimeout time.Duration) repo.NotebookStorageI {
+	return &notebookRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (nr *notebookRepo) Create(ctx context.Context, notebook *repo.Notebook) (*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO notebooks(
+			user_id,
+			parent_id,
+			name
+		) VALUES($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := nr.db.QueryRowxContext(ctx, query, notebook.UserID, notebook.ParentID, notebook.Name).Scan(
+		&notebook.ID,
+		&notebook.CreatedAt,
+	)
+	if err != nil {
+		return nil, notebookError(err)
+	}
+
+	return notebook, nil
+}
+
+func (nr *notebookRepo) Get(ctx context.Context, id, userID int64) (*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			nb.id,
+			nb.user_id,
+			nb.parent_id,
+			nb.name,
+			(SELECT count(*) FROM notes n WHERE n.notebook_id=nb.id AND n.deleted_at IS NULL),
+			nb.created_at,
+			nb.updated_at
+		FROM notebooks nb
+		WHERE nb.id=$1 AND nb.user_id=$2
+	`
+
+	return scanNotebook(nr.db.QueryRowxContext(ctx, query, id, userID))
+}
+
+func (nr *notebookRepo) GetAll(ctx context.Context, userID int64) ([]*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			nb.id,
+			nb.user_id,
+			nb.parent_id,
+			nb.name,
+			(SELECT count(*) FROM notes n WHERE n.notebook_id=nb.id AND n.deleted_at IS NULL),
+			nb.created_at,
+			nb.updated_at
+		FROM notebooks nb
+		WHERE nb.user_id=$1
+		ORDER BY nb.name, nb.id
+	`
+
+	rows, err := nr.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := make([]*repo.Notebook, 0)
+	for rows.Next() {
+		notebook, err := scanNotebook(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, notebook)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+func (nr *notebookRepo) Rename(ctx context.Context, id, userID int64, name string) (*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notebooks SET
+			name=$1,
+			updated_at=CURRENT_TIMESTAMP
+		WHERE id=$2 AND user_id=$3
+	`
+
+	return nr.update(ctx, query, id, userID, name, id, userID)
+}
+
+// Move relies on the notebooks_check_parent trigger to reject foreign parents and cycles
+func (nr *notebookRepo) Move(ctx context.Context, id, userID int64, parentID *int64) (*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notebooks SET
+			parent_id=$1,
+			updated_at=CURRENT_TIMESTAMP
+		WHERE id=$2 AND user_id=$3
+	`
+
+	return nr.update(ctx, query, id, userID, parentID, id, userID)
+}
+
+func (nr *notebookRepo) update(ctx context.Context, query string, id, userID int64, args ...interface{}) (*repo.Notebook, error) {
+	result, err := nr.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, notebookError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return nil, repo.ErrNotFound
+	}
+
+	return nr.Get(ctx, id, userID)
+}
+
+func (nr *notebookRepo) Delete(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := "DELETE FROM notebooks WHERE id=$1 AND user_id=$2"
+
+	result, err := nr.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanNotebook(row scanner) (*repo.Notebook, error) {
+	var result repo.Notebook
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.ParentID,
+		&result.Name,
+		&result.NoteCount,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &result, nil
+}
+
+// notebookError translates the errors raised by the notebooks_check_parent trigger
+func notebookError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return translateError(err)
+	}
+
+	switch pqErr.Code {
+	case foreignKeyViolation:
+		return repo.ErrNotebookNotFound
+	case checkViolation:
+		return repo.ErrNotebookCycle
+	}
+
+	return translateError(err)
+}
@@ -0,0 +1,87 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createNotebook(userID int64, parentID *int64, t *testing.T) *repo.Notebook {
+	notebook, err := strg.Notebook().Create(context.Background(), &repo.Notebook{
+		UserID:   userID,
+		ParentID: parentID,
+		Name:     faker.Word(),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, notebook)
+
+	return notebook
+}
+
+func TestMoveNotebookCycle(t *testing.T) {
+	root := createNotebook(3, nil, t)
+	child := createNotebook(root.UserID, &root.ID, t)
+
+	_, err := strg.Notebook().Move(context.Background(), root.ID, root.UserID, &child.ID)
+	require.ErrorIs(t, err, repo.ErrNotebookCycle)
+
+	_, err = strg.Notebook().Move(context.Background(), root.ID, root.UserID, &root.ID)
+	require.ErrorIs(t, err, repo.ErrNotebookCycle)
+
+	_, err = strg.Notebook().Create(context.Background(), &repo.Notebook{UserID: root.UserID + 1, ParentID: &root.ID, Name: faker.Word()})
+	require.ErrorIs(t, err, repo.ErrNotebookNotFound)
+
+	moved, err := strg.Notebook().Move(context.Background(), child.ID, child.UserID, nil)
+	require.NoError(t, err)
+	require.Nil(t, moved.ParentID)
+
+	require.NoError(t, strg.Notebook().Delete(context.Background(), root.ID, root.UserID))
+	require.NoError(t, strg.Notebook().Delete(context.Background(), child.ID, child.UserID))
+}
+
+func TestNotebookNotes(t *testing.T) {
+	note := createNote(t)
+	root := createNotebook(note.UserID, nil, t)
+	child := createNotebook(note.UserID, &root.ID, t)
+
+	moved, err := strg.Note().Move(context.Background(), note.ID, note.UserID, &child.ID)
+	require.NoError(t, err)
+	require.Equal(t, child.ID, *moved.NotebookID)
+
+	notes, err := strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:      10,
+		Page:       1,
+		UserID:     note.UserID,
+		NotebookID: &root.ID,
+	})
+	require.NoError(t, err)
+	require.Empty(t, notes.Notes)
+
+	notes, err = strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:              10,
+		Page:               1,
+		UserID:             note.UserID,
+		NotebookID:         &root.ID,
+		IncludeDescendants: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, notes.Notes, 1)
+
+	notebook, err := strg.Notebook().Get(context.Background(), child.ID, child.UserID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), notebook.NoteCount)
+
+	require.NoError(t, strg.Notebook().Delete(context.Background(), root.ID, root.UserID))
+
+	_, err = strg.Notebook().Get(context.Background(), child.ID, child.UserID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	got, err := strg.Note().Get(context.Background(), note.ID, note.UserID)
+	require.NoError(t, err)
+	require.Nil(t, got.NotebookID)
+
+	purgeNote(note.ID, note.UserID, t)
+}
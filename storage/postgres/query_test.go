@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"math"
+	"testing"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectQuery(t *testing.T) {
+	q := newSelectQuery("notes", "id", "title")
+	userID := q.arg(int64(7))
+	q.where("user_id=" + userID)
+	q.where("title ILIKE " + q.arg(likePattern("'; DROP TABLE notes; --")))
+	require.NoError(t, q.sort("title", "asc", noteSortColumns))
+	q.sortBy("id DESC")
+	q.page(3, 10)
+
+	query, args := q.sql()
+	require.Equal(t, "SELECT id, title FROM notes WHERE (user_id=$1) AND (title ILIKE $2) ORDER BY title ASC, id DESC LIMIT $3 OFFSET $4", query)
+	require.Equal(t, []interface{}{int64(7), `%'; DROP TABLE notes; --%`, int32(10), int64(20)}, args)
+
+	query, args = q.countSQL()
+	require.Equal(t, "SELECT count(*) FROM notes WHERE (user_id=$1) AND (title ILIKE $2)", query)
+	require.Len(t, args, 2)
+}
+
+func TestSelectQueryPage(t *testing.T) {
+	q := newSelectQuery("notes", "id")
+	q.page(0, 10)
+	_, args := q.sql()
+	require.Equal(t, []interface{}{int32(10), int64(0)}, args)
+
+	q = newSelectQuery("notes", "id")
+	q.page(math.MaxInt32, 100)
+	_, args = q.sql()
+	require.Equal(t, []interface{}{int32(100), int64(math.MaxInt32-1) * 100}, args)
+}
+
+func TestSelectQuerySort(t *testing.T) {
+	tests := []struct {
+		name      string
+		column    string
+		direction string
+		err       error
+	}{
+		{"default direction", "created_at", "", nil},
+		{"upper case", "title", "DESC", nil},
+		{"unknown column", "password", "asc", repo.ErrInvalidSort},
+		{"injected column", "title; DROP TABLE notes", "asc", repo.ErrInvalidSort},
+		{"injected direction", "title", "asc, (SELECT 1)", repo.ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newSelectQuery("notes", "id")
+			require.ErrorIs(t, q.sort(tt.column, tt.direction, noteSortColumns), tt.err)
+		})
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	require.Equal(t, `%100\%%`, likePattern("100%"))
+	require.Equal(t, `%a\_b%`, likePattern("a_b"))
+	require.Equal(t, `%c:\\dir%`, likePattern(`c:\dir`))
+}
@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type tagRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewTag(db sqlx.ExtContext, queryTimeout time.Duration) repo.TagStorageI {
+	return &tagRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (tr *tagRepo) Create(ctx context.Context, tag *repo.Tag) (*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO tags(
+			user_id,
+			name
+		) VALUES($1, $2)
+		RETURNING id, created_at
+	`
+
+	err := tr.db.QueryRowxContext(ctx, query, tag.UserID, tag.Name).Scan(
+		&tag.ID,
+		&tag.CreatedAt,
+	)
+	if isUniqueViolation(err) {
+		return nil, repo.ErrTagExists
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return tag, nil
+}
+
+func (tr *tagRepo) Get(ctx context.Context, id, userID int64) (*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			t.id,
+			t.user_id,
+			t.name,
+			(SELECT count(*) FROM note_tags nt WHERE nt.tag_id=t.id),
+			t.created_at
+		FROM tags t
+		WHERE t.id=$1 AND t.user_id=$2
+	`
+
+	return scanTag(tr.db.QueryRowxContext(ctx, query, id, userID))
+}
+
+func (tr *tagRepo) GetAll(ctx context.Context, userID int64) ([]*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			t.id,
+			t.user_id,
+			t.name,
+			(SELECT count(*) FROM note_tags nt WHERE nt.tag_id=t.id),
+			t.created_at
+		FROM tags t
+		WHERE t.user_id=$1
+		ORDER BY t.name
+	`
+
+	rows, err := tr.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := make([]*repo.Tag, 0)
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, tag)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+func (tr *tagRepo) Rename(ctx context.Context, id, userID int64, name string) (*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE tags SET
+			name=$1
+		WHERE id=$2 AND user_id=$3
+		RETURNING
+			id,
+			user_id,
+			name,
+			(SELECT count(*) FROM note_tags nt WHERE nt.tag_id=tags.id),
+			created_at
+	`
+
+	tag, err := scanTag(tr.db.QueryRowxContext(ctx, query, name, id, userID))
+	if isUniqueViolation(err) {
+		return nil, repo.ErrTagExists
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return tag, nil
+}
+
+// Merge relinks the notes and drops the source tag in one statement,
+// notes that already had both tags keep a single link to the target
+func (tr *tagRepo) Merge(ctx context.Context, sourceID, targetID, userID int64) (*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		WITH source AS (
+			SELECT id FROM tags WHERE id=$1 AND user_id=$3
+		), target AS (
+			SELECT id FROM tags WHERE id=$2 AND user_id=$3
+		), moved AS (
+			INSERT INTO note_tags(note_id, tag_id)
+			SELECT nt.note_id, target.id
+			FROM note_tags nt, source, target
+			WHERE nt.tag_id=source.id
+			ON CONFLICT DO NOTHING
+		)
+		DELETE FROM tags
+		WHERE id IN (SELECT id FROM source) AND EXISTS (SELECT 1 FROM target)
+	`
+
+	result, err := tr.db.ExecContext(ctx, query, sourceID, targetID, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return nil, repo.ErrNotFound
+	}
+
+	return tr.Get(ctx, targetID, userID)
+}
+
+func (tr *tagRepo) Delete(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := "DELETE FROM tags WHERE id=$1 AND user_id=$2"
+
+	result, err := tr.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (tr *tagRepo) Attach(ctx context.Context, noteID, tagID, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		WITH pair AS (
+			SELECT n.id AS note_id, t.id AS tag_id
+			FROM notes n, tags t
+			WHERE n.id=$1 AND n.user_id=$3 AND n.deleted_at IS NULL
+				AND t.id=$2 AND t.user_id=$3
+		), attached AS (
+			INSERT INTO note_tags(note_id, tag_id)
+			SELECT note_id, tag_id FROM pair
+			ON CONFLICT DO NOTHING
+		)
+		SELECT count(*) FROM pair
+	`
+
+	var count int
+	err := tr.db.QueryRowxContext(ctx, query, noteID, tagID, userID).Scan(&count)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if count == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (tr *tagRepo) Detach(ctx context.Context, noteID, tagID, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM note_tags nt
+		USING notes n
+		WHERE nt.note_id=$1 AND nt.tag_id=$2
+			AND n.id=nt.note_id AND n.user_id=$3 AND n.deleted_at IS NULL
+	`
+
+	result, err := tr.db.ExecContext(ctx, query, noteID, tagID, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanTag(row scanner) (*repo.Tag, error) {
+	var result repo.Tag
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Name,
+		&result.NoteCount,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,92 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createTag(userID int64, t *testing.T) *repo.Tag {
+	tag, err := strg.Tag().Create(context.Background(), &repo.Tag{
+		UserID: userID,
+		Name:   faker.Word() + faker.Word(),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, tag)
+
+	return tag
+}
+
+func TestCreateTagDuplicate(t *testing.T) {
+	tag := createTag(3, t)
+
+	_, err := strg.Tag().Create(context.Background(), &repo.Tag{UserID: tag.UserID, Name: tag.Name})
+	require.ErrorIs(t, err, repo.ErrTagExists)
+
+	require.NoError(t, strg.Tag().Delete(context.Background(), tag.ID, tag.UserID))
+}
+
+func TestAttachTag(t *testing.T) {
+	note := createNote(t)
+	tag := createTag(note.UserID, t)
+
+	require.NoError(t, strg.Tag().Attach(context.Background(), note.ID, tag.ID, note.UserID))
+	require.NoError(t, strg.Tag().Attach(context.Background(), note.ID, tag.ID, note.UserID))
+
+	err := strg.Tag().Attach(context.Background(), note.ID, tag.ID, note.UserID+1)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	got, err := strg.Note().Get(context.Background(), note.ID, note.UserID)
+	require.NoError(t, err)
+	require.Len(t, got.Tags, 1)
+	require.Equal(t, tag.Name, got.Tags[0].Name)
+
+	notes, err := strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:   10,
+		Page:    1,
+		UserID:  note.UserID,
+		TagsAll: []int64{tag.ID},
+	})
+	require.NoError(t, err)
+	require.Len(t, notes.Notes, 1)
+	require.Equal(t, note.ID, notes.Notes[0].ID)
+
+	notes, err = strg.Note().GetAll(context.Background(), &repo.GetAllNotesParams{
+		Limit:       10,
+		Page:        1,
+		UserID:      note.UserID,
+		TagsExclude: []int64{tag.ID},
+	})
+	require.NoError(t, err)
+	for _, n := range notes.Notes {
+		require.NotEqual(t, note.ID, n.ID)
+	}
+
+	require.NoError(t, strg.Tag().Detach(context.Background(), note.ID, tag.ID, note.UserID))
+	require.ErrorIs(t, strg.Tag().Detach(context.Background(), note.ID, tag.ID, note.UserID), repo.ErrNotFound)
+
+	require.NoError(t, strg.Tag().Delete(context.Background(), tag.ID, tag.UserID))
+	purgeNote(note.ID, note.UserID, t)
+}
+
+func TestMergeTag(t *testing.T) {
+	note := createNote(t)
+	source := createTag(note.UserID, t)
+	target := createTag(note.UserID, t)
+
+	require.NoError(t, strg.Tag().Attach(context.Background(), note.ID, source.ID, note.UserID))
+	require.NoError(t, strg.Tag().Attach(context.Background(), note.ID, target.ID, note.UserID))
+
+	merged, err := strg.Tag().Merge(context.Background(), source.ID, target.ID, note.UserID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), merged.NoteCount)
+
+	_, err = strg.Tag().Get(context.Background(), source.ID, note.UserID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	require.NoError(t, strg.Tag().Delete(context.Background(), target.ID, target.UserID))
+	purgeNote(note.ID, note.UserID, t)
+}
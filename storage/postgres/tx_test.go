@@ -0,0 +1,111 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func newTxUser() *repo.User {
+	return &repo.User{
+		FirstName: faker.FirstName(),
+		LastName:  faker.LastName(),
+		Email:     faker.Email(),
+	}
+}
+
+func TestWithTxCommit(t *testing.T) {
+	var (
+		user *repo.User
+		note *repo.Note
+	)
+	err := strg.WithTx(context.Background(), func(tx storage.StorageI) error {
+		var err error
+		user, err = tx.User().Create(context.Background(), newTxUser())
+		if err != nil {
+			return err
+		}
+
+		note, err = tx.Note().Create(context.Background(), &repo.Note{
+			UserID: user.ID,
+			Title:  "Welcome",
+		})
+		return err
+	})
+	require.NoError(t, err)
+
+	stored, err := strg.Note().Get(context.Background(), note.ID, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Welcome", stored.Title)
+
+	purgeNote(note.ID, user.ID, t)
+	deleteUser(user.ID, t)
+}
+
+func TestWithTxRollback(t *testing.T) {
+	errStop := errors.New("stop")
+
+	var user *repo.User
+	err := strg.WithTx(context.Background(), func(tx storage.StorageI) error {
+		var err error
+		user, err = tx.User().Create(context.Background(), newTxUser())
+		if err != nil {
+			return err
+		}
+
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+
+	_, err = strg.User().Get(context.Background(), user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
+
+func TestWithTxNested(t *testing.T) {
+	errStop := errors.New("stop")
+
+	var user *repo.User
+	err := strg.WithTx(context.Background(), func(tx storage.StorageI) error {
+		err := tx.WithTx(context.Background(), func(inner storage.StorageI) error {
+			var err error
+			user, err = inner.User().Create(context.Background(), newTxUser())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+
+	_, err = strg.User().Get(context.Background(), user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
+
+func TestWithTxRetry(t *testing.T) {
+	attempts := 0
+	err := strg.WithTx(context.Background(), func(tx storage.StorageI) error {
+		attempts++
+		if attempts == 1 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+
+	attempts = 0
+	err = strg.WithTx(context.Background(), func(tx storage.StorageI) error {
+		attempts++
+		return &pq.Error{Code: "40001"}
+	})
+	require.Error(t, err)
+	require.Equal(t, 3, attempts)
+}
@@ -1,57 +1,78 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/burxondv/note-template/storage/repo"
 	"github.com/jmoiron/sqlx"
 )
 
 type userRepo struct {
-	db *sqlx.DB
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
 }
 
-func NewUser(db *sqlx.DB) repo.UserStorageI {
+func NewUser(db sqlx.ExtContext, queryTimeout time.Duration) repo.UserStorageI {
 	return &userRepo{
-		db: db,
+		db:           db,
+		queryTimeout: queryTimeout,
 	}
 }
 
-func (ur *userRepo) Create(user *repo.User) (*repo.User, error) {
+// Create stores an empty phone number as NULL, only the numbers that are set must be unique
+func (ur *userRepo) Create(ctx context.Context, user *repo.User) (*repo.User, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	if user.Role == "" {
+		user.Role = repo.RoleUser
+	}
+
 	query := `
 		INSERT INTO users(
 			first_name,
 			last_name,
 			phone_number,
 			email,
-			image_url
-		) VALUES($1, $2, $3, $4, $5)
-		RETURNING id, created_at
+			password,
+			image_url,
+			role,
+			is_active
+		) VALUES($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id, version, created_at
 	`
 
-	row := ur.db.QueryRow(
+	row := ur.db.QueryRowxContext(ctx,
 		query,
 		user.FirstName,
 		user.LastName,
 		user.PhoneNumber,
 		user.Email,
+		user.Password,
 		user.ImageURL,
+		user.Role,
+		user.IsActive,
 	)
 
 	err := row.Scan(
 		&user.ID,
+		&user.Version,
 		&user.CreatedAt,
 	)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return user, nil
 }
 
-func (ur *userRepo) Get(id int64) (*repo.User, error) {
-	var result repo.User
+func (ur *userRepo) Get(ctx context.Context, id int64) (*repo.User, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
 
 	query := `
 		SELECT 
@@ -60,7 +81,11 @@ func (ur *userRepo) Get(id int64) (*repo.User, error) {
 			last_name, 
 			phone_number, 
 			email, 
+			password,
 			image_url,
+			role,
+			is_active,
+			version,
 			created_at,
 			updated_at,
 			deleted_at
@@ -68,108 +93,132 @@ func (ur *userRepo) Get(id int64) (*repo.User, error) {
         WHERE id=$1
 	`
 
-	row := ur.db.QueryRow(query, id)
-	err := row.Scan(
-		&result.ID,
-		&result.FirstName,
-		&result.LastName,
-		&result.PhoneNumber,
-		&result.Email,
-		&result.ImageURL,
-		&result.CreatedAt,
-		&result.UpdatedAt,
-		&result.DeletedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
+	return scanUser(ur.db.QueryRowxContext(ctx, query, id))
+}
 
-	return &result, nil
+func (ur *userRepo) GetByEmail(ctx context.Context, email string) (*repo.User, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT 
+			id,
+			first_name, 
+			last_name, 
+			phone_number, 
+			email, 
+			password,
+			image_url,
+			role,
+			is_active,
+			version,
+			created_at,
+			updated_at,
+			deleted_at
+        FROM users
+        WHERE email=$1
+	`
+
+	return scanUser(ur.db.QueryRowxContext(ctx, query, email))
 }
 
-func (ur *userRepo) GetAll(params *repo.GetAllUsersParams) (*repo.GetAllUsersResult, error) {
+var userSortColumns = map[string]string{
+	"created_at": "created_at",
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+}
+
+func (ur *userRepo) GetAll(ctx context.Context, params *repo.GetAllUsersParams) (*repo.GetAllUsersResult, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
 	result := repo.GetAllUsersResult{
 		Users: make([]*repo.User, 0),
 	}
 
-	offset := (params.Page - 1) * params.Limit
-
-	limit := fmt.Sprintf(" LIMIT %d OFFSET %d", params.Limit, offset)
+	q := newSelectQuery(
+		"users",
+		"id",
+		"first_name",
+		"last_name",
+		"phone_number",
+		"email",
+		"password",
+		"image_url",
+		"role",
+		"is_active",
+		"version",
+		"created_at",
+		"updated_at",
+		"deleted_at",
+	)
 
-	filter := ""
 	if params.Search != "" {
-		str := "%" + params.Search + "%"
-		filter += fmt.Sprintf(`
-			WHERE first_name ilike '%s' OR last_name ilike '%s' OR email ilike '%s'`,
-			str, str, str,
-		)
+		search := q.arg(likePattern(params.Search))
+		q.where(fmt.Sprintf("first_name ILIKE %[1]s OR last_name ILIKE %[1]s OR email ILIKE %[1]s", search))
 	}
 
-	query := `
-		SELECT
-			id,
-			first_name,
-            last_name,
-			phone_number,
-            email,
-            image_url,
-			created_at,
-            updated_at,
-            deleted_at
-		FROM users
-		` + filter + `
-		ORDER BY created_at DESC
-		` + limit
+	sortBy := params.SortBy
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
 
-	rows, err := ur.db.Query(query)
+	err := q.sort(sortBy, params.SortOrder, userSortColumns)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
+	}
+	q.sortBy("id DESC")
+	q.page(params.Page, params.Limit)
+
+	query, args := q.sql()
+	rows, err := ur.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
 	}
 
 	defer rows.Close()
 	for rows.Next() {
-		var u repo.User
-
-		err := rows.Scan(
-			&u.ID,
-			&u.FirstName,
-			&u.LastName,
-			&u.PhoneNumber,
-			&u.Email,
-			&u.ImageURL,
-			&u.CreatedAt,
-			&u.UpdatedAt,
-			&u.DeletedAt,
-		)
+		u, err := scanUser(rows)
 		if err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 
-		result.Users = append(result.Users, &u)
+		result.Users = append(result.Users, u)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, translateError(err)
 	}
 
-	queryCount := `SELECT count(1) FROM users ` + filter
-	err = ur.db.QueryRow(queryCount).Scan(&result.Count)
+	queryCount, args := q.countSQL()
+	err = ur.db.QueryRowxContext(ctx, queryCount, args...).Scan(&result.Count)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return &result, nil
 }
 
-func (ur *userRepo) Update(user *repo.User) (*repo.User, error) {
+// Update changes the user only when the version still matches the stored one
+func (ur *userRepo) Update(ctx context.Context, user *repo.User) (*repo.User, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
 	query := `
 		UPDATE users SET
 			first_name=$1,
 			last_name=$2,
-            phone_number=$3,
+            phone_number=NULLIF($3, ''),
 			email=$4,
-            image_url=$5
-		WHERE id=$6
-		RETURNING id, first_name, last_name, phone_number, email, image_url, created_at, updated_at, deleted_at
+            image_url=NULLIF($5, ''),
+			version=version+1,
+			updated_at=CURRENT_TIMESTAMP
+		WHERE id=$6 AND version=$7
+		RETURNING id, first_name, last_name, phone_number, email, password, image_url, role, is_active, version, created_at, updated_at, deleted_at
 	`
 
-	row := ur.db.QueryRow(
+	row := ur.db.QueryRowxContext(ctx,
 		query,
 		user.FirstName,
 		user.LastName,
@@ -177,43 +226,199 @@ func (ur *userRepo) Update(user *repo.User) (*repo.User, error) {
 		user.Email,
 		user.ImageURL,
 		user.ID,
+		user.Version,
 	)
 
-	var result repo.User
-	err := row.Scan(
-		&result.ID,
-		&result.FirstName,
-		&result.LastName,
-		&result.PhoneNumber,
-		&result.Email,
-		&result.ImageURL,
-		&result.CreatedAt,
-		&result.UpdatedAt,
-		&result.DeletedAt,
-	)
+	result, err := scanUser(row)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, ur.updateError(ctx, user.ID)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result, nil
+}
+
+var userPatchColumns = map[string]patchColumn{
+	"first_name":   {},
+	"last_name":    {},
+	"phone_number": {nullable: true},
+	"email":        {},
+	"image_url":    {nullable: true},
+}
+
+// Patch updates only the given columns, the statement is built from the patch
+func (ur *userRepo) Patch(ctx context.Context, patch *repo.UserPatch) (*repo.User, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	set, args, err := setClause(patch.Fields, userPatchColumns)
 	if err != nil {
 		return nil, err
 	}
+	if set != "" {
+		set += ", "
+	}
 
-	return user, nil
+	query := fmt.Sprintf(`
+		UPDATE users SET
+			%sversion=version+1,
+			updated_at=CURRENT_TIMESTAMP
+		WHERE id=$%d AND version=$%d
+		RETURNING id, first_name, last_name, phone_number, email, password, image_url, role, is_active, version, created_at, updated_at, deleted_at
+	`, set, len(args)+1, len(args)+2)
+
+	args = append(args, patch.ID, patch.Version)
+
+	result, err := scanUser(ur.db.QueryRowxContext(ctx, query, args...))
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, ur.updateError(ctx, patch.ID)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result, nil
 }
 
-func (ur *userRepo) Delete(id int64) error {
+// updateError tells apart a missing user from a stale version after an update matched no rows
+func (ur *userRepo) updateError(ctx context.Context, id int64) error {
+	query := "SELECT EXISTS(SELECT 1 FROM users WHERE id=$1)"
+
+	var exists bool
+	err := ur.db.QueryRowxContext(ctx, query, id).Scan(&exists)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if exists {
+		return repo.ErrVersionMismatch
+	}
+
+	return repo.ErrNotFound
+}
+
+func (ur *userRepo) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
 	query := "DELETE FROM users WHERE id=$1"
 
-	result, err := ur.db.Exec(query, id)
+	result, err := ur.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (ur *userRepo) Activate(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := "UPDATE users SET is_active=true, version=version+1 WHERE id=$1"
+
+	result, err := ur.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (ur *userRepo) UpdatePassword(ctx context.Context, id int64, password string) error {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := "UPDATE users SET password=$1, version=version+1, updated_at=CURRENT_TIMESTAMP WHERE id=$2"
+
+	result, err := ur.db.ExecContext(ctx, query, password, id)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (ur *userRepo) UpdateRole(ctx context.Context, id int64, role string) error {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := "UPDATE users SET role=$1, version=version+1, updated_at=CURRENT_TIMESTAMP WHERE id=$2"
+
+	result, err := ur.db.ExecContext(ctx, query, role, id)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 
 	rowsCount, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 
 	if rowsCount == 0 {
-		return sql.ErrNoRows
+		return repo.ErrNotFound
 	}
 
 	return nil
 }
+
+func scanUser(row scanner) (*repo.User, error) {
+	var (
+		result      repo.User
+		phoneNumber sql.NullString
+		imageURL    sql.NullString
+	)
+
+	err := row.Scan(
+		&result.ID,
+		&result.FirstName,
+		&result.LastName,
+		&phoneNumber,
+		&result.Email,
+		&result.Password,
+		&imageURL,
+		&result.Role,
+		&result.IsActive,
+		&result.Version,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.DeletedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	result.PhoneNumber = phoneNumber.String
+	result.ImageURL = imageURL.String
+
+	return &result, nil
+}
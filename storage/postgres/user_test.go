@@ -1,6 +1,7 @@
 package postgres_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/burxondv/note-template/storage/repo"
@@ -11,7 +12,7 @@ import (
 // har gal run qilganda argumentlarini ozgartirish kere, chunki hamasi unique
 
 func createUser(t *testing.T) *repo.User {
-	user, err := strg.User().Create(&repo.User{
+	user, err := strg.User().Create(context.Background(), &repo.User{
 		FirstName:   faker.Name(),
 		LastName:    faker.Name(),
 		PhoneNumber: faker.Phonenumber(),
@@ -25,7 +26,7 @@ func createUser(t *testing.T) *repo.User {
 }
 
 func deleteUser(id int64, t *testing.T) {
-	err := strg.User().Delete(id)
+	err := strg.User().Delete(context.Background(), id)
 	require.NoError(t, err)
 }
 
@@ -36,7 +37,7 @@ func TestCreateUser(t *testing.T) {
 func TestGetUser(t *testing.T) {
 	c := createUser(t)
 
-	user, err := strg.User().Get(c.ID)
+	user, err := strg.User().Get(context.Background(), c.ID)
 	require.NoError(t, err)
 	require.NotEmpty(t, user)
 }
@@ -44,7 +45,7 @@ func TestGetUser(t *testing.T) {
 func TestGetAllUser(t *testing.T) {
 	user := createUser(t)
 
-	users, err := strg.User().GetAll(&repo.GetAllUsersParams{
+	users, err := strg.User().GetAll(context.Background(), &repo.GetAllUsersParams{
 		Limit: 10,
 		Page:  1,
 	})
@@ -63,6 +64,15 @@ func TestUpdateUser(t *testing.T) {
 	user.PhoneNumber = faker.Phonenumber()
 	user.Email = faker.Email()
 	user.ImageURL = faker.URL()
+
+	updated, err := strg.User().Update(context.Background(), user)
+	require.NoError(t, err)
+	require.Equal(t, user.Version+1, updated.Version)
+
+	_, err = strg.User().Update(context.Background(), user)
+	require.ErrorIs(t, err, repo.ErrVersionMismatch)
+
+	deleteUser(user.ID, t)
 }
 
 func TestDeleteUser(t *testing.T) {
@@ -70,3 +80,124 @@ func TestDeleteUser(t *testing.T) {
 
 	deleteUser(user.ID, t)
 }
+
+func TestGetUserByEmail(t *testing.T) {
+	c := createUser(t)
+
+	user, err := strg.User().GetByEmail(context.Background(), c.Email)
+	require.NoError(t, err)
+	require.Equal(t, c.ID, user.ID)
+
+	deleteUser(c.ID, t)
+}
+
+func TestActivateUser(t *testing.T) {
+	c := createUser(t)
+	require.False(t, c.IsActive)
+
+	err := strg.User().Activate(context.Background(), c.ID)
+	require.NoError(t, err)
+
+	user, err := strg.User().Get(context.Background(), c.ID)
+	require.NoError(t, err)
+	require.True(t, user.IsActive)
+
+	deleteUser(c.ID, t)
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	c := createUser(t)
+
+	password := faker.Password()
+	err := strg.User().UpdatePassword(context.Background(), c.ID, password)
+	require.NoError(t, err)
+
+	user, err := strg.User().Get(context.Background(), c.ID)
+	require.NoError(t, err)
+	require.Equal(t, password, user.Password)
+
+	deleteUser(c.ID, t)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	c := createUser(t)
+	require.Equal(t, repo.RoleUser, c.Role)
+
+	err := strg.User().UpdateRole(context.Background(), c.ID, repo.RoleAdmin)
+	require.NoError(t, err)
+
+	user, err := strg.User().Get(context.Background(), c.ID)
+	require.NoError(t, err)
+	require.Equal(t, repo.RoleAdmin, user.Role)
+
+	deleteUser(c.ID, t)
+}
+
+func TestPatchUser(t *testing.T) {
+	c := createUser(t)
+
+	user, err := strg.User().Patch(context.Background(), &repo.UserPatch{
+		ID:      c.ID,
+		Version: c.Version,
+		Fields: map[string]interface{}{
+			"first_name": "Patched",
+			"image_url":  nil,
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Patched", user.FirstName)
+	require.Equal(t, c.LastName, user.LastName)
+	require.Equal(t, c.Email, user.Email)
+	require.Empty(t, user.ImageURL)
+
+	_, err = strg.User().Patch(context.Background(), &repo.UserPatch{
+		ID:      c.ID,
+		Version: c.Version,
+		Fields:  map[string]interface{}{"last_name": "Stale"},
+	})
+	require.ErrorIs(t, err, repo.ErrVersionMismatch)
+
+	_, err = strg.User().Patch(context.Background(), &repo.UserPatch{
+		ID:      c.ID,
+		Version: user.Version,
+		Fields:  map[string]interface{}{"role": repo.RoleAdmin},
+	})
+	require.Error(t, err)
+
+	deleteUser(c.ID, t)
+}
+
+func TestGetAllUserHostileSearch(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	for _, search := range []string{"'", "%", "_", "' OR '1'='1", "'; DROP TABLE users; --"} {
+		users, err := strg.User().GetAll(context.Background(), &repo.GetAllUsersParams{
+			Limit:  10,
+			Page:   1,
+			Search: search,
+		})
+		require.NoError(t, err, search)
+		require.Zero(t, users.Count, search)
+	}
+
+	_, err := strg.User().GetAll(context.Background(), &repo.GetAllUsersParams{
+		Limit:  10,
+		Page:   1,
+		SortBy: "password",
+	})
+	require.ErrorIs(t, err, repo.ErrInvalidSort)
+}
+
+func TestCreateUserDuplicateEmail(t *testing.T) {
+	user := createUser(t)
+
+	_, err := strg.User().Create(context.Background(), &repo.User{
+		FirstName: faker.Name(),
+		LastName:  faker.Name(),
+		Email:     user.Email,
+	})
+	require.ErrorIs(t, err, repo.ErrConflict)
+
+	deleteUser(user.ID, t)
+}
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type ApiToken struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type ApiTokenStorageI interface {
+	Create(ctx context.Context, t *ApiToken) (*ApiToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*ApiToken, error)
+	GetAll(ctx context.Context, userID int64) ([]*ApiToken, error)
+	UpdateLastUsed(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id, userID int64) error
+}
@@ -0,0 +1,52 @@
+package repo
+
+import "errors"
+
+// The kinds of errors every storage returns, the specific errors below match
+// their kind with errors.Is so callers can handle either
+var (
+	// ErrNotFound is returned when the row does not exist or is not visible to the user
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when the change clashes with an existing row
+	ErrConflict = errors.New("conflict")
+	// ErrInvalidReference is returned when the row refers to another one that does not exist
+	ErrInvalidReference = errors.New("invalid reference")
+)
+
+var (
+	// ErrVersionMismatch is returned by conditional updates when the row
+	// exists but was changed since the caller read it
+	ErrVersionMismatch = errors.New("resource was modified, reload it and try again")
+	ErrTagExists       = kindError(ErrConflict, "tag with this name already exists")
+	ErrNotebookCycle   = kindError(ErrConflict, "notebook can not be moved into itself or its descendants")
+	// ErrNotebookNotFound is returned when a notebook referenced by another row does not exist
+	ErrNotebookNotFound = kindError(ErrInvalidReference, "notebook not found")
+	ErrShareExists      = kindError(ErrConflict, "note is already shared with this user")
+	// ErrReadOnly is returned when the user may read the note but not change it
+	ErrReadOnly = errors.New("note is shared with you read-only")
+	// ErrLinkUnavailable is returned for a share link that was revoked, expired or used up its views
+	ErrLinkUnavailable = errors.New("share link is no longer available")
+	// ErrInvalidSort is returned by list queries for a sort column or direction that is not allowed
+	ErrInvalidSort = errors.New("invalid sort column or direction")
+)
+
+// specificError has its own message and matches its kind
+type specificError struct {
+	kind    error
+	message string
+}
+
+func kindError(kind error, message string) error {
+	return &specificError{
+		kind:    kind,
+		message: message,
+	}
+}
+
+func (e *specificError) Error() string {
+	return e.message
+}
+
+func (e *specificError) Unwrap() error {
+	return e.kind
+}
@@ -1,23 +1,65 @@
 package repo
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Note struct {
 	ID          int64
 	UserID      int64
+	NotebookID  *int64
 	Title       string
 	Description string
+	Version     int64
+	Tags        []*Tag
 	CreatedAt   time.Time
 	UpdatedAt   *time.Time
 	DeletedAt   *time.Time
+	// Highlight is filled only by a full-text search
+	Highlight *NoteHighlight
+}
+
+// NoteHighlight holds HTML-escaped fragments of the note with the search matches wrapped
+// in <mark> tags, the tags are the only markup in them
+type NoteHighlight struct {
+	Title       string
+	Description string
+}
+
+// NotePatch holds the columns to change and their new values
+type NotePatch struct {
+	ID      int64
+	UserID  int64
+	Version int64
+	Fields  map[string]interface{}
 }
 
 type GetAllNotesParams struct {
-	Limit      int32
-	Page       int32
-	Search     string
-	UserID     int64
-	SortByData string
+	Limit  int32
+	Page   int32
+	Search string
+	UserID int64
+	// SortBy is created_at, updated_at or title, SortOrder is asc or desc.
+	// A full-text search without SortBy is ordered by relevance
+	SortBy    string
+	SortOrder string
+	// FullText matches Search against the title and description with the web search
+	// syntax, results are ordered by relevance and carry highlighted fragments
+	FullText bool
+	// TagsAny keeps notes with at least one of the tags, TagsAll notes with every tag
+	// and TagsExclude drops notes with any of the tags
+	TagsAny     []int64
+	TagsAll     []int64
+	TagsExclude []int64
+	// NotebookID keeps the notes of one notebook, with IncludeDescendants
+	// the notes of its nested notebooks are kept as well
+	NotebookID         *int64
+	IncludeDescendants bool
+	// IncludeShared also lists the notes shared with the user
+	IncludeShared bool
+	// Deleted lists the notes in the trash instead of the active ones
+	Deleted bool
 }
 
 type GetAllNotesResult struct {
@@ -25,10 +67,23 @@ type GetAllNotesResult struct {
 	Count int32
 }
 
+// NoteStorageI takes the id of the acting user in userID, Get and the revisions
+// accept the owner and users the note is shared with, Update and Patch accept the
+// owner and editors, the rest of the methods are for the owner only
 type NoteStorageI interface {
-	Create(u *Note) (*Note, error)
-	Get(id int64) (*Note, error)
-	GetAll(params *GetAllNotesParams) (*GetAllNotesResult, error)
-	Update(u *Note) (*Note, error)
-	Delete(id int64) error
+	Create(ctx context.Context, u *Note) (*Note, error)
+	Get(ctx context.Context, id, userID int64) (*Note, error)
+	GetAll(ctx context.Context, params *GetAllNotesParams) (*GetAllNotesResult, error)
+	// Update changes the note only when u.Version matches the stored version,
+	// otherwise it returns ErrVersionMismatch, u.UserID is the acting user.
+	// ErrReadOnly is returned when the note is only shared with a viewer
+	Update(ctx context.Context, u *Note) (*Note, error)
+	// Patch changes only the columns listed in p.Fields, the version is checked like in Update
+	Patch(ctx context.Context, p *NotePatch) (*Note, error)
+	Delete(ctx context.Context, id, userID int64) error
+	Restore(ctx context.Context, id, userID int64) (*Note, error)
+	Purge(ctx context.Context, id, userID int64) error
+	// Move places the note in a notebook of the user or at the top level when notebookID is nil,
+	// it returns ErrNotebookNotFound when the notebook is not the user's
+	Move(ctx context.Context, id, userID int64, notebookID *int64) (*Note, error)
 }
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// NoteLink is a public read-only link to a note, only the hash of its token is stored
+type NoteLink struct {
+	ID        int64
+	NoteID    int64
+	UserID    int64
+	TokenHash string
+	// Password is the bcrypt hash of the password, nil when the link is open
+	Password  *string
+	ExpiresAt *time.Time
+	MaxViews  *int64
+	ViewCount int64
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type NoteLinkStorageI interface {
+	// Create returns ErrNotFound when the note is not owned by l.UserID
+	Create(ctx context.Context, l *NoteLink) (*NoteLink, error)
+	GetByHash(ctx context.Context, tokenHash string) (*NoteLink, error)
+	GetAll(ctx context.Context, userID int64) ([]*NoteLink, error)
+	// AddView counts a view only while the link is not revoked, expired or
+	// out of views, otherwise it returns ErrLinkUnavailable
+	AddView(ctx context.Context, id int64) (*NoteLink, error)
+	Revoke(ctx context.Context, id, userID int64) (*NoteLink, error)
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// NoteRevision is a snapshot of a note, one is written on every create and update.
+// AuthorID is nil once the author's account is deleted, the revision is kept
+type NoteRevision struct {
+	ID          int64
+	NoteID      int64
+	AuthorID    *int64
+	Title       string
+	Description string
+	CreatedAt   time.Time
+}
+
+type NoteRevisionStorageI interface {
+	Get(ctx context.Context, id, noteID, userID int64) (*NoteRevision, error)
+	GetAll(ctx context.Context, noteID, userID int64) ([]*NoteRevision, error)
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	PermissionViewer = "viewer"
+	PermissionEditor = "editor"
+)
+
+// NoteShare grants a user other than the owner access to a note,
+// viewers can read the note and its revisions, editors can also change it
+type NoteShare struct {
+	NoteID     int64
+	UserID     int64
+	Email      string
+	Permission string
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+}
+
+// NoteShareStorageI is used by the owner of the note, every method
+// returns ErrNotFound when the note is not owned by ownerID
+type NoteShareStorageI interface {
+	// Create returns ErrShareExists when the note is already shared with the user
+	Create(ctx context.Context, ownerID int64, s *NoteShare) (*NoteShare, error)
+	GetAll(ctx context.Context, noteID, ownerID int64) ([]*NoteShare, error)
+	Update(ctx context.Context, ownerID int64, s *NoteShare) (*NoteShare, error)
+	Delete(ctx context.Context, noteID, ownerID, userID int64) error
+}
@@ -0,0 +1,31 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type Notebook struct {
+	ID       int64
+	UserID   int64
+	ParentID *int64
+	Name     string
+	// NoteCount counts the notes placed directly in the notebook
+	NoteCount int64
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+type NotebookStorageI interface {
+	// Create returns ErrNotebookNotFound when the parent is not a notebook of the user
+	Create(ctx context.Context, n *Notebook) (*Notebook, error)
+	Get(ctx context.Context, id, userID int64) (*Notebook, error)
+	// GetAll returns every notebook of the user as a flat list ordered by name
+	GetAll(ctx context.Context, userID int64) ([]*Notebook, error)
+	Rename(ctx context.Context, id, userID int64, name string) (*Notebook, error)
+	// Move places the notebook under parentID or at the top level when it is nil,
+	// it returns ErrNotebookCycle when the parent is the notebook itself or one of its descendants
+	Move(ctx context.Context, id, userID int64, parentID *int64) (*Notebook, error)
+	// Delete removes the notebook with its descendants, their notes are moved to the top level
+	Delete(ctx context.Context, id, userID int64) error
+}
@@ -0,0 +1,27 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+type Tag struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	NoteCount int64
+	CreatedAt time.Time
+}
+
+type TagStorageI interface {
+	Create(ctx context.Context, t *Tag) (*Tag, error)
+	Get(ctx context.Context, id, userID int64) (*Tag, error)
+	GetAll(ctx context.Context, userID int64) ([]*Tag, error)
+	Rename(ctx context.Context, id, userID int64, name string) (*Tag, error)
+	// Merge moves the notes of the source tag to the target tag and deletes the source
+	Merge(ctx context.Context, sourceID, targetID, userID int64) (*Tag, error)
+	Delete(ctx context.Context, id, userID int64) error
+	// Attach is idempotent, it returns ErrNotFound when the note or the tag is not the user's
+	Attach(ctx context.Context, noteID, tagID, userID int64) error
+	Detach(ctx context.Context, noteID, tagID, userID int64) error
+}
@@ -1,6 +1,14 @@
 package repo
 
-import "time"
+import (
+	"context"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 type User struct {
 	ID          int64
@@ -8,16 +16,31 @@ type User struct {
 	LastName    string
 	PhoneNumber string
 	Email       string
+	Password    string
 	ImageURL    string
+	Role        string
+	IsActive    bool
+	Version     int64
 	CreatedAt   time.Time
 	UpdatedAt   *time.Time
 	DeletedAt   *time.Time
 }
 
+// UserPatch holds the columns to change and their new values,
+// a nil value sets a nullable column to NULL
+type UserPatch struct {
+	ID      int64
+	Version int64
+	Fields  map[string]interface{}
+}
+
 type GetAllUsersParams struct {
 	Limit  int32
 	Page   int32
 	Search string
+	// SortBy is created_at, first_name, last_name or email, SortOrder is asc or desc
+	SortBy    string
+	SortOrder string
 }
 
 type GetAllUsersResult struct {
@@ -26,9 +49,17 @@ type GetAllUsersResult struct {
 }
 
 type UserStorageI interface {
-	Create(u *User) (*User, error)
-	Get(id int64) (*User, error)
-	GetAll(params *GetAllUsersParams) (*GetAllUsersResult, error)
-	Update(u *User) (*User, error)
-	Delete(id int64) error
+	Create(ctx context.Context, u *User) (*User, error)
+	Get(ctx context.Context, id int64) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetAll(ctx context.Context, params *GetAllUsersParams) (*GetAllUsersResult, error)
+	// Update changes the user only when u.Version matches the stored version,
+	// otherwise it returns ErrVersionMismatch
+	Update(ctx context.Context, u *User) (*User, error)
+	// Patch changes only the columns listed in p.Fields, the version is checked like in Update
+	Patch(ctx context.Context, p *UserPatch) (*User, error)
+	Delete(ctx context.Context, id int64) error
+	Activate(ctx context.Context, id int64) error
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	UpdateRole(ctx context.Context, id int64, role string) error
 }
@@ -0,0 +1,159 @@
+// Package schema applies the migrations embedded in the binary and checks the version
+// of the database schema against them. The version is kept in schema_migrations the way
+// the migrate CLI keeps it, so a database migrated with the CLI carries on where it stopped
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+var (
+	// ErrVersionMismatch is returned by Check when the schema is behind or ahead of the code
+	ErrVersionMismatch = errors.New("schema version does not match")
+	// ErrDirty is returned by Check when a migration failed halfway, the schema has to be
+	// fixed by hand and the version set with Force
+	ErrDirty = errors.New("schema is dirty")
+)
+
+// Status is the version of the schema, Version is zero when no migration was applied
+// and Expected is the version of the last embedded migration
+type Status struct {
+	Version  uint
+	Dirty    bool
+	Expected uint
+}
+
+type Migrator struct {
+	m        *migrate.Migrate
+	expected uint
+	release  func() error
+}
+
+// New reads the migrations at the root of migrations and applies them through driver,
+// databaseName is the name the migrate CLI knows the driver by. release is called by
+// Close to free what the driver holds, it may be nil
+func New(migrations fs.FS, databaseName string, driver database.Driver, release func() error) (*Migrator, error) {
+	src, err := iofs.New(migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := lastVersion(src)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, databaseName, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		m:        m,
+		expected: expected,
+		release:  release,
+	}, nil
+}
+
+func lastVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("no migrations found: %w", err)
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		version = next
+	}
+}
+
+// Up applies the next n migrations or all the pending ones when n is zero
+func (m *Migrator) Up(n int) error {
+	var err error
+	if n == 0 {
+		err = m.m.Up()
+	} else {
+		err = m.m.Steps(n)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
+
+// Down reverts the last n migrations
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid number of migrations %d", n)
+	}
+
+	return m.m.Steps(-n)
+}
+
+// Force sets the version and clears the dirty flag without running any migration,
+// -1 marks the schema as not migrated at all
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+func (m *Migrator) Status() (Status, error) {
+	status := Status{Expected: m.expected}
+
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	status.Version = version
+	status.Dirty = dirty
+
+	return status, nil
+}
+
+// Check returns an error unless the schema is at the version of the last migration
+func (m *Migrator) Check() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty {
+		return fmt.Errorf("%w at version %d", ErrDirty, status.Version)
+	}
+	if status.Version != status.Expected {
+		return fmt.Errorf("%w: the database is at version %d, the code expects %d",
+			ErrVersionMismatch, status.Version, status.Expected)
+	}
+
+	return nil
+}
+
+// Close frees what the driver holds, the database itself is left open. A nil
+// Migrator, as the memory storage has, is fine to close
+func (m *Migrator) Close() error {
+	if m == nil || m.release == nil {
+		return nil
+	}
+
+	return m.release()
+}
@@ -0,0 +1,91 @@
+package schema_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/burxondv/note-template/migrations"
+	"github.com/burxondv/note-template/storage/schema"
+	"github.com/burxondv/note-template/storage/sqlite"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = fstest.MapFS{
+	"1_create_items.up.sql":     {Data: []byte("CREATE TABLE items (id INTEGER PRIMARY KEY)")},
+	"1_create_items.down.sql":   {Data: []byte("DROP TABLE items")},
+	"2_add_items_name.up.sql":   {Data: []byte("ALTER TABLE items ADD COLUMN name TEXT")},
+	"2_add_items_name.down.sql": {Data: []byte("ALTER TABLE items DROP COLUMN name")},
+	"3_broken.up.sql":           {Data: []byte("CREATE TABLE")},
+	"3_broken.down.sql":         {Data: []byte("")},
+}
+
+func newMigrator(t *testing.T) *schema.Migrator {
+	db, err := sqlite.Open(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := migratesqlite.WithInstance(db.DB, &migratesqlite.Config{})
+	require.NoError(t, err)
+
+	migrator, err := schema.New(testMigrations, "sqlite3", driver, nil)
+	require.NoError(t, err)
+
+	return migrator
+}
+
+func TestMigrator(t *testing.T) {
+	migrator := newMigrator(t)
+
+	status, err := migrator.Status()
+	require.NoError(t, err)
+	require.Equal(t, schema.Status{Version: 0, Expected: 3}, status)
+	require.ErrorIs(t, migrator.Check(), schema.ErrVersionMismatch)
+
+	require.NoError(t, migrator.Up(2))
+	status, err = migrator.Status()
+	require.NoError(t, err)
+	require.Equal(t, uint(2), status.Version)
+	require.ErrorIs(t, migrator.Check(), schema.ErrVersionMismatch)
+
+	require.NoError(t, migrator.Down(1))
+	status, err = migrator.Status()
+	require.NoError(t, err)
+	require.Equal(t, uint(1), status.Version)
+
+	require.Error(t, migrator.Down(0))
+
+	// the broken migration leaves the schema dirty until the version is forced
+	require.Error(t, migrator.Up(0))
+	status, err = migrator.Status()
+	require.NoError(t, err)
+	require.Equal(t, schema.Status{Version: 3, Dirty: true, Expected: 3}, status)
+	require.ErrorIs(t, migrator.Check(), schema.ErrDirty)
+
+	require.NoError(t, migrator.Force(3))
+	require.NoError(t, migrator.Check())
+	require.NoError(t, migrator.Up(0))
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	src, err := iofs.New(migrations.FS, ".")
+	require.NoError(t, err)
+
+	// every up migration has a down migration
+	version, err := src.First()
+	require.NoError(t, err)
+	for {
+		_, _, err = src.ReadUp(version)
+		require.NoError(t, err, "up migration %d", version)
+		_, _, err = src.ReadDown(version)
+		require.NoError(t, err, "down migration %d", version)
+
+		next, err := src.Next(version)
+		if err != nil {
+			break
+		}
+		version = next
+	}
+	require.Equal(t, uint(13), version)
+}
@@ -0,0 +1,21 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/burxondv/note-template/storage/sqlite"
+	"github.com/burxondv/note-template/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, strg)
+}
+
+func TestMigrateTwice(t *testing.T) {
+	migrator, err := sqlite.NewMigrator(db.DB)
+	require.NoError(t, err)
+
+	require.NoError(t, migrator.Up(0))
+	require.NoError(t, migrator.Check())
+}
@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/mattn/go-sqlite3"
+)
+
+// dbError matches one of the kinds of storage/repo errors and keeps the driver error it came from
+type dbError struct {
+	kind error
+	err  error
+}
+
+func (e *dbError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *dbError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *dbError) Unwrap() error {
+	return e.err
+}
+
+// translateError turns a missing row and constraint violations into the errors of
+// storage/repo and a query interrupted by its context into context.DeadlineExceeded
+// like the postgres repos do, everything else is returned as it is
+func translateError(err error) error {
+	var sqliteErr sqlite3.Error
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repo.ErrNotFound), errors.Is(err, repo.ErrConflict), errors.Is(err, repo.ErrInvalidReference):
+		return err
+	case errors.Is(err, sql.ErrNoRows):
+		return &dbError{kind: repo.ErrNotFound, err: err}
+	case isUniqueViolation(err):
+		return &dbError{kind: repo.ErrConflict, err: err}
+	case errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		return &dbError{kind: repo.ErrInvalidReference, err: err}
+	case errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrInterrupt:
+		return &dbError{kind: context.DeadlineExceeded, err: err}
+	}
+
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/burxondv/note-template/storage/fulltext"
+	"github.com/mattn/go-sqlite3"
+)
+
+// registerFunctions adds the functions the queries use in place of the postgres operators:
+//
+//	ilike(text, pattern)                  text ILIKE pattern
+//	fts_match(title, description, search) search_vector @@ websearch_to_tsquery(search)
+//	fts_rank(title, description, search)  ts_rank(search_vector, websearch_to_tsquery(search))
+//	fts_highlight(text, search)           ts_headline(text, websearch_to_tsquery(search))
+func registerFunctions(conn *sqlite3.SQLiteConn) error {
+	functions := map[string]interface{}{
+		"ilike":         ilike,
+		"fts_match":     ftsMatch,
+		"fts_rank":      ftsRank,
+		"fts_highlight": ftsHighlight,
+	}
+
+	for name, impl := range functions {
+		err := conn.RegisterFunc(name, impl, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ilike matches text against a LIKE pattern ignoring the case of any letter, not only
+// of ASCII ones like the LIKE of SQLite does. % matches any run of characters, _ a
+// single character and a backslash makes the next character match literally
+func ilike(text, pattern string) bool {
+	return matchLike([]rune(strings.ToLower(text)), []rune(strings.ToLower(pattern)))
+}
+
+func matchLike(text, pattern []rune) bool {
+	var (
+		ti, pi int
+		// the last % seen and the position in text it is matched up to,
+		// on a mismatch the % takes one more character and the match goes on
+		percent, percentEnd = -1, 0
+	)
+
+	for ti < len(text) {
+		if pi < len(pattern) {
+			switch p := pattern[pi]; {
+			case p == '%':
+				percent, percentEnd = pi, ti
+				pi++
+				continue
+			case p == '_':
+				ti++
+				pi++
+				continue
+			case p == '\\' && pi+1 < len(pattern):
+				if pattern[pi+1] == text[ti] {
+					ti++
+					pi += 2
+					continue
+				}
+			case p == text[ti]:
+				ti++
+				pi++
+				continue
+			}
+		}
+
+		if percent < 0 {
+			return false
+		}
+
+		percentEnd++
+		ti = percentEnd
+		pi = percent + 1
+	}
+
+	for pi < len(pattern) && pattern[pi] == '%' {
+		pi++
+	}
+
+	return pi == len(pattern)
+}
+
+func ftsMatch(title, description, search string) bool {
+	_, ok := fulltext.Parse(search).Rank(title, description)
+	return ok
+}
+
+func ftsRank(title, description, search string) float64 {
+	rank, _ := fulltext.Parse(search).Rank(title, description)
+	return rank
+}
+
+func ftsHighlight(text, search string) string {
+	return fulltext.Parse(search).Highlight(text)
+}
@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIlike(t *testing.T) {
+	tests := []struct {
+		text    string
+		pattern string
+		match   bool
+	}{
+		{"Weekly Report", "%report%", true},
+		{"Weekly Report", "weekly%", true},
+		{"Weekly Report", "%weekly", false},
+		{"ÜBER uns", "%über%", true},
+		{"Привет мир", "%ПРИВЕТ%", true},
+		{"abc", "a_c", true},
+		{"ac", "a_c", false},
+		{"100%", likePattern("100%"), true},
+		{"1000", likePattern("100%"), false},
+		{"a_b", likePattern("a_b"), true},
+		{"axb", likePattern("a_b"), false},
+		{`c:\dir`, likePattern(`c:\dir`), true},
+		{"", "%", true},
+		{"", "_", false},
+		{"aaa", "%a%a%a%", true},
+		{"aa", "%a%a%a%", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.match, ilike(tt.text, tt.pattern), "%q ILIKE %q", tt.text, tt.pattern)
+	}
+}
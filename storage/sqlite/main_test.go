@@ -0,0 +1,48 @@
+package sqlite_test
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/sqlite"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	db   *sqlx.DB
+	strg storage.StorageI
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "note-sqlite")
+	if err != nil {
+		log.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	db, err = sqlite.Open(filepath.Join(dir, "note.db"))
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+
+	migrator, err := sqlite.NewMigrator(db.DB)
+	if err != nil {
+		log.Fatalf("failed to read migrations: %v", err)
+	}
+
+	err = migrator.Up(0)
+	if err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
+	strg = storage.NewStorageSqlite(db, 5*time.Second)
+
+	code := m.Run()
+
+	db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/burxondv/note-template/storage/schema"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite3"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// NewMigrator applies the migrations embedded from the migrations directory of this
+// package to db, they share the connections of db so Close has nothing to free
+func NewMigrator(db *sql.DB) (*schema.Migrator, error) {
+	files, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.New(files, "sqlite3", driver, nil)
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS note_links;
+DROP TABLE IF EXISTS note_shares;
+DROP TABLE IF EXISTS note_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS note_revisions;
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS notebooks;
+DROP TABLE IF EXISTS users;
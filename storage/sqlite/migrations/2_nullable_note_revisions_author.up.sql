@@ -0,0 +1,16 @@
+CREATE TABLE note_revisions_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    title VARCHAR(60) NOT NULL,
+    description VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+INSERT INTO note_revisions_new(id, note_id, author_id, title, description, created_at)
+SELECT id, note_id, author_id, title, description, created_at FROM note_revisions;
+
+DROP TABLE note_revisions;
+ALTER TABLE note_revisions_new RENAME TO note_revisions;
+
+CREATE INDEX IF NOT EXISTS note_revisions_note_id_idx ON note_revisions(note_id);
@@ -0,0 +1,548 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type noteRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewNote(db sqlx.ExtContext, queryTimeout time.Duration) repo.NoteStorageI {
+	return &noteRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// Create stores the note and its first revision in one transaction
+func (ur *noteRepo) Create(ctx context.Context, note *repo.Note) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO notes(
+			user_id,
+			title,
+			description
+		) VALUES (?1, ?2, ?3)
+		RETURNING id, version, created_at
+	`
+
+	err := inTx(ctx, ur.db, func(tx sqlx.ExtContext) error {
+		row := tx.QueryRowxContext(ctx,
+			query,
+			note.UserID,
+			note.Title,
+			note.Description,
+		)
+
+		err := row.Scan(
+			&note.ID,
+			&note.Version,
+			&note.CreatedAt,
+		)
+		if err != nil {
+			return translateError(err)
+		}
+
+		return insertRevision(ctx, tx, note, note.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	note.Tags = make([]*repo.Tag, 0)
+
+	return note, nil
+}
+
+func (ur *noteRepo) Get(ctx context.Context, id, userID int64) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT 
+		    id,
+            user_id,
+			notebook_id,
+            title,
+			description,
+			version,
+            created_at,
+			updated_at,
+			deleted_at
+		FROM notes
+        WHERE id=?1 AND deleted_at IS NULL AND ` + canReadNote("notes", 2) + `
+	`
+
+	return ur.scanNoteWithTags(ctx, ur.db.QueryRowxContext(ctx, query, id, userID))
+}
+
+var noteSortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
+}
+
+func (ur *noteRepo) GetAll(ctx context.Context, params *repo.GetAllNotesParams) (*repo.GetAllNotesResult, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	result := repo.GetAllNotesResult{
+		Notes: make([]*repo.Note, 0),
+	}
+
+	q := newSelectQuery(
+		"notes",
+		"id",
+		"user_id",
+		"notebook_id",
+		"title",
+		"description",
+		"version",
+		"created_at",
+		"updated_at",
+		"deleted_at",
+	)
+
+	userID := q.arg(params.UserID)
+	if params.IncludeShared && !params.Deleted {
+		q.where(fmt.Sprintf("user_id=%[1]s OR id IN (SELECT note_id FROM note_shares WHERE user_id=%[1]s)", userID))
+	} else {
+		q.where("user_id=" + userID)
+	}
+	if params.Deleted {
+		q.where("deleted_at IS NOT NULL")
+	} else {
+		q.where("deleted_at IS NULL")
+	}
+
+	var search string
+	if params.Search != "" && params.FullText {
+		search = q.arg(params.Search)
+		q.where("fts_match(title, description, " + search + ")")
+		q.column("fts_highlight(title, " + search + ")")
+		q.column("fts_highlight(description, " + search + ")")
+	} else if params.Search != "" {
+		q.where("ilike(title, " + q.arg(likePattern(params.Search)) + ")")
+	}
+
+	if params.NotebookID != nil && params.IncludeDescendants {
+		q.where(`notebook_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM notebooks WHERE id=` + q.arg(*params.NotebookID) + `
+				UNION ALL
+				SELECT nb.id FROM notebooks nb JOIN tree t ON nb.parent_id=t.id
+			)
+			SELECT id FROM tree
+		)`)
+	} else if params.NotebookID != nil {
+		q.where("notebook_id=" + q.arg(*params.NotebookID))
+	}
+	if len(params.TagsAny) > 0 {
+		q.where("id IN (SELECT note_id FROM note_tags WHERE tag_id IN " + q.argList(params.TagsAny) + ")")
+	}
+	if len(params.TagsAll) > 0 {
+		q.where(`id IN (
+			SELECT note_id FROM note_tags WHERE tag_id IN ` + q.argList(params.TagsAll) + `
+			GROUP BY note_id HAVING count(DISTINCT tag_id) = ` + q.arg(len(params.TagsAll)) + `
+		)`)
+	}
+	if len(params.TagsExclude) > 0 {
+		q.where("id NOT IN (SELECT note_id FROM note_tags WHERE tag_id IN " + q.argList(params.TagsExclude) + ")")
+	}
+
+	if search != "" && params.SortBy == "" {
+		q.sortBy("fts_rank(title, description, " + search + ") DESC")
+		q.sortBy("created_at DESC")
+	} else {
+		sortBy := params.SortBy
+		if sortBy == "" {
+			sortBy = "created_at"
+		}
+
+		err := q.sort(sortBy, params.SortOrder, noteSortColumns)
+		if err != nil {
+			return nil, translateError(err)
+		}
+	}
+	q.sortBy("id DESC")
+	q.page(params.Page, params.Limit)
+
+	query, args := q.sql()
+	rows, err := ur.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var u *repo.Note
+		if search != "" {
+			u, err = scanNoteHighlight(rows)
+		} else {
+			u, err = scanNote(rows)
+		}
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result.Notes = append(result.Notes, u)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	err = ur.loadTags(ctx, result.Notes...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	queryCount, args := q.countSQL()
+	err = ur.db.QueryRowxContext(ctx, queryCount, args...).Scan(&result.Count)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &result, nil
+}
+
+// Update changes the note and records the new content as a revision in the same transaction,
+// the version is checked in the WHERE clause so a concurrent update can not be overwritten.
+// The revision is authored by the acting user who is the owner or an editor
+func (ur *noteRepo) Update(ctx context.Context, note *repo.Note) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notes SET
+			title=?1,
+			description=?2,
+			version=version+1,
+			updated_at=` + now + `
+		WHERE id=?3 AND version=?5 AND deleted_at IS NULL AND ` + canWriteNote("notes", 4) + `
+		RETURNING id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at
+	`
+
+	return ur.updateWithRevision(ctx, note.ID, note.UserID, query,
+		note.Title,
+		note.Description,
+		note.ID,
+		note.UserID,
+		note.Version,
+	)
+}
+
+var notePatchColumns = map[string]patchColumn{
+	"title":       {},
+	"description": {},
+}
+
+// Patch updates only the given columns and records the result as a revision like Update does
+func (ur *noteRepo) Patch(ctx context.Context, patch *repo.NotePatch) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	set, args, err := setClause(patch.Fields, notePatchColumns)
+	if err != nil {
+		return nil, err
+	}
+	if set != "" {
+		set += ", "
+	}
+
+	n := len(args)
+	query := fmt.Sprintf(`
+		UPDATE notes SET
+			%sversion=version+1,
+			updated_at=%s
+		WHERE id=?%d AND version=?%d AND deleted_at IS NULL AND %s
+		RETURNING id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at
+	`, set, now, n+1, n+3, canWriteNote("notes", n+2))
+
+	args = append(args, patch.ID, patch.UserID, patch.Version)
+
+	return ur.updateWithRevision(ctx, patch.ID, patch.UserID, query, args...)
+}
+
+// updateWithRevision runs the update of Update or Patch and records the updated note
+// as a revision by userID in the same transaction
+func (ur *noteRepo) updateWithRevision(ctx context.Context, id, userID int64, query string, args ...interface{}) (*repo.Note, error) {
+	var result *repo.Note
+	err := inTx(ctx, ur.db, func(tx sqlx.ExtContext) error {
+		var err error
+		result, err = scanNote(tx.QueryRowxContext(ctx, query, args...))
+		if err != nil {
+			return err
+		}
+
+		return insertRevision(ctx, tx, result, userID)
+	})
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, ur.updateError(ctx, id, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = ur.loadTags(ctx, result)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result, nil
+}
+
+// updateError tells apart a missing note, a read-only share and a stale version
+// after an update matched no rows
+func (ur *noteRepo) updateError(ctx context.Context, id, userID int64) error {
+	query := `
+		SELECT
+			` + canReadNote("notes", 2) + `,
+			` + canWriteNote("notes", 2) + `
+		FROM notes
+		WHERE id=?1 AND deleted_at IS NULL
+	`
+
+	var readable, writable bool
+	err := ur.db.QueryRowxContext(ctx, query, id, userID).Scan(&readable, &writable)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if !readable {
+		return repo.ErrNotFound
+	}
+	if !writable {
+		return repo.ErrReadOnly
+	}
+
+	return repo.ErrVersionMismatch
+}
+
+// Delete moves the note to the trash, it can be restored until it is purged
+func (ur *noteRepo) Delete(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notes SET
+			deleted_at=` + now + `,
+			version=version+1
+		WHERE id=?1 AND user_id=?2 AND deleted_at IS NULL
+	`
+
+	result, err := ur.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (ur *noteRepo) Restore(ctx context.Context, id, userID int64) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notes SET
+			deleted_at=NULL,
+			version=version+1
+		WHERE id=?1 AND user_id=?2 AND deleted_at IS NOT NULL
+		RETURNING id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at
+	`
+
+	return ur.scanNoteWithTags(ctx, ur.db.QueryRowxContext(ctx, query, id, userID))
+}
+
+// Move checks in the same statement that the notebook belongs to the owner of the note
+func (ur *noteRepo) Move(ctx context.Context, id, userID int64, notebookID *int64) (*repo.Note, error) {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notes SET
+			notebook_id=?1,
+			version=version+1
+		WHERE id=?2 AND user_id=?3 AND deleted_at IS NULL
+			AND (?1 IS NULL OR EXISTS (SELECT 1 FROM notebooks WHERE id=?1 AND user_id=?3))
+		RETURNING id, user_id, notebook_id, title, description, version, created_at, updated_at, deleted_at
+	`
+
+	result, err := ur.scanNoteWithTags(ctx, ur.db.QueryRowxContext(ctx, query, notebookID, id, userID))
+	if !errors.Is(err, repo.ErrNotFound) {
+		return result, err
+	}
+
+	var owned bool
+	query = "SELECT EXISTS(SELECT 1 FROM notes WHERE id=?1 AND user_id=?2 AND deleted_at IS NULL)"
+	err = ur.db.QueryRowxContext(ctx, query, id, userID).Scan(&owned)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if !owned {
+		return nil, repo.ErrNotFound
+	}
+
+	return nil, repo.ErrNotebookNotFound
+}
+
+// Purge deletes the note permanently whether it is in the trash or not
+func (ur *noteRepo) Purge(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query := "DELETE FROM notes WHERE id=?1 AND user_id=?2"
+
+	result, err := ur.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanNote(row scanner) (*repo.Note, error) {
+	var result repo.Note
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.NotebookID,
+		&result.Title,
+		&result.Description,
+		&result.Version,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.DeletedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &result, nil
+}
+
+func scanNoteHighlight(row scanner) (*repo.Note, error) {
+	var (
+		result    repo.Note
+		highlight repo.NoteHighlight
+	)
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.NotebookID,
+		&result.Title,
+		&result.Description,
+		&result.Version,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.DeletedAt,
+		&highlight.Title,
+		&highlight.Description,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	result.Highlight = &highlight
+
+	return &result, nil
+}
+
+func (ur *noteRepo) scanNoteWithTags(ctx context.Context, row scanner) (*repo.Note, error) {
+	note, err := scanNote(row)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	err = ur.loadTags(ctx, note)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return note, nil
+}
+
+// loadTags fills the tags of all the notes with a single query
+func (ur *noteRepo) loadTags(ctx context.Context, notes ...*repo.Note) error {
+	ids := make([]interface{}, 0, len(notes))
+	byID := make(map[int64]*repo.Note, len(notes))
+	for _, note := range notes {
+		note.Tags = make([]*repo.Tag, 0)
+		ids = append(ids, note.ID)
+		byID[note.ID] = note
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT
+			nt.note_id,
+			t.id,
+			t.user_id,
+			t.name,
+			t.created_at
+		FROM note_tags nt
+		JOIN tags t ON t.id=nt.tag_id
+		WHERE nt.note_id IN ` + placeholders(len(ids)) + `
+		ORDER BY t.name
+	`
+
+	rows, err := ur.db.QueryContext(ctx, query, ids...)
+	if err != nil {
+		return translateError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			noteID int64
+			tag    repo.Tag
+		)
+
+		err := rows.Scan(
+			&noteID,
+			&tag.ID,
+			&tag.UserID,
+			&tag.Name,
+			&tag.CreatedAt,
+		)
+		if err != nil {
+			return translateError(err)
+		}
+
+		byID[noteID].Tags = append(byID[noteID].Tags, &tag)
+	}
+
+	return translateError(rows.Err())
+}
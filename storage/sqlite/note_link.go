@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type noteLinkRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewNoteLink(db sqlx.ExtContext, queryTimeout time.Duration) repo.NoteLinkStorageI {
+	return &noteLinkRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// Create adds the link only when the note belongs to the user and is not in the trash
+func (lr *noteLinkRepo) Create(ctx context.Context, link *repo.NoteLink) (*repo.NoteLink, error) {
+	ctx, cancel := withQueryTimeout(ctx, lr.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO note_links(
+			note_id,
+			user_id,
+			token_hash,
+			password,
+			expires_at,
+			max_views
+		)
+		SELECT id, user_id, ?3, ?4, ?5, ?6 FROM notes
+		WHERE id=?1 AND user_id=?2 AND deleted_at IS NULL
+		RETURNING id, view_count, created_at
+	`
+
+	row := lr.db.QueryRowxContext(ctx,
+		query,
+		link.NoteID,
+		link.UserID,
+		link.TokenHash,
+		link.Password,
+		utc(link.ExpiresAt),
+		link.MaxViews,
+	)
+
+	err := row.Scan(
+		&link.ID,
+		&link.ViewCount,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return link, nil
+}
+
+func (lr *noteLinkRepo) GetByHash(ctx context.Context, tokenHash string) (*repo.NoteLink, error) {
+	ctx, cancel := withQueryTimeout(ctx, lr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			id,
+			note_id,
+			user_id,
+			token_hash,
+			password,
+			expires_at,
+			max_views,
+			view_count,
+			revoked_at,
+			created_at
+		FROM note_links
+		WHERE token_hash=?1
+	`
+
+	return scanNoteLink(lr.db.QueryRowxContext(ctx, query, tokenHash))
+}
+
+func (lr *noteLinkRepo) GetAll(ctx context.Context, userID int64) ([]*repo.NoteLink, error) {
+	ctx, cancel := withQueryTimeout(ctx, lr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			id,
+			note_id,
+			user_id,
+			token_hash,
+			password,
+			expires_at,
+			max_views,
+			view_count,
+			revoked_at,
+			created_at
+		FROM note_links
+		WHERE user_id=?1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := lr.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := make([]*repo.NoteLink, 0)
+	for rows.Next() {
+		link, err := scanNoteLink(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, link)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+// utc binds the time in UTC like the timestamps SQLite writes itself, the driver stores
+// the offset in the text so a time of another zone would not compare right with them
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	u := t.UTC()
+	return &u
+}
+
+// AddView checks the link and counts the view in one statement
+// so concurrent views can not go over max_views
+func (lr *noteLinkRepo) AddView(ctx context.Context, id int64) (*repo.NoteLink, error) {
+	ctx, cancel := withQueryTimeout(ctx, lr.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE note_links SET
+			view_count=view_count+1
+		WHERE id=?1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR julianday(expires_at) > julianday('now'))
+			AND (max_views IS NULL OR view_count < max_views)
+		RETURNING id, note_id, user_id, token_hash, password, expires_at, max_views, view_count, revoked_at, created_at
+	`
+
+	result, err := scanNoteLink(lr.db.QueryRowxContext(ctx, query, id))
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, repo.ErrLinkUnavailable
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result, nil
+}
+
+// Revoke keeps the link in the list of the owner, it just stops working
+func (lr *noteLinkRepo) Revoke(ctx context.Context, id, userID int64) (*repo.NoteLink, error) {
+	ctx, cancel := withQueryTimeout(ctx, lr.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE note_links SET
+			revoked_at=` + now + `
+		WHERE id=?1 AND user_id=?2 AND revoked_at IS NULL
+		RETURNING id, note_id, user_id, token_hash, password, expires_at, max_views, view_count, revoked_at, created_at
+	`
+
+	return scanNoteLink(lr.db.QueryRowxContext(ctx, query, id, userID))
+}
+
+func scanNoteLink(row scanner) (*repo.NoteLink, error) {
+	var (
+		result   repo.NoteLink
+		password sql.NullString
+		maxViews sql.NullInt64
+	)
+
+	err := row.Scan(
+		&result.ID,
+		&result.NoteID,
+		&result.UserID,
+		&result.TokenHash,
+		&password,
+		&result.ExpiresAt,
+		&maxViews,
+		&result.ViewCount,
+		&result.RevokedAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if password.Valid {
+		result.Password = &password.String
+	}
+	if maxViews.Valid {
+		result.MaxViews = &maxViews.Int64
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type noteRevisionRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewNoteRevision(db sqlx.ExtContext, queryTimeout time.Duration) repo.NoteRevisionStorageI {
+	return &noteRevisionRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (rr *noteRevisionRepo) Get(ctx context.Context, id, noteID, userID int64) (*repo.NoteRevision, error) {
+	ctx, cancel := withQueryTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			r.id,
+			r.note_id,
+			r.author_id,
+			r.title,
+			r.description,
+			r.created_at
+		FROM note_revisions r
+		JOIN notes n ON n.id=r.note_id
+		WHERE r.id=?1 AND r.note_id=?2 AND ` + canReadNote("n", 3) + ` AND n.deleted_at IS NULL
+	`
+
+	return scanNoteRevision(rr.db.QueryRowxContext(ctx, query, id, noteID, userID))
+}
+
+func (rr *noteRevisionRepo) GetAll(ctx context.Context, noteID, userID int64) ([]*repo.NoteRevision, error) {
+	ctx, cancel := withQueryTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			r.id,
+			r.note_id,
+			r.author_id,
+			r.title,
+			r.description,
+			r.created_at
+		FROM note_revisions r
+		JOIN notes n ON n.id=r.note_id
+		WHERE r.note_id=?1 AND ` + canReadNote("n", 2) + ` AND n.deleted_at IS NULL
+		ORDER BY r.id DESC
+	`
+
+	rows, err := rr.db.QueryContext(ctx, query, noteID, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := make([]*repo.NoteRevision, 0)
+	for rows.Next() {
+		revision, err := scanNoteRevision(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, revision)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+func scanNoteRevision(row scanner) (*repo.NoteRevision, error) {
+	var result repo.NoteRevision
+
+	err := row.Scan(
+		&result.ID,
+		&result.NoteID,
+		&result.AuthorID,
+		&result.Title,
+		&result.Description,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &result, nil
+}
+
+// insertRevision records the current content of the note as a revision by authorID,
+// postgres does it in the statement that changes the note
+func insertRevision(ctx context.Context, db sqlx.ExtContext, note *repo.Note, authorID int64) error {
+	query := "INSERT INTO note_revisions(note_id, author_id, title, description) VALUES (?1, ?2, ?3, ?4)"
+
+	_, err := db.ExecContext(ctx, query, note.ID, authorID, note.Title, note.Description)
+	return translateError(err)
+}
@@ -0,0 +1,196 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type noteShareRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewNoteShare(db sqlx.ExtContext, queryTimeout time.Duration) repo.NoteShareStorageI {
+	return &noteShareRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// canReadNote is true when the user in placeholder n owns the note in table
+// alias or the note is shared with them
+func canReadNote(alias string, n int) string {
+	return fmt.Sprintf(`(%[1]s.user_id=?%[2]d OR EXISTS (
+		SELECT 1 FROM note_shares s WHERE s.note_id=%[1]s.id AND s.user_id=?%[2]d
+	))`, alias, n)
+}
+
+// canWriteNote is like canReadNote but accepts only the editors
+func canWriteNote(alias string, n int) string {
+	return fmt.Sprintf(`(%[1]s.user_id=?%[2]d OR EXISTS (
+		SELECT 1 FROM note_shares s WHERE s.note_id=%[1]s.id AND s.user_id=?%[2]d AND s.permission='editor'
+	))`, alias, n)
+}
+
+// noteShareColumns are returned by the writes, RETURNING can not join so the email is a subquery
+const noteShareColumns = `
+	note_id,
+	user_id,
+	(SELECT email FROM users WHERE id=note_shares.user_id),
+	permission,
+	created_at,
+	updated_at
+`
+
+// Create shares the note only when it belongs to ownerID, the email of the user is filled from users
+func (sr *noteShareRepo) Create(ctx context.Context, ownerID int64, share *repo.NoteShare) (*repo.NoteShare, error) {
+	ctx, cancel := withQueryTimeout(ctx, sr.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO note_shares(
+			note_id,
+			user_id,
+			permission
+		)
+		SELECT id, ?2, ?3 FROM notes
+		WHERE id=?1 AND user_id=?4 AND deleted_at IS NULL
+		RETURNING ` + noteShareColumns
+
+	result, err := scanNoteShare(sr.db.QueryRowxContext(ctx,
+		query,
+		share.NoteID,
+		share.UserID,
+		share.Permission,
+		ownerID,
+	))
+	if isUniqueViolation(err) {
+		return nil, repo.ErrShareExists
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result, nil
+}
+
+func (sr *noteShareRepo) GetAll(ctx context.Context, noteID, ownerID int64) ([]*repo.NoteShare, error) {
+	ctx, cancel := withQueryTimeout(ctx, sr.queryTimeout)
+	defer cancel()
+
+	var exists bool
+	err := sr.db.QueryRowxContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM notes WHERE id=?1 AND user_id=?2 AND deleted_at IS NULL)",
+		noteID,
+		ownerID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if !exists {
+		return nil, repo.ErrNotFound
+	}
+
+	query := `
+		SELECT
+			s.note_id,
+			s.user_id,
+			u.email,
+			s.permission,
+			s.created_at,
+			s.updated_at
+		FROM note_shares s
+		JOIN users u ON u.id=s.user_id
+		WHERE s.note_id=?1
+		ORDER BY s.created_at, s.rowid
+	`
+
+	rows, err := sr.db.QueryContext(ctx, query, noteID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := make([]*repo.NoteShare, 0)
+	for rows.Next() {
+		share, err := scanNoteShare(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, share)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+func (sr *noteShareRepo) Update(ctx context.Context, ownerID int64, share *repo.NoteShare) (*repo.NoteShare, error) {
+	ctx, cancel := withQueryTimeout(ctx, sr.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE note_shares SET
+			permission=?3,
+			updated_at=` + now + `
+		WHERE note_id=?1 AND user_id=?2
+			AND note_id IN (SELECT id FROM notes WHERE user_id=?4 AND deleted_at IS NULL)
+		RETURNING ` + noteShareColumns
+
+	return scanNoteShare(sr.db.QueryRowxContext(ctx,
+		query,
+		share.NoteID,
+		share.UserID,
+		share.Permission,
+		ownerID,
+	))
+}
+
+func (sr *noteShareRepo) Delete(ctx context.Context, noteID, ownerID, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, sr.queryTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM note_shares
+		WHERE note_id=?1 AND user_id=?2
+			AND note_id IN (SELECT id FROM notes WHERE user_id=?3)
+	`
+
+	result, err := sr.db.ExecContext(ctx, query, noteID, userID, ownerID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanNoteShare(row scanner) (*repo.NoteShare, error) {
+	var result repo.NoteShare
+
+	err := row.Scan(
+		&result.NoteID,
+		&result.UserID,
+		&result.Email,
+		&result.Permission,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,264 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type notebookRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewNotebook(db sqlx.ExtContext, queryTimeout time.Duration) repo.NotebookStorageI {
+	return &notebookRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// Create checks the parent in the same transaction, see checkParent
+func (nr *notebookRepo) Create(ctx context.Context, notebook *repo.Notebook) (*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO notebooks(
+			user_id,
+			parent_id,
+			name
+		) VALUES(?1, ?2, ?3)
+		RETURNING id, created_at
+	`
+
+	err := inTx(ctx, nr.db, func(tx sqlx.ExtContext) error {
+		err := checkParent(ctx, tx, 0, notebook.UserID, notebook.ParentID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowxContext(ctx, query, notebook.UserID, notebook.ParentID, notebook.Name).Scan(
+			&notebook.ID,
+			&notebook.CreatedAt,
+		)
+		return translateError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notebook, nil
+}
+
+const queryNotebook = `
+	SELECT
+		nb.id,
+		nb.user_id,
+		nb.parent_id,
+		nb.name,
+		(SELECT count(*) FROM notes n WHERE n.notebook_id=nb.id AND n.deleted_at IS NULL),
+		nb.created_at,
+		nb.updated_at
+	FROM notebooks nb
+	WHERE nb.id=?1 AND nb.user_id=?2
+`
+
+func (nr *notebookRepo) Get(ctx context.Context, id, userID int64) (*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	return scanNotebook(nr.db.QueryRowxContext(ctx, queryNotebook, id, userID))
+}
+
+func (nr *notebookRepo) GetAll(ctx context.Context, userID int64) ([]*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			nb.id,
+			nb.user_id,
+			nb.parent_id,
+			nb.name,
+			(SELECT count(*) FROM notes n WHERE n.notebook_id=nb.id AND n.deleted_at IS NULL),
+			nb.created_at,
+			nb.updated_at
+		FROM notebooks nb
+		WHERE nb.user_id=?1
+		ORDER BY nb.name, nb.id
+	`
+
+	rows, err := nr.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := make([]*repo.Notebook, 0)
+	for rows.Next() {
+		notebook, err := scanNotebook(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, notebook)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+func (nr *notebookRepo) Rename(ctx context.Context, id, userID int64, name string) (*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notebooks SET
+			name=?1,
+			updated_at=` + now + `
+		WHERE id=?2 AND user_id=?3
+	`
+
+	return nr.update(ctx, nr.db, query, id, userID, name, id, userID)
+}
+
+// Move checks the new parent in the same transaction, see checkParent
+func (nr *notebookRepo) Move(ctx context.Context, id, userID int64, parentID *int64) (*repo.Notebook, error) {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	queryOwned := "SELECT EXISTS(SELECT 1 FROM notebooks WHERE id=?1 AND user_id=?2)"
+
+	query := `
+		UPDATE notebooks SET
+			parent_id=?1,
+			updated_at=` + now + `
+		WHERE id=?2 AND user_id=?3
+	`
+
+	var result *repo.Notebook
+	err := inTx(ctx, nr.db, func(tx sqlx.ExtContext) error {
+		var owned bool
+		err := tx.QueryRowxContext(ctx, queryOwned, id, userID).Scan(&owned)
+		if err != nil {
+			return translateError(err)
+		}
+
+		if !owned {
+			return repo.ErrNotFound
+		}
+
+		err = checkParent(ctx, tx, id, userID, parentID)
+		if err != nil {
+			return err
+		}
+
+		result, err = nr.update(ctx, tx, query, id, userID, parentID, id, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (nr *notebookRepo) update(ctx context.Context, db sqlx.ExtContext, query string, id, userID int64, args ...interface{}) (*repo.Notebook, error) {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return nil, repo.ErrNotFound
+	}
+
+	return scanNotebook(db.QueryRowxContext(ctx, queryNotebook, id, userID))
+}
+
+func (nr *notebookRepo) Delete(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, nr.queryTimeout)
+	defer cancel()
+
+	query := "DELETE FROM notebooks WHERE id=?1 AND user_id=?2"
+
+	result, err := nr.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanNotebook(row scanner) (*repo.Notebook, error) {
+	var result repo.Notebook
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.ParentID,
+		&result.Name,
+		&result.NoteCount,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &result, nil
+}
+
+// checkParent does what the notebooks_check_parent trigger does in postgres: the parent
+// must be a notebook of the same user and notebook id must not be the parent or one of
+// its ancestors. It runs in the transaction of the write that follows it, SQLite takes
+// the write lock when the transaction begins so the tree can not change in between
+func checkParent(ctx context.Context, db sqlx.ExtContext, id, userID int64, parentID *int64) error {
+	if parentID == nil {
+		return nil
+	}
+
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM notebooks WHERE id=?1 AND user_id=?2),
+			EXISTS (
+				WITH RECURSIVE ancestors(id, parent_id) AS (
+					SELECT id, parent_id FROM notebooks WHERE id=?1
+					UNION
+					SELECT nb.id, nb.parent_id FROM notebooks nb JOIN ancestors a ON nb.id=a.parent_id
+				)
+				SELECT 1 FROM ancestors WHERE id=?3
+			)
+	`
+
+	var found, cycle bool
+	err := db.QueryRowxContext(ctx, query, *parentID, userID, id).Scan(&found, &cycle)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if !found {
+		return repo.ErrNotebookNotFound
+	}
+	if cycle {
+		return repo.ErrNotebookCycle
+	}
+
+	return nil
+}
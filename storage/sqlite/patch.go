@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// patchColumn is a column a patch may set, an empty string is stored
+// as NULL in a nullable one the same way the full update stores it
+type patchColumn struct {
+	nullable bool
+}
+
+// setClause builds the SET list of a dynamic UPDATE from the patched columns,
+// only whitelisted columns are accepted and every value is passed as a placeholder
+// starting at ?1, the values are returned in the order of the placeholders
+func setClause(fields map[string]interface{}, allowed map[string]patchColumn) (string, []interface{}, error) {
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		if _, ok := allowed[column]; !ok {
+			return "", nil, fmt.Errorf("column %q can not be patched", column)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	sets := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns))
+	for i, column := range columns {
+		placeholder := fmt.Sprintf("?%d", i+1)
+		if allowed[column].nullable {
+			placeholder = "NULLIF(" + placeholder + ", '')"
+		}
+		sets = append(sets, column+"="+placeholder)
+		args = append(args, fields[column])
+	}
+
+	return strings.Join(sets, ", "), args, nil
+}
@@ -0,0 +1,149 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/burxondv/note-template/storage/repo"
+)
+
+// selectQuery builds the SELECT of a list endpoint, values are only ever bound
+// through placeholders and sorting accepts only whitelisted columns and directions
+type selectQuery struct {
+	from    string
+	columns []string
+	filters []string
+	orderBy []string
+	args    []interface{}
+	limit   int32
+	offset  int64
+}
+
+func newSelectQuery(from string, columns ...string) *selectQuery {
+	return &selectQuery{
+		from:    from,
+		columns: columns,
+	}
+}
+
+// arg binds a value and returns its placeholder, the placeholder can be used
+// more than once. Values bound for the filters are shared with the count query
+// so columns and sorting may only reference placeholders the filters use too
+func (q *selectQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("?%d", len(q.args))
+}
+
+// argList binds every value and returns the placeholders for an IN list,
+// it takes the place of = ANY with an array parameter in postgres
+func (q *selectQuery) argList(values []int64) string {
+	placeholders := make([]string, 0, len(values))
+	for _, v := range values {
+		placeholders = append(placeholders, q.arg(v))
+	}
+
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// placeholders returns the list of the placeholders ?1 to ?n for an IN list
+func placeholders(n int) string {
+	list := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		list = append(list, fmt.Sprintf("?%d", i))
+	}
+
+	return "(" + strings.Join(list, ", ") + ")"
+}
+
+// where adds a condition, the conditions are joined with AND
+func (q *selectQuery) where(condition string) {
+	q.filters = append(q.filters, "("+condition+")")
+}
+
+func (q *selectQuery) column(expr string) {
+	q.columns = append(q.columns, expr)
+}
+
+// sort orders by the column allowed maps the key to, direction is asc or desc
+// and anything else is rejected with ErrInvalidSort
+func (q *selectQuery) sort(key, direction string, allowed map[string]string) error {
+	column, ok := allowed[key]
+	if !ok {
+		return repo.ErrInvalidSort
+	}
+
+	direction = strings.ToUpper(direction)
+	if direction == "" {
+		direction = "DESC"
+	}
+	if direction != "ASC" && direction != "DESC" {
+		return repo.ErrInvalidSort
+	}
+
+	// SQLite sorts NULL as the smallest value, postgres as the largest
+	nulls := " NULLS LAST"
+	if direction == "DESC" {
+		nulls = " NULLS FIRST"
+	}
+
+	q.orderBy = append(q.orderBy, column+" "+direction+nulls)
+
+	// the timestamps SQLite writes have milliseconds only, the rows created in the same
+	// millisecond keep their order by id as ids are given out in the order of creation
+	if column == "created_at" {
+		q.orderBy = append(q.orderBy, "id "+direction)
+	}
+
+	return nil
+}
+
+// sortBy adds an ordering expression written by the caller, it must not hold user input
+func (q *selectQuery) sortBy(expr string) {
+	q.orderBy = append(q.orderBy, expr)
+}
+
+// page limits the rows to the given page, a limit of zero returns all of them
+// and a page below one is the first page
+func (q *selectQuery) page(page, limit int32) {
+	if page < 1 {
+		page = 1
+	}
+
+	q.limit = limit
+	q.offset = int64(page-1) * int64(limit)
+}
+
+func (q *selectQuery) whereClause() string {
+	if len(q.filters) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(q.filters, " AND ")
+}
+
+// sql returns the query with the page bound after the filter values
+func (q *selectQuery) sql() (string, []interface{}) {
+	query := "SELECT " + strings.Join(q.columns, ", ") + " FROM " + q.from + q.whereClause()
+	if len(q.orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(q.orderBy, ", ")
+	}
+
+	args := append([]interface{}{}, q.args...)
+	if q.limit > 0 {
+		args = append(args, q.limit, q.offset)
+		query += fmt.Sprintf(" LIMIT ?%d OFFSET ?%d", len(args)-1, len(args))
+	}
+
+	return query, args
+}
+
+// countSQL counts all the rows matching the filters regardless of the page
+func (q *selectQuery) countSQL() (string, []interface{}) {
+	return "SELECT count(*) FROM " + q.from + q.whereClause(), q.args
+}
+
+// likePattern escapes the wildcards of s and matches it anywhere in the text
+func likePattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
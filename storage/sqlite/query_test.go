@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"math"
+	"testing"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectQuery(t *testing.T) {
+	q := newSelectQuery("notes", "id", "title")
+	userID := q.arg(int64(7))
+	q.where("user_id=" + userID)
+	q.where("ilike(title, " + q.arg(likePattern("'; DROP TABLE notes; --")) + ")")
+	q.where("id IN " + q.argList([]int64{1, 2}))
+	require.NoError(t, q.sort("title", "asc", noteSortColumns))
+	q.sortBy("id DESC")
+	q.page(3, 10)
+
+	query, args := q.sql()
+	require.Equal(t, "SELECT id, title FROM notes WHERE (user_id=?1) AND (ilike(title, ?2)) AND (id IN (?3, ?4)) ORDER BY title ASC NULLS LAST, id DESC LIMIT ?5 OFFSET ?6", query)
+	require.Equal(t, []interface{}{int64(7), `%'; DROP TABLE notes; --%`, int64(1), int64(2), int32(10), int64(20)}, args)
+
+	query, args = q.countSQL()
+	require.Equal(t, "SELECT count(*) FROM notes WHERE (user_id=?1) AND (ilike(title, ?2)) AND (id IN (?3, ?4))", query)
+	require.Len(t, args, 4)
+}
+
+func TestSelectQueryPage(t *testing.T) {
+	q := newSelectQuery("notes", "id")
+	q.page(0, 10)
+	_, args := q.sql()
+	require.Equal(t, []interface{}{int32(10), int64(0)}, args)
+
+	q = newSelectQuery("notes", "id")
+	q.page(math.MaxInt32, 100)
+	_, args = q.sql()
+	require.Equal(t, []interface{}{int32(100), int64(math.MaxInt32-1) * 100}, args)
+}
+
+func TestSelectQuerySort(t *testing.T) {
+	tests := []struct {
+		name      string
+		column    string
+		direction string
+		orderBy   []string
+		err       error
+	}{
+		{"default direction", "created_at", "", []string{"created_at DESC NULLS FIRST", "id DESC"}, nil},
+		{"upper case", "title", "DESC", []string{"title DESC NULLS FIRST"}, nil},
+		{"ascending", "updated_at", "asc", []string{"updated_at ASC NULLS LAST"}, nil},
+		{"unknown column", "password", "asc", nil, repo.ErrInvalidSort},
+		{"injected column", "title; DROP TABLE notes", "asc", nil, repo.ErrInvalidSort},
+		{"injected direction", "title", "asc, (SELECT 1)", nil, repo.ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newSelectQuery("notes", "id")
+			require.ErrorIs(t, q.sort(tt.column, tt.direction, noteSortColumns), tt.err)
+			require.Equal(t, tt.orderBy, q.orderBy)
+		})
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	require.Equal(t, `%100\%%`, likePattern("100%"))
+	require.Equal(t, `%a\_b%`, likePattern("a_b"))
+	require.Equal(t, `%c:\\dir%`, likePattern(`c:\dir`))
+}
@@ -0,0 +1,60 @@
+// Package sqlite stores the data in a SQLite database for installs that do not run
+// postgres. The repos follow the semantics of the postgres repos, what SQLite lacks
+// like ILIKE and the full-text search is provided by functions registered on every
+// connection, see functions.go
+package sqlite
+
+import (
+	"database/sql"
+	"net/url"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// DriverName is the database/sql driver with the functions of this package registered
+const DriverName = "sqlite3_notes"
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: registerFunctions,
+	})
+}
+
+// Open opens the database file at path, creating it when it does not exist.
+// Foreign keys are enforced, writers wait for each other instead of failing
+// and transactions take the write lock when they begin so they can not deadlock.
+// The ":memory:" path opens a private in-memory database on a single connection
+func Open(path string) (*sqlx.DB, error) {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_busy_timeout", "5000")
+	params.Set("_txlock", "immediate")
+
+	memory := path == ":memory:"
+	if !memory {
+		params.Set("_journal_mode", "WAL")
+	}
+
+	db, err := sqlx.Open(DriverName, "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	// every connection to :memory: opens a database of its own
+	if memory {
+		db.SetMaxOpenConns(1)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// now is the current time in the format the timestamps are stored in, with milliseconds
+// so the order of the rows created one after another is kept
+const now = "strftime('%Y-%m-%d %H:%M:%f', 'now')"
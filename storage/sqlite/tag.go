@@ -0,0 +1,269 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type tagRepo struct {
+	db           sqlx.ExtContext
+	queryTimeout time.Duration
+}
+
+func NewTag(db sqlx.ExtContext, queryTimeout time.Duration) repo.TagStorageI {
+	return &tagRepo{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (tr *tagRepo) Create(ctx context.Context, tag *repo.Tag) (*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO tags(
+			user_id,
+			name
+		) VALUES(?1, ?2)
+		RETURNING id, created_at
+	`
+
+	err := tr.db.QueryRowxContext(ctx, query, tag.UserID, tag.Name).Scan(
+		&tag.ID,
+		&tag.CreatedAt,
+	)
+	if isUniqueViolation(err) {
+		return nil, repo.ErrTagExists
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return tag, nil
+}
+
+func (tr *tagRepo) Get(ctx context.Context, id, userID int64) (*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			t.id,
+			t.user_id,
+			t.name,
+			(SELECT count(*) FROM note_tags nt WHERE nt.tag_id=t.id),
+			t.created_at
+		FROM tags t
+		WHERE t.id=?1 AND t.user_id=?2
+	`
+
+	return scanTag(tr.db.QueryRowxContext(ctx, query, id, userID))
+}
+
+func (tr *tagRepo) GetAll(ctx context.Context, userID int64) ([]*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			t.id,
+			t.user_id,
+			t.name,
+			(SELECT count(*) FROM note_tags nt WHERE nt.tag_id=t.id),
+			t.created_at
+		FROM tags t
+		WHERE t.user_id=?1
+		ORDER BY t.name
+	`
+
+	rows, err := tr.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := make([]*repo.Tag, 0)
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, tag)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+func (tr *tagRepo) Rename(ctx context.Context, id, userID int64, name string) (*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE tags SET
+			name=?1
+		WHERE id=?2 AND user_id=?3
+		RETURNING
+			id,
+			user_id,
+			name,
+			(SELECT count(*) FROM note_tags nt WHERE nt.tag_id=tags.id),
+			created_at
+	`
+
+	tag, err := scanTag(tr.db.QueryRowxContext(ctx, query, name, id, userID))
+	if isUniqueViolation(err) {
+		return nil, repo.ErrTagExists
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return tag, nil
+}
+
+// Merge relinks the notes and drops the source tag in one transaction,
+// notes that already had both tags keep a single link to the target
+func (tr *tagRepo) Merge(ctx context.Context, sourceID, targetID, userID int64) (*repo.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	owned := `
+		EXISTS (SELECT 1 FROM tags WHERE id=?1 AND user_id=?3)
+		AND EXISTS (SELECT 1 FROM tags WHERE id=?2 AND user_id=?3)
+	`
+
+	queryMove := `
+		INSERT OR IGNORE INTO note_tags(note_id, tag_id)
+		SELECT note_id, ?2 FROM note_tags
+		WHERE tag_id=?1 AND ` + owned
+
+	queryDelete := "DELETE FROM tags WHERE id=?1 AND " + owned
+
+	err := inTx(ctx, tr.db, func(tx sqlx.ExtContext) error {
+		_, err := tx.ExecContext(ctx, queryMove, sourceID, targetID, userID)
+		if err != nil {
+			return translateError(err)
+		}
+
+		result, err := tx.ExecContext(ctx, queryDelete, sourceID, targetID, userID)
+		if err != nil {
+			return translateError(err)
+		}
+
+		rowsCount, err := result.RowsAffected()
+		if err != nil {
+			return translateError(err)
+		}
+
+		if rowsCount == 0 {
+			return repo.ErrNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.Get(ctx, targetID, userID)
+}
+
+func (tr *tagRepo) Delete(ctx context.Context, id, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := "DELETE FROM tags WHERE id=?1 AND user_id=?2"
+
+	result, err := tr.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (tr *tagRepo) Attach(ctx context.Context, noteID, tagID, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	queryOwned := `
+		SELECT
+			EXISTS (SELECT 1 FROM notes WHERE id=?1 AND user_id=?3 AND deleted_at IS NULL)
+			AND EXISTS (SELECT 1 FROM tags WHERE id=?2 AND user_id=?3)
+	`
+
+	query := "INSERT OR IGNORE INTO note_tags(note_id, tag_id) VALUES (?1, ?2)"
+
+	return inTx(ctx, tr.db, func(tx sqlx.ExtContext) error {
+		var owned bool
+		err := tx.QueryRowxContext(ctx, queryOwned, noteID, tagID, userID).Scan(&owned)
+		if err != nil {
+			return translateError(err)
+		}
+
+		if !owned {
+			return repo.ErrNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, query, noteID, tagID)
+		return translateError(err)
+	})
+}
+
+func (tr *tagRepo) Detach(ctx context.Context, noteID, tagID, userID int64) error {
+	ctx, cancel := withQueryTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM note_tags
+		WHERE note_id=?1 AND tag_id=?2
+			AND note_id IN (SELECT id FROM notes WHERE user_id=?3 AND deleted_at IS NULL)
+	`
+
+	result, err := tr.db.ExecContext(ctx, query, noteID, tagID, userID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+
+	if rowsCount == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanTag(row scanner) (*repo.Tag, error) {
+	var result repo.Tag
+
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.Name,
+		&result.NoteCount,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,16 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+)
+
+// withQueryTimeout bounds a repo call with the query timeout on top of the deadline of the caller,
+// a zero timeout leaves only the deadline of the caller
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// maxTxAttempts is how many times RunInTx runs a transaction that keeps finding the database busy
+const maxTxAttempts = 3
+
+// RunInTx runs fn in a transaction, it commits when fn returns nil and rolls back on
+// an error or a panic. SQLite runs one writer at a time so transactions are serializable,
+// a transaction that could not get the lock within the busy timeout is retried from
+// the start, so fn must not have side effects outside of the transaction
+func RunInTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = runInTx(ctx, db, fn)
+		if !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+
+	return err
+}
+
+func runInTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	return translateError(tx.Commit())
+}
+
+// inTx runs fn in a transaction of its own when db is not a transaction yet,
+// the repos use it for the changes postgres makes in a single statement
+func inTx(ctx context.Context, db sqlx.ExtContext, fn func(tx sqlx.ExtContext) error) error {
+	sqlDB, ok := db.(*sqlx.DB)
+	if !ok {
+		return fn(db)
+	}
+
+	return RunInTx(ctx, sqlDB, func(tx *sqlx.Tx) error {
+		return fn(tx)
+	})
+}
+
+func isRetryable(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
@@ -0,0 +1,79 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentWrites runs transactions on many connections at once, they must wait
+// for the write lock instead of failing with database is locked
+func TestConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	email := faker.Email()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		created   []int64
+		conflicts int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var user *repo.User
+			err := strg.WithTx(ctx, func(tx storage.StorageI) error {
+				var err error
+				user, err = tx.User().Create(ctx, &repo.User{
+					FirstName: "Same",
+					LastName:  "Email",
+					Email:     email,
+				})
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Note().Create(ctx, &repo.Note{UserID: user.ID, Title: "Welcome"})
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created = append(created, user.ID)
+			case errors.Is(err, repo.ErrConflict):
+				conflicts++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, created, 1)
+	require.Equal(t, 19, conflicts)
+
+	notes, err := strg.Note().GetAll(ctx, &repo.GetAllNotesParams{UserID: created[0]})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), notes.Count)
+
+	require.NoError(t, strg.Note().Purge(ctx, notes.Notes[0].ID, created[0]))
+	require.NoError(t, strg.User().Delete(ctx, created[0]))
+}
+
+func TestCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := strg.User().Get(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/burxondv/note-template/storage/sqlite"
+	"github.com/jmoiron/sqlx"
+)
+
+type storageSqlite struct {
+	// db is nil when the repos run inside a transaction
+	db           *sqlx.DB
+	queryTimeout time.Duration
+
+	userRepo     repo.UserStorageI
+	noteRepo     repo.NoteStorageI
+	apiTokenRepo repo.ApiTokenStorageI
+	revisionRepo repo.NoteRevisionStorageI
+	tagRepo      repo.TagStorageI
+	notebookRepo repo.NotebookStorageI
+	shareRepo    repo.NoteShareStorageI
+	linkRepo     repo.NoteLinkStorageI
+}
+
+// NewStorageSqlite builds the repos on db, queryTimeout bounds every repo call and zero disables it
+func NewStorageSqlite(db *sqlx.DB, queryTimeout time.Duration) StorageI {
+	s := newStorageSqlite(db, queryTimeout)
+	s.db = db
+
+	return s
+}
+
+func newStorageSqlite(db sqlx.ExtContext, queryTimeout time.Duration) *storageSqlite {
+	return &storageSqlite{
+		queryTimeout: queryTimeout,
+		userRepo:     sqlite.NewUser(db, queryTimeout),
+		noteRepo:     sqlite.NewNote(db, queryTimeout),
+		apiTokenRepo: sqlite.NewApiToken(db, queryTimeout),
+		revisionRepo: sqlite.NewNoteRevision(db, queryTimeout),
+		tagRepo:      sqlite.NewTag(db, queryTimeout),
+		notebookRepo: sqlite.NewNotebook(db, queryTimeout),
+		shareRepo:    sqlite.NewNoteShare(db, queryTimeout),
+		linkRepo:     sqlite.NewNoteLink(db, queryTimeout),
+	}
+}
+
+func (s *storageSqlite) WithTx(ctx context.Context, fn func(StorageI) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+
+	return sqlite.RunInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		return fn(newStorageSqlite(tx, s.queryTimeout))
+	})
+}
+
+func (s *storageSqlite) User() repo.UserStorageI {
+	return s.userRepo
+}
+
+func (s *storageSqlite) Note() repo.NoteStorageI {
+	return s.noteRepo
+}
+
+func (s *storageSqlite) ApiToken() repo.ApiTokenStorageI {
+	return s.apiTokenRepo
+}
+
+func (s *storageSqlite) NoteRevision() repo.NoteRevisionStorageI {
+	return s.revisionRepo
+}
+
+func (s *storageSqlite) Tag() repo.TagStorageI {
+	return s.tagRepo
+}
+
+func (s *storageSqlite) Notebook() repo.NotebookStorageI {
+	return s.notebookRepo
+}
+
+func (s *storageSqlite) NoteShare() repo.NoteShareStorageI {
+	return s.shareRepo
+}
+
+func (s *storageSqlite) NoteLink() repo.NoteLinkStorageI {
+	return s.linkRepo
+}
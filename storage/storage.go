@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"context"
+	"time"
+
 	"github.com/burxondv/note-template/storage/postgres"
 	"github.com/burxondv/note-template/storage/repo"
 	"github.com/jmoiron/sqlx"
@@ -10,20 +13,65 @@ import (
 type StorageI interface {
 	User() repo.UserStorageI
 	Note() repo.NoteStorageI
+	ApiToken() repo.ApiTokenStorageI
+	NoteRevision() repo.NoteRevisionStorageI
+	Tag() repo.TagStorageI
+	Notebook() repo.NotebookStorageI
+	NoteShare() repo.NoteShareStorageI
+	NoteLink() repo.NoteLinkStorageI
+	// WithTx runs fn with a storage whose repos share one transaction, it is committed
+	// when fn returns nil and rolled back otherwise. fn may be run again when the
+	// transaction fails to serialize, a WithTx inside fn joins the outer transaction
+	WithTx(ctx context.Context, fn func(StorageI) error) error
 }
 
 type storagePg struct {
-	userRepo repo.UserStorageI
-	noteRepo repo.NoteStorageI
+	// db is nil when the repos run inside a transaction
+	db           *sqlx.DB
+	queryTimeout time.Duration
+
+	userRepo     repo.UserStorageI
+	noteRepo     repo.NoteStorageI
+	apiTokenRepo repo.ApiTokenStorageI
+	revisionRepo repo.NoteRevisionStorageI
+	tagRepo      repo.TagStorageI
+	notebookRepo repo.NotebookStorageI
+	shareRepo    repo.NoteShareStorageI
+	linkRepo     repo.NoteLinkStorageI
+}
+
+// NewStoragePg builds the repos on db, queryTimeout bounds every repo call and zero disables it
+func NewStoragePg(db *sqlx.DB, queryTimeout time.Duration) StorageI {
+	s := newStoragePg(db, queryTimeout)
+	s.db = db
+
+	return s
 }
 
-func NewStoragePg(db *sqlx.DB) StorageI {
+func newStoragePg(db sqlx.ExtContext, queryTimeout time.Duration) *storagePg {
 	return &storagePg{
-		userRepo: postgres.NewUser(db),
-		noteRepo: postgres.NewNote(db),
+		queryTimeout: queryTimeout,
+		userRepo:     postgres.NewUser(db, queryTimeout),
+		noteRepo:     postgres.NewNote(db, queryTimeout),
+		apiTokenRepo: postgres.NewApiToken(db, queryTimeout),
+		revisionRepo: postgres.NewNoteRevision(db, queryTimeout),
+		tagRepo:      postgres.NewTag(db, queryTimeout),
+		notebookRepo: postgres.NewNotebook(db, queryTimeout),
+		shareRepo:    postgres.NewNoteShare(db, queryTimeout),
+		linkRepo:     postgres.NewNoteLink(db, queryTimeout),
 	}
 }
 
+func (s *storagePg) WithTx(ctx context.Context, fn func(StorageI) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+
+	return postgres.RunInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		return fn(newStoragePg(tx, s.queryTimeout))
+	})
+}
+
 func (s *storagePg) User() repo.UserStorageI {
 	return s.userRepo
 }
@@ -31,3 +79,27 @@ func (s *storagePg) User() repo.UserStorageI {
 func (s *storagePg) Note() repo.NoteStorageI {
 	return s.noteRepo
 }
+
+func (s *storagePg) ApiToken() repo.ApiTokenStorageI {
+	return s.apiTokenRepo
+}
+
+func (s *storagePg) NoteRevision() repo.NoteRevisionStorageI {
+	return s.revisionRepo
+}
+
+func (s *storagePg) Tag() repo.TagStorageI {
+	return s.tagRepo
+}
+
+func (s *storagePg) Notebook() repo.NotebookStorageI {
+	return s.notebookRepo
+}
+
+func (s *storagePg) NoteShare() repo.NoteShareStorageI {
+	return s.shareRepo
+}
+
+func (s *storagePg) NoteLink() repo.NoteLinkStorageI {
+	return s.linkRepo
+}
@@ -0,0 +1,931 @@
+// Package storagetest holds the tests every implementation of storage.StorageI
+// must pass, each backend runs them from its own tests with Run. The tests create
+// their own rows with unique names so they can run against a database in use
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/burxondv/note-template/storage"
+	"github.com/burxondv/note-template/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// missingID does not exist in any table and fits the INTEGER ids of postgres
+const missingID = math.MaxInt32
+
+type suite struct {
+	strg storage.StorageI
+}
+
+// Run runs the conformance tests against strg
+func Run(t *testing.T, strg storage.StorageI) {
+	s := &suite{strg: strg}
+
+	t.Run("UserCreate", s.testUserCreate)
+	t.Run("UserUnique", s.testUserUnique)
+	t.Run("UserGetAll", s.testUserGetAll)
+	t.Run("UserVersion", s.testUserVersion)
+	t.Run("UserDelete", s.testUserDelete)
+	t.Run("NoteCreate", s.testNoteCreate)
+	t.Run("NoteAccess", s.testNoteAccess)
+	t.Run("NoteGetAll", s.testNoteGetAll)
+	t.Run("NoteFullText", s.testNoteFullText)
+	t.Run("NoteTrash", s.testNoteTrash)
+	t.Run("NoteTags", s.testNoteTags)
+	t.Run("Notebooks", s.testNotebooks)
+	t.Run("NoteShares", s.testNoteShares)
+	t.Run("NoteLinks", s.testNoteLinks)
+	t.Run("ApiTokens", s.testApiTokens)
+	t.Run("WithTx", s.testWithTx)
+}
+
+var uniqueCounter int64
+
+// unique returns a word of lowercase letters that no other call returns,
+// it is used in names and titles to find the rows of one test
+func unique() string {
+	n := time.Now().UnixNano() + atomic.AddInt64(&uniqueCounter, 1)
+
+	var b strings.Builder
+	b.WriteString("zq")
+	for ; n > 0; n /= 26 {
+		b.WriteByte(byte('a' + n%26))
+	}
+
+	return b.String()
+}
+
+func newUser() *repo.User {
+	return &repo.User{
+		FirstName:   faker.FirstName(),
+		LastName:    faker.LastName(),
+		PhoneNumber: faker.E164PhoneNumber(),
+		Email:       unique() + "@example.com",
+		ImageURL:    faker.URL(),
+	}
+}
+
+// createUser stores u and deletes it when the test ends, a nil u is a random user
+func (s *suite) createUser(t *testing.T, u *repo.User) *repo.User {
+	if u == nil {
+		u = newUser()
+	}
+
+	user, err := s.strg.User().Create(context.Background(), u)
+	require.NoError(t, err)
+	require.NotZero(t, user.ID)
+
+	t.Cleanup(func() {
+		_ = s.strg.User().Delete(context.Background(), user.ID)
+	})
+
+	return user
+}
+
+// createNote stores a note of the user and purges it when the test ends,
+// the cleanup runs before the one of the user
+func (s *suite) createNote(t *testing.T, userID int64, title, description string) *repo.Note {
+	note, err := s.strg.Note().Create(context.Background(), &repo.Note{
+		UserID:      userID,
+		Title:       title,
+		Description: description,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, note.ID)
+
+	t.Cleanup(func() {
+		_ = s.strg.Note().Purge(context.Background(), note.ID, userID)
+	})
+
+	return note
+}
+
+func noteIDs(notes []*repo.Note) []int64 {
+	ids := make([]int64, 0, len(notes))
+	for _, n := range notes {
+		ids = append(ids, n.ID)
+	}
+
+	return ids
+}
+
+func (s *suite) testUserCreate(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+
+	require.Equal(t, int64(1), user.Version)
+	require.Equal(t, repo.RoleUser, user.Role)
+	require.False(t, user.CreatedAt.IsZero())
+
+	stored, err := s.strg.User().Get(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, stored.Email)
+	require.Equal(t, user.PhoneNumber, stored.PhoneNumber)
+	require.Equal(t, user.ImageURL, stored.ImageURL)
+
+	byEmail, err := s.strg.User().GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, byEmail.ID)
+
+	_, err = s.strg.User().Get(ctx, missingID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	_, err = s.strg.User().GetByEmail(ctx, unique()+"@example.com")
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
+
+func (s *suite) testUserUnique(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+
+	duplicate := newUser()
+	duplicate.Email = user.Email
+	_, err := s.strg.User().Create(ctx, duplicate)
+	require.ErrorIs(t, err, repo.ErrConflict)
+
+	duplicate = newUser()
+	duplicate.PhoneNumber = user.PhoneNumber
+	_, err = s.strg.User().Create(ctx, duplicate)
+	require.ErrorIs(t, err, repo.ErrConflict)
+
+	// users without a phone number do not clash
+	for i := 0; i < 2; i++ {
+		u := newUser()
+		u.PhoneNumber = ""
+		s.createUser(t, u)
+	}
+
+	other := s.createUser(t, nil)
+	other.Email = user.Email
+	_, err = s.strg.User().Update(ctx, other)
+	require.ErrorIs(t, err, repo.ErrConflict)
+
+	_, err = s.strg.User().Patch(ctx, &repo.UserPatch{
+		ID:      other.ID,
+		Version: other.Version,
+		Fields:  map[string]interface{}{"phone_number": user.PhoneNumber},
+	})
+	require.ErrorIs(t, err, repo.ErrConflict)
+
+	// a patch clears the phone number with an empty string like an update does
+	for i := 0; i < 2; i++ {
+		u := s.createUser(t, nil)
+		patched, err := s.strg.User().Patch(ctx, &repo.UserPatch{
+			ID:      u.ID,
+			Version: u.Version,
+			Fields:  map[string]interface{}{"phone_number": "", "image_url": ""},
+		})
+		require.NoError(t, err)
+		require.Empty(t, patched.PhoneNumber)
+		require.Empty(t, patched.ImageURL)
+	}
+}
+
+func (s *suite) testUserGetAll(t *testing.T) {
+	ctx := context.Background()
+	lastName := unique()
+
+	for _, firstName := range []string{"Bob", "Alice", "Carol"} {
+		u := newUser()
+		u.FirstName = firstName
+		u.LastName = lastName
+		s.createUser(t, u)
+	}
+
+	result, err := s.strg.User().GetAll(ctx, &repo.GetAllUsersParams{
+		Search:    strings.ToUpper(lastName),
+		SortBy:    "first_name",
+		SortOrder: "asc",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(3), result.Count)
+	require.Len(t, result.Users, 3)
+	require.Equal(t, "Alice", result.Users[0].FirstName)
+	require.Equal(t, "Bob", result.Users[1].FirstName)
+	require.Equal(t, "Carol", result.Users[2].FirstName)
+
+	result, err = s.strg.User().GetAll(ctx, &repo.GetAllUsersParams{
+		Search:    lastName,
+		SortBy:    "first_name",
+		SortOrder: "desc",
+		Page:      2,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(3), result.Count)
+	require.Len(t, result.Users, 1)
+	require.Equal(t, "Alice", result.Users[0].FirstName)
+
+	// the newest user comes first by default
+	result, err = s.strg.User().GetAll(ctx, &repo.GetAllUsersParams{
+		Search: lastName,
+		Page:   1,
+		Limit:  1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Carol", result.Users[0].FirstName)
+
+	result, err = s.strg.User().GetAll(ctx, &repo.GetAllUsersParams{Search: unique()})
+	require.NoError(t, err)
+	require.Zero(t, result.Count)
+	require.Empty(t, result.Users)
+
+	_, err = s.strg.User().GetAll(ctx, &repo.GetAllUsersParams{SortBy: "password"})
+	require.ErrorIs(t, err, repo.ErrInvalidSort)
+
+	_, err = s.strg.User().GetAll(ctx, &repo.GetAllUsersParams{SortBy: "email", SortOrder: "sideways"})
+	require.ErrorIs(t, err, repo.ErrInvalidSort)
+}
+
+func (s *suite) testUserVersion(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+
+	user.FirstName = "Changed"
+	updated, err := s.strg.User().Update(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, user.Version+1, updated.Version)
+	require.Equal(t, "Changed", updated.FirstName)
+	require.NotNil(t, updated.UpdatedAt)
+
+	_, err = s.strg.User().Update(ctx, user)
+	require.ErrorIs(t, err, repo.ErrVersionMismatch)
+
+	patched, err := s.strg.User().Patch(ctx, &repo.UserPatch{
+		ID:      user.ID,
+		Version: updated.Version,
+		Fields: map[string]interface{}{
+			"last_name":    "Patched",
+			"phone_number": nil,
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Changed", patched.FirstName)
+	require.Equal(t, "Patched", patched.LastName)
+	require.Empty(t, patched.PhoneNumber)
+	require.Equal(t, updated.Version+1, patched.Version)
+
+	_, err = s.strg.User().Patch(ctx, &repo.UserPatch{
+		ID:      user.ID,
+		Version: patched.Version,
+		Fields:  map[string]interface{}{"password": "secret"},
+	})
+	require.Error(t, err)
+
+	missing := newUser()
+	missing.ID = missingID
+	_, err = s.strg.User().Update(ctx, missing)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	require.NoError(t, s.strg.User().Activate(ctx, user.ID))
+	require.NoError(t, s.strg.User().UpdatePassword(ctx, user.ID, "hash"))
+	require.NoError(t, s.strg.User().UpdateRole(ctx, user.ID, repo.RoleAdmin))
+
+	stored, err := s.strg.User().Get(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, stored.IsActive)
+	require.Equal(t, "hash", stored.Password)
+	require.Equal(t, repo.RoleAdmin, stored.Role)
+	require.Equal(t, patched.Version+3, stored.Version)
+
+	require.ErrorIs(t, s.strg.User().Activate(ctx, missingID), repo.ErrNotFound)
+	require.ErrorIs(t, s.strg.User().UpdatePassword(ctx, missingID, "hash"), repo.ErrNotFound)
+	require.ErrorIs(t, s.strg.User().UpdateRole(ctx, missingID, repo.RoleAdmin), repo.ErrNotFound)
+}
+
+func (s *suite) testUserDelete(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+	note := s.createNote(t, user.ID, "Keep", "")
+
+	_, err := s.strg.ApiToken().Create(ctx, &repo.ApiToken{
+		UserID:    user.ID,
+		Name:      "cli",
+		TokenHash: unique(),
+		Scopes:    []string{"notes:read"},
+	})
+	require.NoError(t, err)
+
+	// the notes of the user are not deleted with it
+	err = s.strg.User().Delete(ctx, user.ID)
+	require.ErrorIs(t, err, repo.ErrInvalidReference)
+
+	require.NoError(t, s.strg.Note().Purge(ctx, note.ID, user.ID))
+	require.NoError(t, s.strg.User().Delete(ctx, user.ID))
+
+	_, err = s.strg.User().Get(ctx, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	tokens, err := s.strg.ApiToken().GetAll(ctx, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+
+	err = s.strg.User().Delete(ctx, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
+
+func (s *suite) testNoteCreate(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+
+	note := s.createNote(t, user.ID, "First", "description")
+	require.Equal(t, int64(1), note.Version)
+	require.NotNil(t, note.Tags)
+
+	stored, err := s.strg.Note().Get(ctx, note.ID, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "First", stored.Title)
+	require.Equal(t, "description", stored.Description)
+	require.Empty(t, stored.Tags)
+	require.Nil(t, stored.NotebookID)
+
+	revisions, err := s.strg.NoteRevision().GetAll(ctx, note.ID, user.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, &user.ID, revisions[0].AuthorID)
+
+	_, err = s.strg.Note().Create(ctx, &repo.Note{
+		UserID: missingID,
+		Title:  "Orphan",
+	})
+	require.ErrorIs(t, err, repo.ErrInvalidReference)
+}
+
+func (s *suite) testNoteAccess(t *testing.T) {
+	ctx := context.Background()
+	owner := s.createUser(t, nil)
+	viewer := s.createUser(t, nil)
+	editor := s.createUser(t, nil)
+	stranger := s.createUser(t, nil)
+	note := s.createNote(t, owner.ID, "Shared", "")
+
+	for _, share := range []*repo.NoteShare{
+		{NoteID: note.ID, UserID: viewer.ID, Permission: repo.PermissionViewer},
+		{NoteID: note.ID, UserID: editor.ID, Permission: repo.PermissionEditor},
+	} {
+		_, err := s.strg.NoteShare().Create(ctx, owner.ID, share)
+		require.NoError(t, err)
+	}
+
+	_, err := s.strg.Note().Get(ctx, note.ID, stranger.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	_, err = s.strg.Note().Get(ctx, note.ID, viewer.ID)
+	require.NoError(t, err)
+
+	_, err = s.strg.Note().Update(ctx, &repo.Note{ID: note.ID, UserID: viewer.ID, Title: "Viewer", Version: note.Version})
+	require.ErrorIs(t, err, repo.ErrReadOnly)
+
+	_, err = s.strg.Note().Update(ctx, &repo.Note{ID: note.ID, UserID: stranger.ID, Title: "Stranger", Version: note.Version})
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	updated, err := s.strg.Note().Update(ctx, &repo.Note{ID: note.ID, UserID: editor.ID, Title: "Editor", Version: note.Version})
+	require.NoError(t, err)
+	require.Equal(t, "Editor", updated.Title)
+	require.Equal(t, owner.ID, updated.UserID)
+
+	_, err = s.strg.Note().Update(ctx, &repo.Note{ID: note.ID, UserID: owner.ID, Title: "Stale", Version: note.Version})
+	require.ErrorIs(t, err, repo.ErrVersionMismatch)
+
+	patched, err := s.strg.Note().Patch(ctx, &repo.NotePatch{
+		ID:      note.ID,
+		UserID:  owner.ID,
+		Version: updated.Version,
+		Fields:  map[string]interface{}{"description": "patched"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Editor", patched.Title)
+	require.Equal(t, "patched", patched.Description)
+
+	revisions, err := s.strg.NoteRevision().GetAll(ctx, note.ID, viewer.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	require.Equal(t, &owner.ID, revisions[0].AuthorID)
+	require.Equal(t, &editor.ID, revisions[1].AuthorID)
+
+	revision, err := s.strg.NoteRevision().Get(ctx, revisions[1].ID, note.ID, viewer.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Editor", revision.Title)
+
+	_, err = s.strg.NoteRevision().Get(ctx, revisions[1].ID, note.ID, stranger.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	// only the owner may trash, move or purge the note
+	require.ErrorIs(t, s.strg.Note().Delete(ctx, note.ID, editor.ID), repo.ErrNotFound)
+	require.ErrorIs(t, s.strg.Note().Purge(ctx, note.ID, editor.ID), repo.ErrNotFound)
+	_, err = s.strg.Note().Move(ctx, note.ID, editor.ID, nil)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	// the revisions of a deleted editor stay in the history without an author
+	require.NoError(t, s.strg.User().Delete(ctx, editor.ID))
+
+	revisions, err = s.strg.NoteRevision().GetAll(ctx, note.ID, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	require.Nil(t, revisions[1].AuthorID)
+	require.Equal(t, "Editor", revisions[1].Title)
+	require.Equal(t, &owner.ID, revisions[0].AuthorID)
+}
+
+func (s *suite) testNoteGetAll(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+	other := s.createUser(t, nil)
+	word := unique()
+
+	b := s.createNote(t, user.ID, "B "+word, "")
+	a := s.createNote(t, user.ID, "A "+word, "")
+	c := s.createNote(t, user.ID, "C "+word, "")
+	s.createNote(t, user.ID, "Unrelated", word)
+	shared := s.createNote(t, other.ID, "D "+word, "")
+
+	_, err := s.strg.NoteShare().Create(ctx, other.ID, &repo.NoteShare{
+		NoteID:     shared.ID,
+		UserID:     user.ID,
+		Permission: repo.PermissionViewer,
+	})
+	require.NoError(t, err)
+
+	result, err := s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{
+		UserID: user.ID,
+		Search: strings.ToUpper(word),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(3), result.Count)
+	require.Equal(t, []int64{c.ID, a.ID, b.ID}, noteIDs(result.Notes))
+
+	result, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{
+		UserID:    user.ID,
+		Search:    word,
+		SortBy:    "title",
+		SortOrder: "asc",
+		Page:      1,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(3), result.Count)
+	require.Equal(t, []int64{a.ID, b.ID}, noteIDs(result.Notes))
+
+	result, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{
+		UserID:        user.ID,
+		Search:        word,
+		SortBy:        "title",
+		SortOrder:     "desc",
+		IncludeShared: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{shared.ID, c.ID, b.ID, a.ID}, noteIDs(result.Notes))
+
+	// notes that were never updated come last in ascending order
+	_, err = s.strg.Note().Update(ctx, &repo.Note{ID: b.ID, UserID: user.ID, Title: b.Title, Version: b.Version})
+	require.NoError(t, err)
+
+	result, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{
+		UserID:    user.ID,
+		Search:    word,
+		SortBy:    "updated_at",
+		SortOrder: "asc",
+	})
+	require.NoError(t, err)
+	require.Equal(t, b.ID, result.Notes[0].ID)
+
+	_, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{UserID: user.ID, SortBy: "description"})
+	require.ErrorIs(t, err, repo.ErrInvalidSort)
+
+	_, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{UserID: user.ID, SortOrder: "random"})
+	require.ErrorIs(t, err, repo.ErrInvalidSort)
+}
+
+func (s *suite) testNoteFullText(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+	word := unique()
+
+	inDescription := s.createNote(t, user.ID, "Weekly report", "the "+word+" numbers")
+	inTitle := s.createNote(t, user.ID, "Report "+word, "")
+	s.createNote(t, user.ID, "Report", "nothing here")
+
+	result, err := s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{
+		UserID:   user.ID,
+		Search:   word,
+		FullText: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.Count)
+	require.Equal(t, []int64{inTitle.ID, inDescription.ID}, noteIDs(result.Notes))
+	require.NotNil(t, result.Notes[0].Highlight)
+	require.Contains(t, result.Notes[0].Highlight.Title, "<mark>")
+	require.Contains(t, result.Notes[1].Highlight.Description, "<mark>")
+
+	result, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{
+		UserID:   user.ID,
+		Search:   word + " -weekly",
+		FullText: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{inTitle.ID}, noteIDs(result.Notes))
+
+	// the text of the note is escaped, the <mark> tags are the only markup
+	other := unique()
+	s.createNote(t, user.ID, "<script>alert(1)</script> "+other, `<img src=x onerror="alert(1)">`)
+
+	result, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{
+		UserID:   user.ID,
+		Search:   other,
+		FullText: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Notes, 1)
+	require.Contains(t, result.Notes[0].Highlight.Title, "&lt;script&gt;")
+	require.Contains(t, result.Notes[0].Highlight.Title, "<mark>"+other+"</mark>")
+	require.NotContains(t, result.Notes[0].Highlight.Title, "<script>")
+	require.NotContains(t, result.Notes[0].Highlight.Description, "<img")
+
+	result, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{
+		UserID:    user.ID,
+		Search:    word,
+		FullText:  true,
+		SortBy:    "created_at",
+		SortOrder: "asc",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{inDescription.ID, inTitle.ID}, noteIDs(result.Notes))
+}
+
+func (s *suite) testNoteTrash(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+	note := s.createNote(t, user.ID, "Trash "+unique(), "")
+
+	require.NoError(t, s.strg.Note().Delete(ctx, note.ID, user.ID))
+	require.ErrorIs(t, s.strg.Note().Delete(ctx, note.ID, user.ID), repo.ErrNotFound)
+
+	_, err := s.strg.Note().Get(ctx, note.ID, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	revisions, err := s.strg.NoteRevision().GetAll(ctx, note.ID, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, revisions)
+
+	result, err := s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{UserID: user.ID, Deleted: true})
+	require.NoError(t, err)
+	require.Equal(t, []int64{note.ID}, noteIDs(result.Notes))
+	require.NotNil(t, result.Notes[0].DeletedAt)
+
+	result, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{UserID: user.ID})
+	require.NoError(t, err)
+	require.Empty(t, result.Notes)
+
+	restored, err := s.strg.Note().Restore(ctx, note.ID, user.ID)
+	require.NoError(t, err)
+	require.Nil(t, restored.DeletedAt)
+	require.Equal(t, note.Version+2, restored.Version)
+
+	_, err = s.strg.Note().Restore(ctx, note.ID, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	require.NoError(t, s.strg.Note().Purge(ctx, note.ID, user.ID))
+	require.ErrorIs(t, s.strg.Note().Purge(ctx, note.ID, user.ID), repo.ErrNotFound)
+
+	_, err = s.strg.Note().Get(ctx, note.ID, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
+
+func (s *suite) testNoteTags(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+	other := s.createUser(t, nil)
+
+	work, err := s.strg.Tag().Create(ctx, &repo.Tag{UserID: user.ID, Name: "work"})
+	require.NoError(t, err)
+	urgent, err := s.strg.Tag().Create(ctx, &repo.Tag{UserID: user.ID, Name: "urgent"})
+	require.NoError(t, err)
+
+	_, err = s.strg.Tag().Create(ctx, &repo.Tag{UserID: user.ID, Name: "work"})
+	require.ErrorIs(t, err, repo.ErrTagExists)
+	require.ErrorIs(t, err, repo.ErrConflict)
+
+	_, err = s.strg.Tag().Create(ctx, &repo.Tag{UserID: other.ID, Name: "work"})
+	require.NoError(t, err)
+
+	_, err = s.strg.Tag().Rename(ctx, urgent.ID, user.ID, "work")
+	require.ErrorIs(t, err, repo.ErrTagExists)
+
+	both := s.createNote(t, user.ID, "Both", "")
+	onlyWork := s.createNote(t, user.ID, "Work", "")
+	none := s.createNote(t, user.ID, "None", "")
+	foreign := s.createNote(t, other.ID, "Foreign", "")
+
+	for _, pair := range [][2]int64{{both.ID, work.ID}, {both.ID, urgent.ID}, {onlyWork.ID, work.ID}, {onlyWork.ID, work.ID}} {
+		require.NoError(t, s.strg.Tag().Attach(ctx, pair[0], pair[1], user.ID))
+	}
+	require.ErrorIs(t, s.strg.Tag().Attach(ctx, foreign.ID, work.ID, user.ID), repo.ErrNotFound)
+	require.ErrorIs(t, s.strg.Tag().Detach(ctx, none.ID, work.ID, user.ID), repo.ErrNotFound)
+
+	stored, err := s.strg.Note().Get(ctx, both.ID, user.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Tags, 2)
+	require.Equal(t, "urgent", stored.Tags[0].Name)
+	require.Equal(t, "work", stored.Tags[1].Name)
+
+	tag, err := s.strg.Tag().Get(ctx, work.ID, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), tag.NoteCount)
+
+	for _, tc := range []struct {
+		params repo.GetAllNotesParams
+		want   []int64
+	}{
+		{repo.GetAllNotesParams{TagsAny: []int64{work.ID, urgent.ID}}, []int64{onlyWork.ID, both.ID}},
+		{repo.GetAllNotesParams{TagsAll: []int64{work.ID, urgent.ID}}, []int64{both.ID}},
+		{repo.GetAllNotesParams{TagsExclude: []int64{urgent.ID}}, []int64{none.ID, onlyWork.ID}},
+	} {
+		params := tc.params
+		params.UserID = user.ID
+		result, err := s.strg.Note().GetAll(ctx, &params)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, noteIDs(result.Notes))
+	}
+
+	merged, err := s.strg.Tag().Merge(ctx, urgent.ID, work.ID, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), merged.NoteCount)
+
+	tags, err := s.strg.Tag().GetAll(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+
+	require.NoError(t, s.strg.Tag().Detach(ctx, both.ID, work.ID, user.ID))
+	require.NoError(t, s.strg.Tag().Delete(ctx, work.ID, user.ID))
+	require.ErrorIs(t, s.strg.Tag().Delete(ctx, work.ID, user.ID), repo.ErrNotFound)
+
+	stored, err = s.strg.Note().Get(ctx, onlyWork.ID, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, stored.Tags)
+}
+
+func (s *suite) testNotebooks(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+	other := s.createUser(t, nil)
+
+	create := func(userID int64, parentID *int64, name string) (*repo.Notebook, error) {
+		return s.strg.Notebook().Create(ctx, &repo.Notebook{UserID: userID, ParentID: parentID, Name: name})
+	}
+
+	root, err := create(user.ID, nil, "Root")
+	require.NoError(t, err)
+	child, err := create(user.ID, &root.ID, "Child")
+	require.NoError(t, err)
+	foreign, err := create(other.ID, nil, "Foreign")
+	require.NoError(t, err)
+
+	_, err = create(user.ID, &foreign.ID, "Nested")
+	require.ErrorIs(t, err, repo.ErrNotebookNotFound)
+
+	_, err = s.strg.Notebook().Move(ctx, root.ID, user.ID, &child.ID)
+	require.ErrorIs(t, err, repo.ErrNotebookCycle)
+	_, err = s.strg.Notebook().Move(ctx, root.ID, user.ID, &root.ID)
+	require.ErrorIs(t, err, repo.ErrNotebookCycle)
+
+	inRoot := s.createNote(t, user.ID, "Root note", "")
+	inChild := s.createNote(t, user.ID, "Child note", "")
+	s.createNote(t, user.ID, "Loose note", "")
+
+	_, err = s.strg.Note().Move(ctx, inRoot.ID, user.ID, &root.ID)
+	require.NoError(t, err)
+	moved, err := s.strg.Note().Move(ctx, inChild.ID, user.ID, &child.ID)
+	require.NoError(t, err)
+	require.Equal(t, child.ID, *moved.NotebookID)
+
+	_, err = s.strg.Note().Move(ctx, inRoot.ID, user.ID, &foreign.ID)
+	require.ErrorIs(t, err, repo.ErrNotebookNotFound)
+
+	stored, err := s.strg.Notebook().Get(ctx, root.ID, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stored.NoteCount)
+
+	result, err := s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{UserID: user.ID, NotebookID: &root.ID})
+	require.NoError(t, err)
+	require.Equal(t, []int64{inRoot.ID}, noteIDs(result.Notes))
+
+	result, err = s.strg.Note().GetAll(ctx, &repo.GetAllNotesParams{UserID: user.ID, NotebookID: &root.ID, IncludeDescendants: true})
+	require.NoError(t, err)
+	require.Equal(t, []int64{inChild.ID, inRoot.ID}, noteIDs(result.Notes))
+
+	renamed, err := s.strg.Notebook().Rename(ctx, child.ID, user.ID, "Renamed")
+	require.NoError(t, err)
+	require.Equal(t, "Renamed", renamed.Name)
+	require.NotNil(t, renamed.UpdatedAt)
+
+	notebooks, err := s.strg.Notebook().GetAll(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, notebooks, 2)
+	require.Equal(t, "Renamed", notebooks[0].Name)
+
+	_, err = s.strg.Notebook().Get(ctx, root.ID, other.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	// deleting a notebook deletes its descendants and keeps their notes at the top level
+	require.NoError(t, s.strg.Notebook().Delete(ctx, root.ID, user.ID))
+	_, err = s.strg.Notebook().Get(ctx, child.ID, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	note, err := s.strg.Note().Get(ctx, inChild.ID, user.ID)
+	require.NoError(t, err)
+	require.Nil(t, note.NotebookID)
+}
+
+func (s *suite) testNoteShares(t *testing.T) {
+	ctx := context.Background()
+	owner := s.createUser(t, nil)
+	friend := s.createUser(t, nil)
+	note := s.createNote(t, owner.ID, "Shared", "")
+
+	share, err := s.strg.NoteShare().Create(ctx, owner.ID, &repo.NoteShare{
+		NoteID:     note.ID,
+		UserID:     friend.ID,
+		Permission: repo.PermissionViewer,
+	})
+	require.NoError(t, err)
+	require.Equal(t, friend.Email, share.Email)
+
+	_, err = s.strg.NoteShare().Create(ctx, owner.ID, share)
+	require.ErrorIs(t, err, repo.ErrShareExists)
+
+	_, err = s.strg.NoteShare().Create(ctx, owner.ID, &repo.NoteShare{
+		NoteID:     note.ID,
+		UserID:     missingID,
+		Permission: repo.PermissionViewer,
+	})
+	require.ErrorIs(t, err, repo.ErrInvalidReference)
+
+	_, err = s.strg.NoteShare().Create(ctx, friend.ID, &repo.NoteShare{
+		NoteID:     note.ID,
+		UserID:     owner.ID,
+		Permission: repo.PermissionViewer,
+	})
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	share.Permission = repo.PermissionEditor
+	updated, err := s.strg.NoteShare().Update(ctx, owner.ID, share)
+	require.NoError(t, err)
+	require.Equal(t, repo.PermissionEditor, updated.Permission)
+	require.NotNil(t, updated.UpdatedAt)
+
+	shares, err := s.strg.NoteShare().GetAll(ctx, note.ID, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+
+	_, err = s.strg.NoteShare().GetAll(ctx, note.ID, friend.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	require.NoError(t, s.strg.NoteShare().Delete(ctx, note.ID, owner.ID, friend.ID))
+	require.ErrorIs(t, s.strg.NoteShare().Delete(ctx, note.ID, owner.ID, friend.ID), repo.ErrNotFound)
+
+	_, err = s.strg.Note().Get(ctx, note.ID, friend.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
+
+func (s *suite) testNoteLinks(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+	note := s.createNote(t, user.ID, "Public", "")
+	maxViews := int64(1)
+
+	link, err := s.strg.NoteLink().Create(ctx, &repo.NoteLink{
+		NoteID:    note.ID,
+		UserID:    user.ID,
+		TokenHash: unique(),
+		MaxViews:  &maxViews,
+	})
+	require.NoError(t, err)
+	require.Zero(t, link.ViewCount)
+
+	_, err = s.strg.NoteLink().Create(ctx, &repo.NoteLink{
+		NoteID:    note.ID,
+		UserID:    user.ID,
+		TokenHash: link.TokenHash,
+	})
+	require.ErrorIs(t, err, repo.ErrConflict)
+
+	stored, err := s.strg.NoteLink().GetByHash(ctx, link.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, link.ID, stored.ID)
+
+	viewed, err := s.strg.NoteLink().AddView(ctx, link.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), viewed.ViewCount)
+
+	_, err = s.strg.NoteLink().AddView(ctx, link.ID)
+	require.ErrorIs(t, err, repo.ErrLinkUnavailable)
+
+	expired := time.Now().Add(-time.Minute)
+	old, err := s.strg.NoteLink().Create(ctx, &repo.NoteLink{
+		NoteID:    note.ID,
+		UserID:    user.ID,
+		TokenHash: unique(),
+		ExpiresAt: &expired,
+	})
+	require.NoError(t, err)
+
+	_, err = s.strg.NoteLink().AddView(ctx, old.ID)
+	require.ErrorIs(t, err, repo.ErrLinkUnavailable)
+
+	revoked, err := s.strg.NoteLink().Revoke(ctx, link.ID, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+
+	_, err = s.strg.NoteLink().Revoke(ctx, link.ID, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+
+	links, err := s.strg.NoteLink().GetAll(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, old.ID, links[0].ID)
+}
+
+func (s *suite) testApiTokens(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+
+	token, err := s.strg.ApiToken().Create(ctx, &repo.ApiToken{
+		UserID:    user.ID,
+		Name:      "cli",
+		TokenHash: unique(),
+		Scopes:    []string{"notes:read", "notes:write"},
+	})
+	require.NoError(t, err)
+
+	_, err = s.strg.ApiToken().Create(ctx, &repo.ApiToken{
+		UserID:    missingID,
+		Name:      "orphan",
+		TokenHash: unique(),
+	})
+	require.ErrorIs(t, err, repo.ErrInvalidReference)
+
+	require.NoError(t, s.strg.ApiToken().UpdateLastUsed(ctx, token.ID))
+
+	stored, err := s.strg.ApiToken().GetByHash(ctx, token.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, token.Scopes, stored.Scopes)
+	require.NotNil(t, stored.LastUsedAt)
+
+	require.ErrorIs(t, s.strg.ApiToken().Delete(ctx, token.ID, missingID), repo.ErrNotFound)
+	require.NoError(t, s.strg.ApiToken().Delete(ctx, token.ID, user.ID))
+
+	_, err = s.strg.ApiToken().GetByHash(ctx, token.TokenHash)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
+
+func (s *suite) testWithTx(t *testing.T) {
+	ctx := context.Background()
+	user := s.createUser(t, nil)
+
+	var committed *repo.Note
+	err := s.strg.WithTx(ctx, func(tx storage.StorageI) error {
+		var err error
+		committed, err = tx.Note().Create(ctx, &repo.Note{UserID: user.ID, Title: "Committed"})
+		if err != nil {
+			return err
+		}
+
+		// a nested transaction joins the outer one
+		return tx.WithTx(ctx, func(nested storage.StorageI) error {
+			_, err := nested.Note().Get(ctx, committed.ID, user.ID)
+			return err
+		})
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.strg.Note().Purge(context.Background(), committed.ID, user.ID)
+	})
+
+	_, err = s.strg.Note().Get(ctx, committed.ID, user.ID)
+	require.NoError(t, err)
+
+	errStop := errors.New("stop")
+	var rolledBack *repo.Note
+	err = s.strg.WithTx(ctx, func(tx storage.StorageI) error {
+		var err error
+		rolledBack, err = tx.Note().Create(ctx, &repo.Note{UserID: user.ID, Title: "Rolled back"})
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("after create: %w", errStop)
+	})
+	require.ErrorIs(t, err, errStop)
+
+	_, err = s.strg.Note().Get(ctx, rolledBack.ID, user.ID)
+	require.ErrorIs(t, err, repo.ErrNotFound)
+}
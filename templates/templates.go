@@ -0,0 +1,6 @@
+package templates
+
+import "embed"
+
+//go:embed *.html
+var FS embed.FS